@@ -2,94 +2,351 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/project-list-go/internal/app"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/auth"
 	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/config"
 
-	"github.com/renniemaharaj/project-list-go/internal/dashboard"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/demo"
 	routes "github.com/renniemaharaj/project-list-go/internal/health"
-	"github.com/renniemaharaj/project-list-go/internal/meta"
+	"github.com/renniemaharaj/project-list-go/internal/leave"
+	"github.com/renniemaharaj/project-list-go/internal/metrics"
 	cors "github.com/renniemaharaj/project-list-go/internal/middleware"
-	"github.com/renniemaharaj/project-list-go/internal/project"
+	"github.com/renniemaharaj/project-list-go/internal/notification"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
 	"github.com/renniemaharaj/project-list-go/internal/schema"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"github.com/renniemaharaj/project-list-go/internal/utils"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 )
 
+// usage of the command line, flags are named after the yaml path of their setting, e.g. -redis.host
+const usage = `usage:
+  main [-config file.yaml] [-<setting> value ...]               run the server
+  main config print [-config file.yaml] [-<setting> value ...]  print the configuration, secrets redacted`
+
 func main() {
 	// main logger used by main function
 	mainLogger := logger.New().Prefix("Backend")
 
-	mainLogger.Info("Allowing time for postgres to initialize...")
-	// allow time for postgres to initialize
-	time.Sleep(5 * time.Second)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		if len(args) < 2 || args[1] != "print" {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		os.Exit(printConfig(args[2:]))
+	}
 
-	mainLogger.Info("Resolving automatic database profile")
-	// initialize automatic database profile
-	db, err := database.Automatic.Resolve()
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(usage)
+		return
+	}
+	if err == nil {
+		err = cfg.Validate()
+	}
 	if err != nil {
-		panic(err)
-
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n\n%s\n", err, usage)
+		os.Exit(2)
 	}
-	mainLogger.SuccessF("Connected to database using %s", database.Automatic.EnvVar())
 
-	// will automatically initialize tables
-	if err := schema.NewRepository(database.Automatic, mainLogger).InitializeDatabaseTables(context.Background()); err != nil {
-		panic(err)
-	}
+	// stop on interrupt or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// initialize redis
-	if err := cache.InitializeRedis(); err != nil {
-		panic(err)
+	if err := run(ctx, cfg, mainLogger); err != nil {
+		mainLogger.Error(err.Error())
+		os.Exit(1)
 	}
+	mainLogger.Success("Stopped")
+}
 
-	demoData := true
-	// seed demo data
-	if demoData {
-		err = demo.NewService(demo.NewRepository(database.Automatic, mainLogger), mainLogger).GenerateInsertDemoData(context.Background())
-		if err != nil {
-			logger.NewLogger().Fatal(err)
-		}
+// printConfig writes the resolved configuration of args to stdout and returns the exit code, invalid
+// configurations are printed too so the offending values can be seen
+func printConfig(args []string) int {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		return 2
 	}
-	db.QueryLogFunc = database.QueryDBLogFunc()
-	db.ExecLogFunc = database.ExecDBLogFunc()
+	if err := config.Print(os.Stdout, cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		return 2
+	}
+	return 0
+}
+
+// run starts the server and its background workers and stops them once ctx is done. The server
+// drains first, then resources are released in reverse order of acquisition, also when startup fails.
+func run(ctx context.Context, cfg config.Config, mainLogger *logger.Logger) error {
+	// database profiles and cache of the configuration, connected below
+	dbs := database.New(cfg.Database)
+	c := cache.New(cfg.Redis)
+
+	// handlers wired to their services, they only use the pools once requests are served
+	api := app.New(cfg, dbs.Automatic, dbs.Replica, c)
 
 	// setup chi router and start server
 	r := chi.NewRouter()
-	// use middlewares
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Logger)
-	r.Use(cors.CORS) // CORS middleware
+	// use middlewares, the correlation id comes first so every log line and problem carries it
+	r.Use(problem.CorrelationID)
+	r.Use(tracing.Middleware)
+	r.Use(problem.Recoverer)
+	r.Use(metrics.Middleware)
+	// access log of chi, with the token query param of WebSocket clients redacted
+	r.Use(middleware.RequestLogger(auth.LogFormatter(&middleware.DefaultLogFormatter{
+		Logger:  log.New(os.Stdout, "", log.LstdFlags),
+		NoColor: runtime.GOOS == "windows",
+	})))
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		problem.Write(w, r, apperror.NotFound("no route for %s", r.URL.Path))
+	})
+	// CORS policy of the route groups below, the api policy applies to everything not public
+	r.Use(cors.ByPath(cfg.CORS.API,
+		cors.PathPolicy{Prefix: "/healthz", Policy: cfg.CORS.Public},
+		cors.PathPolicy{Prefix: "/readyz", Policy: cfg.CORS.Public},
+		cors.PathPolicy{Prefix: "/metrics", Policy: cfg.CORS.Public},
+	))
 
 	// public routes
 	r.Group(func(r chi.Router) {
 		// public
-		r.Get("/public", routes.HealthCheck)
+		r.Get("/healthz", routes.Liveness)
+		r.Get("/readyz", api.Health.Readiness)
+		r.Handle("/metrics", metrics.Handler())
 	})
 	// private routes
 	r.Group(func(r chi.Router) {
+		// refuse traffic until dependencies are initialized
+		r.Use(routes.RequireStarted)
 		// authenticate here
-		r.Route("/meta", meta.Meta)
-		r.Route("/project", project.ProjectHandler)
-		r.Route("/dashboard", dashboard.Dashboard)
+		r.Route("/meta", api.Meta.Routes)
+		r.Route("/project", api.Projects.Routes)
+		r.Route("/dashboard", api.Dashboard.Routes)
+		r.Route("/timeline", api.Timeline.Routes)
+		r.Route("/consultant", api.Capacity.Routes)
+		r.Route("/allocation", api.Allocations.Routes)
+		r.Route("/leave", api.Leaves.Routes)
+		r.Route("/time", api.Time.Routes)
+		r.Route("/events", api.Events.Routes)
+		r.Route("/webhooks", api.Webhooks.Routes)
+		r.Route("/notifications", api.Notifications.Routes)
 	})
 
-	// start rest server
+	// start rest server, /readyz reports starting until initialization below completes
+	server := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: r, // chi router as handler
+	}
+	// end streaming responses once shutdown starts so they do not hold up draining
+	server.RegisterOnShutdown(api.Dashboard.CloseStreams)
+	server.RegisterOnShutdown(api.Events.CloseConnections)
+	serverErr := make(chan error, 1)
 	go func() {
-		mainLogger.Info("Starting server on :8081")
-		server := &http.Server{
-			Addr:    ":8081",
-			Handler: r, // chi router as handler
+		mainLogger.InfoF("Starting server on %s", cfg.Server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
-		if err := server.ListenAndServe(); err != nil {
-			mainLogger.Fatal(err)
+	}()
+	// stop serving when startup fails, private routes were refused until then. After startup the
+	// server drains explicitly before anything below is released.
+	shutdown := sync.OnceFunc(func() { shutdownServer(server, cfg.Server.ShutdownTimeout, mainLogger) })
+	defer shutdown()
+
+	mainLogger.Info("Resolving automatic database profile")
+	// initialize automatic database profile, waiting for postgres to accept connections
+	var db *dbx.DB
+	err := utils.Retry(ctx, cfg.Server.ConnectTimeout, mainLogger, "Connecting to postgres", func(context.Context) error {
+		var err error
+		db, err = dbs.Automatic.Resolve()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer closeWith(mainLogger, "postgres", dbs.Automatic.Close)
+	mainLogger.SuccessF("Connected to database using %s", dbs.Automatic.EnvVar())
+
+	// export traces, flushed before the pools close
+	shutdownTracing, err := tracing.Init(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer closeWith(mainLogger, "tracing", func() error {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		return shutdownTracing(flushCtx)
+	})
+
+	// will automatically initialize tables
+	if err := schema.NewRepository(dbs.Automatic, mainLogger).InitializeDatabaseTables(ctx); err != nil {
+		return err
+	}
+
+	// import the configured holiday calendar
+	if path := cfg.Leave.HolidayCalendarICS; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
 		}
+		imported, err := leave.NewService(leave.NewRepository(dbs.Automatic, mainLogger), mainLogger).ImportHolidayCalendar(ctx, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		mainLogger.SuccessF("Imported %d holidays from %s", imported, path)
+	}
+
+	// initialize redis, waiting for it to accept connections
+	err = utils.Retry(ctx, cfg.Server.ConnectTimeout, mainLogger, "Connecting to redis", func(ctx context.Context) error {
+		return c.Connect(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	defer closeWith(mainLogger, "redis", c.Close)
+
+	// seed demo data
+	if cfg.Demo.Seed {
+		if err := demo.NewService(demo.NewRepository(dbs.Automatic, cfg.TimeEntryTypes, mainLogger), mainLogger).GenerateInsertDemoData(ctx); err != nil {
+			return err
+		}
+	}
+	db.QueryLogFunc = database.QueryDBLogFunc()
+	db.ExecLogFunc = database.ExecDBLogFunc()
+	if err := metrics.RegisterDBStats(db.DB(), "postgres"); err != nil {
+		return err
+	}
+
+	// the replica connects in its monitor, closed once the workers returned
+	defer closeWith(mainLogger, "replica", dbs.Replica.Close)
+
+	// background workers run until the server stopped, the pools close only after they returned
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		waitWorkers(&workers, cfg.Server.ShutdownTimeout, mainLogger)
+	}()
+
+	// route heavy reads to the replica while it keeps up, and to the primary otherwise
+	if cfg.Database.Replica.DSN != "" {
+		goWorker(&workers, func() {
+			dbs.Replica.MonitorReplica(workerCtx, cfg.Database.Replica.MaxLag, cfg.Database.Replica.LagInterval)
+		})
+	}
+
+	// fan project events out to the /events WebSocket clients of this instance
+	eventsDone, err := api.Events.Run(workerCtx)
+	if err != nil {
+		return err
+	}
+	track(&workers, eventsDone)
+
+	// deliver outbox events to webhook subscriptions
+	dispatcherLogger := logger.New().Prefix("Webhook Dispatcher")
+	dispatcher := webhook.NewDispatcher(webhook.NewRepository(dbs.Automatic, dispatcherLogger), &http.Client{Timeout: 10 * time.Second}, dispatcherLogger)
+	goWorker(&workers, func() { dispatcher.Run(workerCtx) })
+
+	// email managers and approvers about budgets, holds and pending approvals
+	notifierLogger := logger.New().Prefix("Notifier")
+	transport, err := notification.NewTransport(cfg.Notifications, notifierLogger)
+	if err != nil {
+		return err
+	}
+	notifier := notification.NewNotifier(notification.NewRepository(dbs.Automatic, notifierLogger), transport, notification.NewNotifierConfig(cfg.Notifications, cfg.TimeEntryTypes), notifierLogger)
+	goWorker(&workers, func() { notifier.Run(workerCtx) })
+
+	// push dashboard updates to /dashboard/stream on database changes
+	if liveDone, err := api.Dashboard.StartLiveUpdates(workerCtx, dbs.Automatic); err != nil {
+		mainLogger.Warning("Live dashboard updates disabled: " + err.Error())
+	} else {
+		track(&workers, liveDone)
+	}
+
+	routes.SetState(routes.StateReady)
+	mainLogger.Success("Ready")
+
+	select {
+	case <-ctx.Done():
+		mainLogger.Info("Shutting down")
+	case err := <-serverErr:
+		return err
+	}
+
+	// report not ready first so orchestrators stop routing traffic, then drain in-flight requests
+	// while workers and pools are still available
+	routes.SetState(routes.StateStopping)
+	time.Sleep(cfg.Server.DrainDelay)
+	shutdown()
+	return nil
+}
+
+// shutdownServer stops accepting connections and waits for in-flight requests, at most timeout
+func shutdownServer(server *http.Server, timeout time.Duration, l *logger.Logger) {
+	routes.SetState(routes.StateStopping)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		l.Error("Server shutdown: " + err.Error())
+	}
+}
+
+// goWorker runs fn as a background worker of workers
+func goWorker(workers *sync.WaitGroup, fn func()) {
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		fn()
+	}()
+}
+
+// track adds a worker that stopped once done is closed
+func track(workers *sync.WaitGroup, done <-chan struct{}) {
+	goWorker(workers, func() { <-done })
+}
+
+// waitWorkers waits for stopped workers to return, at most timeout
+func waitWorkers(workers *sync.WaitGroup, timeout time.Duration, l *logger.Logger) {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
 	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		l.Warning("Background workers did not stop in time")
+	}
+}
 
-	select {}
+// closeWith releases a resource on shutdown and logs failures
+func closeWith(l *logger.Logger, name string, close func() error) {
+	if err := close(); err != nil {
+		l.ErrorF("Failed to close %s: %s", name, err.Error())
+	}
 }
@@ -0,0 +1,235 @@
+package allocation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+const (
+	// dateLayout is the layout of the from and to query parameters
+	dateLayout = "2006-01-02"
+	// maxWindow caps conflict and forecast windows
+	maxWindow = 2 * 366 * 24 * time.Hour
+)
+
+// Handler serves the allocation routes through its service
+type Handler struct {
+	service Service
+}
+
+// NewHandler returns a handler serving the allocation routes through service
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Routes registers the allocation routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Post("/", h.CreateAllocation)
+	r.Get("/conflicts", h.GetAllocationConflicts)
+	r.Get("/forecast", h.GetAllocationForecast)
+	r.Get("/project/{projectID}", h.GetAllocationsByProjectID)
+	r.Get("/consultant/{consultantID}", h.GetAllocationsByConsultantID)
+	r.Get("/{allocationID}", h.GetAllocationByID)
+	r.Put("/{allocationID}", h.UpdateAllocation)
+	r.Delete("/{allocationID}", h.DeleteAllocation)
+}
+
+// Gets the report filter from query parameters, the window defaults to the next 12 weeks
+func getFilterFromRequest(w http.ResponseWriter, r *http.Request) (entity.AllocationFilter, error) {
+	query := r.URL.Query()
+	today := time.Now().Truncate(24 * time.Hour)
+	filter := entity.AllocationFilter{From: today, To: today.AddDate(0, 0, 12*7)}
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("from", "invalid from, expected "+dateLayout))
+			return filter, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if filter.To, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("to", "invalid to, expected "+dateLayout))
+			return filter, err
+		}
+	}
+	if filter.To.Before(filter.From) || filter.To.Sub(filter.From) > maxWindow {
+		problem.Write(w, r, apperror.Invalid("to", "to must not be before from and the window must not exceed 2 years"))
+		return filter, fmt.Errorf("invalid window")
+	}
+	if v := query.Get("projectID"); v != "" {
+		if filter.ProjectID, err = strconv.Atoi(v); err != nil {
+			problem.Write(w, r, apperror.Invalid("projectID", "invalid projectID"))
+			return filter, err
+		}
+	}
+	if v := query.Get("consultantID"); v != "" {
+		if filter.ConsultantID, err = strconv.Atoi(v); err != nil {
+			problem.Write(w, r, apperror.Invalid("consultantID", "invalid consultantID"))
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// Loads the allocation in the url
+func (h *Handler) getAllocation(w http.ResponseWriter, r *http.Request) (*Allocation, bool) {
+	allocationID, err := problem.IntParam(w, r, "allocationID")
+	if err != nil {
+		return nil, false
+	}
+
+	a, err := h.service.GetAllocationByID(r.Context(), allocationID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return a, true
+}
+
+// CreateAllocation inserts an allocation from the request body, 409 when it over-allocates the consultant
+func (h *Handler) CreateAllocation(w http.ResponseWriter, r *http.Request) {
+	var a Allocation
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid allocation body"))
+		return
+	}
+	a.ID = 0
+
+	if err := h.service.InsertAllocationByStruct(r.Context(), &a); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// GetAllocationByID returns a single allocation
+func (h *Handler) GetAllocationByID(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.getAllocation(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// UpdateAllocation adjusts the size and dates of an allocation, 409 when it over-allocates the consultant
+func (h *Handler) UpdateAllocation(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getAllocation(w, r)
+	if !ok {
+		return
+	}
+
+	var a Allocation
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid allocation body"))
+		return
+	}
+	// The assignment itself is fixed, only size and dates can be adjusted
+	a.ID = existing.ID
+	a.ProjectID = existing.ProjectID
+	a.ConsultantID = existing.ConsultantID
+
+	if err := h.service.UpdateAllocationByStruct(r.Context(), &a); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(a)
+}
+
+// DeleteAllocation removes an allocation
+func (h *Handler) DeleteAllocation(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.getAllocation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAllocationByID(r.Context(), a.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAllocationsByProjectID returns all allocations of a project
+func (h *Handler) GetAllocationsByProjectID(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	allocations, err := h.service.GetAllocationsByProjectID(r.Context(), projectID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(allocations)
+}
+
+// GetAllocationsByConsultantID returns all allocations of a consultant
+func (h *Handler) GetAllocationsByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	allocations, err := h.service.GetAllocationsByConsultantID(r.Context(), consultantID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(allocations)
+}
+
+// GetAllocationConflicts returns the weeks in which consultants are allocated above 100%
+func (h *Handler) GetAllocationConflicts(w http.ResponseWriter, r *http.Request) {
+	filter, err := getFilterFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	conflicts, err := h.service.GetAllocationConflicts(r.Context(), filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(conflicts)
+}
+
+// GetAllocationForecast returns planned vs logged hours per consultant, project and week
+func (h *Handler) GetAllocationForecast(w http.ResponseWriter, r *http.Request) {
+	filter, err := getFilterFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	forecast, err := h.service.GetAllocationForecast(r.Context(), filter)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(forecast)
+}
@@ -0,0 +1,34 @@
+package allocation
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetFilterFromRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"from=2026-11-02&to=2027-01-31&projectID=3&consultantID=4", false},
+		{"from=2026-11-02&to=2026-11-02", false},
+		{"from=2026-11-02&to=2026-11-01", true},
+		{"from=2026-01-01&to=2029-01-01", true},
+		{"from=tomorrow", true},
+		{"projectID=x", true},
+		{"consultantID=x", true},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		filter, err := getFilterFromRequest(w, httptest.NewRequest("GET", "/allocations/conflicts?"+tt.query, nil))
+		if tt.wantErr != (err != nil) || tt.wantErr != (w.Code == 400) {
+			t.Errorf("%s: filter = %+v, err = %v, status %d", tt.query, filter, err, w.Code)
+		}
+	}
+
+	filter, _ := getFilterFromRequest(httptest.NewRecorder(), httptest.NewRequest("GET", "/allocations/conflicts?projectID=3&consultantID=4", nil))
+	if filter.ProjectID != 3 || filter.ConsultantID != 4 || filter.To.Sub(filter.From) != 12*7*24*time.Hour {
+		t.Fatalf("filter = %+v, want project 3, consultant 4 over the next 12 weeks", filter)
+	}
+}
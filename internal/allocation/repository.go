@@ -0,0 +1,330 @@
+package allocation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/capacity"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+// ErrAllocationConflict is matched by ConflictError, returned when a consultant would be allocated above 100%
+var ErrAllocationConflict = apperror.New(apperror.KindConflict, "allocation exceeds consultant capacity")
+
+// ConflictError reports the busiest day of an over-allocated consultant
+type ConflictError struct {
+	Day          time.Time
+	TotalPercent float64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %.1f%% allocated on %s", ErrAllocationConflict, e.TotalPercent, e.Day.Format("2006-01-02"))
+}
+
+// Unwrap returns ErrAllocationConflict, classifying conflicts as domain conflicts
+func (e *ConflictError) Unwrap() error {
+	return ErrAllocationConflict
+}
+
+const (
+	// allocationLockClass namespaces the advisory locks taken per consultant while saving allocations
+	allocationLockClass = 30001
+
+	// percentSQL is the allocation of row a as a percentage of the consultant's capacity (cc),
+	// converting hours_per_week allocations through the effective weekly hours
+	percentSQL = `COALESCE(a.percent, a.hours_per_week * 100.0 / GREATEST(
+		COALESCE(cc.weekly_hours, {:weekly_hours}) * COALESCE(cc.part_time_percent, {:part_time_percent}) / 100.0, 0.01))`
+
+	// plannedDailyHoursSQL is the planned hours of row a on one working day
+	plannedDailyHoursSQL = `COALESCE(a.percent / 100.0 *
+		COALESCE(cc.weekly_hours, {:weekly_hours}) * COALESCE(cc.part_time_percent, {:part_time_percent}) / 100.0,
+		a.hours_per_week) / 5`
+)
+
+type Repository interface {
+	InsertAllocationByStruct(ctx context.Context, a *entity.Allocation) error
+	GetAllocationByID(ctx context.Context, allocationID int) (*entity.Allocation, error)
+	GetAllocationsByProjectID(ctx context.Context, projectID int) ([]entity.Allocation, error)
+	GetAllocationsByConsultantID(ctx context.Context, consultantID int) ([]entity.Allocation, error)
+	UpdateAllocationByStruct(ctx context.Context, a *entity.Allocation) error
+	DeleteAllocationByID(ctx context.Context, allocationID int) error
+	GetAllocationConflicts(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationConflict, error)
+	GetAllocationForecast(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationForecast, error)
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	types     config.TimeEntryTypes
+	logger    *logger.Logger
+}
+
+// NewRepository returns an allocation repository counting hours of the credit type of types as actuals
+func NewRepository(dbContext *database.DBContext, types config.TimeEntryTypes, _l *logger.Logger) Repository {
+	return &repository{dbContext, types, _l}
+}
+
+// capacityParams binds the capacity defaults used by percentSQL and plannedDailyHoursSQL
+func capacityParams(params dbx.Params) dbx.Params {
+	params["weekly_hours"] = capacity.DefaultWeeklyHours
+	params["part_time_percent"] = capacity.DefaultPartTimePercent
+	return params
+}
+
+// lockConsultant serializes allocation writes of one consultant for the rest of the transaction
+func lockConsultant(tx *dbx.Tx, consultantID int) error {
+	_, err := tx.NewQuery("SELECT pg_advisory_xact_lock({:lock_class}, {:consultant_id})").
+		Bind(dbx.Params{"lock_class": allocationLockClass, "consultant_id": consultantID}).
+		Execute()
+	return err
+}
+
+// checkCapacity returns a ConflictError when the consultant's allocations, including uncommitted
+// changes of tx, add up to more than 100% on any day of [from, to]
+func checkCapacity(tx *dbx.Tx, consultantID int, from, to time.Time) error {
+	var peak struct {
+		Day          time.Time
+		TotalPercent float64
+	}
+	err := tx.NewQuery(`SELECT d::date AS day, SUM(` + percentSQL + `) AS total_percent
+		FROM generate_series({:from}::date, {:to}::date, interval '1 day') AS d
+		INNER JOIN project_allocations a
+			ON a.consultant_id = {:consultant_id} AND d::date BETWEEN a.start_date AND a.end_date
+		LEFT JOIN consultant_capacities cc ON cc.consultant_id = a.consultant_id
+		GROUP BY 1
+		ORDER BY 2 DESC, 1 ASC
+		LIMIT 1`).
+		Bind(capacityParams(dbx.Params{"consultant_id": consultantID, "from": from, "to": to})).
+		One(&peak)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if peak.TotalPercent > 100 {
+		return &ConflictError{Day: peak.Day, TotalPercent: peak.TotalPercent}
+	}
+	return nil
+}
+
+// InsertAllocationByStruct will insert an allocation and set its ID. The consultant is assigned to the
+// project when not already, and the insert is rolled back when it over-allocates the consultant.
+func (r *repository) InsertAllocationByStruct(ctx context.Context, a *entity.Allocation) error {
+	ctx, span := tracing.Start(ctx, "allocation.repository.InsertAllocationByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		if err := lockConsultant(tx, a.ConsultantID); err != nil {
+			return err
+		}
+
+		// 1. make sure the allocation belongs to a project assignment
+		_, err := tx.NewQuery(`INSERT INTO project_consultants (project_id, consultant_id, role)
+			SELECT {:project_id}, {:consultant_id}, 'consultant'
+			WHERE NOT EXISTS (
+				SELECT 1 FROM project_consultants
+				WHERE project_id = {:project_id} AND consultant_id = {:consultant_id}
+			)`).
+			Bind(dbx.Params{"project_id": a.ProjectID, "consultant_id": a.ConsultantID}).
+			Execute()
+		if err != nil {
+			return err
+		}
+
+		// 2. insert the allocation
+		err = tx.NewQuery(`INSERT INTO project_allocations
+			(project_id, consultant_id, percent, hours_per_week, start_date, end_date)
+			VALUES ({:project_id}, {:consultant_id}, {:percent}, {:hours_per_week}, {:start_date}, {:end_date})
+			RETURNING id`).
+			Bind(dbx.Params{
+				"project_id":     a.ProjectID,
+				"consultant_id":  a.ConsultantID,
+				"percent":        a.Percent,
+				"hours_per_week": a.HoursPerWeek,
+				"start_date":     a.StartDate,
+				"end_date":       a.EndDate,
+			}).Row(&a.ID)
+		if err != nil {
+			return err
+		}
+
+		// 3. reject when the consultant is now over-allocated
+		return checkCapacity(tx, a.ConsultantID, a.StartDate, a.EndDate)
+	})
+}
+
+// GetAllocationByID will get and return an allocation by ID
+func (r *repository) GetAllocationByID(ctx context.Context, allocationID int) (*entity.Allocation, error) {
+	ctx, span := tracing.Start(ctx, "allocation.repository.GetAllocationByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var a entity.Allocation
+	err := r.dbContext.Get().WithContext(ctx).Select().From("project_allocations").Where(dbx.HashExp{"id": allocationID}).One(&a)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("allocation %d not found", allocationID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAllocationsByProjectID will return all allocations of a project
+func (r *repository) GetAllocationsByProjectID(ctx context.Context, projectID int) ([]entity.Allocation, error) {
+	ctx, span := tracing.Start(ctx, "allocation.repository.GetAllocationsByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Allocation
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("project_allocations").
+		Where(dbx.HashExp{"project_id": projectID}).
+		OrderBy("start_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetAllocationsByConsultantID will return all allocations of a consultant
+func (r *repository) GetAllocationsByConsultantID(ctx context.Context, consultantID int) ([]entity.Allocation, error) {
+	ctx, span := tracing.Start(ctx, "allocation.repository.GetAllocationsByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Allocation
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("project_allocations").
+		Where(dbx.HashExp{"consultant_id": consultantID}).
+		OrderBy("start_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// UpdateAllocationByStruct will adjust the size and dates of an allocation by ID, rolling back when
+// the change over-allocates the consultant
+func (r *repository) UpdateAllocationByStruct(ctx context.Context, a *entity.Allocation) error {
+	ctx, span := tracing.Start(ctx, "allocation.repository.UpdateAllocationByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		if err := lockConsultant(tx, a.ConsultantID); err != nil {
+			return err
+		}
+
+		_, err := tx.Update("project_allocations", dbx.Params{
+			"percent":        a.Percent,
+			"hours_per_week": a.HoursPerWeek,
+			"start_date":     a.StartDate,
+			"end_date":       a.EndDate,
+		}, dbx.HashExp{"id": a.ID}).Execute()
+		if err != nil {
+			return err
+		}
+
+		return checkCapacity(tx, a.ConsultantID, a.StartDate, a.EndDate)
+	})
+}
+
+// DeleteAllocationByID will delete an allocation by ID
+func (r *repository) DeleteAllocationByID(ctx context.Context, allocationID int) error {
+	ctx, span := tracing.Start(ctx, "allocation.repository.DeleteAllocationByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("project_allocations", dbx.HashExp{"id": allocationID}).Execute()
+		return err
+	})
+}
+
+// GetAllocationConflicts will return, per consultant and week of the window, the peak daily
+// allocation where it exceeds 100%
+func (r *repository) GetAllocationConflicts(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationConflict, error) {
+	ctx, span := tracing.Start(ctx, "allocation.repository.GetAllocationConflicts")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.AllocationConflict
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH daily AS (
+			SELECT a.consultant_id, d::date AS day, SUM(` + percentSQL + `) AS total_percent
+			FROM generate_series({:from}::date, {:to}::date, interval '1 day') AS d
+			INNER JOIN project_allocations a ON d::date BETWEEN a.start_date AND a.end_date
+			LEFT JOIN consultant_capacities cc ON cc.consultant_id = a.consultant_id
+			WHERE {:consultant_id} = 0 OR a.consultant_id = {:consultant_id}
+			GROUP BY 1, 2
+		)
+		SELECT consultant_id, date_trunc('week', day::timestamp)::date AS week_start,
+			MAX(total_percent) AS peak_percent
+		FROM daily
+		WHERE total_percent > 100
+		GROUP BY 1, 2
+		ORDER BY 1, 2`).
+		Bind(capacityParams(dbx.Params{
+			"from":          filter.From,
+			"to":            filter.To,
+			"consultant_id": filter.ConsultantID,
+		})).All(&list)
+	return list, err
+}
+
+// GetAllocationForecast will compare, per consultant, project and week of the window, the planned hours
+// on days the consultant is available (no weekend, holiday or approved leave) with the credit hours
+// actually logged
+func (r *repository) GetAllocationForecast(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationForecast, error) {
+	ctx, span := tracing.Start(ctx, "allocation.repository.GetAllocationForecast")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.AllocationForecast
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH planned AS (
+			SELECT a.consultant_id, a.project_id, date_trunc('week', av.day::timestamp)::date AS week_start,
+				SUM(` + plannedDailyHoursSQL + `) AS planned_hours
+			FROM project_allocations a
+			INNER JOIN consultant_availability({:from}::date, {:to}::date, {:weekly_hours}, {:part_time_percent}) AS av
+				ON av.consultant_id = a.consultant_id AND av.day BETWEEN a.start_date AND a.end_date
+			LEFT JOIN consultant_capacities cc ON cc.consultant_id = a.consultant_id
+			WHERE av.available_hours > 0
+				AND ({:project_id} = 0 OR a.project_id = {:project_id})
+				AND ({:consultant_id} = 0 OR a.consultant_id = {:consultant_id})
+			GROUP BY 1, 2, 3
+		),
+		actual AS (
+			SELECT te.consultant_id, te.project_id, date_trunc('week', te.entry_date)::date AS week_start,
+				SUM(te.hours) AS actual_hours
+			FROM project_time_entries te
+			WHERE te.type = {:credit_type}
+				AND te.consultant_id IS NOT NULL AND te.project_id IS NOT NULL
+				AND te.entry_date >= {:from}::date AND te.entry_date < {:to}::date + 1
+				AND ({:project_id} = 0 OR te.project_id = {:project_id})
+				AND ({:consultant_id} = 0 OR te.consultant_id = {:consultant_id})
+			GROUP BY 1, 2, 3
+		)
+		SELECT consultant_id, project_id, week_start,
+			COALESCE(p.planned_hours, 0) AS planned_hours,
+			COALESCE(a.actual_hours, 0) AS actual_hours,
+			COALESCE(a.actual_hours, 0) - COALESCE(p.planned_hours, 0) AS variance_hours
+		FROM planned p
+		FULL OUTER JOIN actual a USING (consultant_id, project_id, week_start)
+		ORDER BY consultant_id, project_id, week_start`).
+		Bind(capacityParams(dbx.Params{
+			"from":          filter.From,
+			"to":            filter.To,
+			"project_id":    filter.ProjectID,
+			"consultant_id": filter.ConsultantID,
+			"credit_type":   r.types.Credit,
+		})).All(&list)
+	return list, err
+}
@@ -0,0 +1,131 @@
+package allocation
+
+import (
+	"context"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// ErrInvalidAllocation is returned when an allocation fails validation
+var ErrInvalidAllocation = apperror.New(apperror.KindValidation, "invalid allocation")
+
+type Service interface {
+	InsertAllocationByStruct(ctx context.Context, a *Allocation) error
+	GetAllocationByID(ctx context.Context, allocationID int) (*Allocation, error)
+	GetAllocationsByProjectID(ctx context.Context, projectID int) ([]Allocation, error)
+	GetAllocationsByConsultantID(ctx context.Context, consultantID int) ([]Allocation, error)
+	UpdateAllocationByStruct(ctx context.Context, a *Allocation) error
+	DeleteAllocationByID(ctx context.Context, allocationID int) error
+	GetAllocationConflicts(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationConflict, error)
+	GetAllocationForecast(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationForecast, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Allocation struct {
+	entity.Allocation
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// validate checks that exactly one allocation size is set and the date range is valid
+func (a *Allocation) validate() error {
+	if a.ProjectID == 0 || a.ConsultantID == 0 {
+		return ErrInvalidAllocation.WithField("projectID", "projectID and consultantID are required")
+	}
+	if (a.Percent == nil) == (a.HoursPerWeek == nil) {
+		return ErrInvalidAllocation.WithField("percent", "exactly one of percent and hoursPerWeek is required")
+	}
+	if a.Percent != nil && (*a.Percent <= 0 || *a.Percent > 100) {
+		return ErrInvalidAllocation.WithField("percent", "percent must be above 0 and at most 100")
+	}
+	if a.HoursPerWeek != nil && (*a.HoursPerWeek <= 0 || *a.HoursPerWeek > 168) {
+		return ErrInvalidAllocation.WithField("hoursPerWeek", "hoursPerWeek must be above 0 and at most 168")
+	}
+	if a.StartDate.IsZero() || a.EndDate.IsZero() {
+		return ErrInvalidAllocation.WithField("startDate", "startDate and endDate are required")
+	}
+	if a.EndDate.Before(a.StartDate) {
+		return ErrInvalidAllocation.WithField("endDate", "endDate must not be before startDate")
+	}
+	return nil
+}
+
+func (s *service) InsertAllocationByStruct(ctx context.Context, a *Allocation) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	return s.repo.InsertAllocationByStruct(ctx, &a.Allocation)
+}
+
+func (s *service) GetAllocationByID(ctx context.Context, allocationID int) (*Allocation, error) {
+	a, err := s.repo.GetAllocationByID(ctx, allocationID)
+	if err != nil {
+		return &Allocation{}, err
+	}
+	return &Allocation{*a}, nil
+}
+
+func (s *service) GetAllocationsByProjectID(ctx context.Context, projectID int) ([]Allocation, error) {
+	allocations, err := s.repo.GetAllocationsByProjectID(ctx, projectID)
+	if err != nil {
+		return []Allocation{}, err
+	}
+	results := []Allocation{}
+	for _, a := range allocations {
+		results = append(results, Allocation{a})
+	}
+	return results, nil
+}
+
+func (s *service) GetAllocationsByConsultantID(ctx context.Context, consultantID int) ([]Allocation, error) {
+	allocations, err := s.repo.GetAllocationsByConsultantID(ctx, consultantID)
+	if err != nil {
+		return []Allocation{}, err
+	}
+	results := []Allocation{}
+	for _, a := range allocations {
+		results = append(results, Allocation{a})
+	}
+	return results, nil
+}
+
+func (s *service) UpdateAllocationByStruct(ctx context.Context, a *Allocation) error {
+	if err := a.validate(); err != nil {
+		return err
+	}
+	return s.repo.UpdateAllocationByStruct(ctx, &a.Allocation)
+}
+
+func (s *service) DeleteAllocationByID(ctx context.Context, allocationID int) error {
+	return s.repo.DeleteAllocationByID(ctx, allocationID)
+}
+
+func (s *service) GetAllocationConflicts(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationConflict, error) {
+	conflicts, err := s.repo.GetAllocationConflicts(ctx, filter)
+	if err != nil {
+		return []entity.AllocationConflict{}, err
+	}
+	if conflicts == nil {
+		conflicts = []entity.AllocationConflict{}
+	}
+	return conflicts, nil
+}
+
+func (s *service) GetAllocationForecast(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationForecast, error) {
+	forecast, err := s.repo.GetAllocationForecast(ctx, filter)
+	if err != nil {
+		return []entity.AllocationForecast{}, err
+	}
+	if forecast == nil {
+		forecast = []entity.AllocationForecast{}
+	}
+	return forecast, nil
+}
@@ -0,0 +1,76 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// fakeRepository counts the allocations it is asked to save and has neither conflicts nor forecast
+type fakeRepository struct {
+	Repository
+	saved int
+}
+
+func (f *fakeRepository) InsertAllocationByStruct(ctx context.Context, a *entity.Allocation) error {
+	f.saved++
+	return nil
+}
+
+func (f *fakeRepository) GetAllocationConflicts(ctx context.Context, filter entity.AllocationFilter) ([]entity.AllocationConflict, error) {
+	return nil, nil
+}
+
+func ptr(v float32) *float32 {
+	return &v
+}
+
+func TestInsertAllocationValidates(t *testing.T) {
+	start := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	valid := entity.Allocation{ProjectID: 1, ConsultantID: 2, Percent: ptr(50), StartDate: start, EndDate: end}
+
+	tests := []struct {
+		name   string
+		modify func(a *entity.Allocation)
+		valid  bool
+	}{
+		{"percent", func(a *entity.Allocation) {}, true},
+		{"hours per week", func(a *entity.Allocation) { a.Percent, a.HoursPerWeek = nil, ptr(16) }, true},
+		{"single day", func(a *entity.Allocation) { a.EndDate = a.StartDate }, true},
+		{"missing consultant", func(a *entity.Allocation) { a.ConsultantID = 0 }, false},
+		{"both sizes", func(a *entity.Allocation) { a.HoursPerWeek = ptr(16) }, false},
+		{"no size", func(a *entity.Allocation) { a.Percent = nil }, false},
+		{"zero percent", func(a *entity.Allocation) { a.Percent = ptr(0) }, false},
+		{"over 100 percent", func(a *entity.Allocation) { a.Percent = ptr(120) }, false},
+		{"over a week of hours", func(a *entity.Allocation) { a.Percent, a.HoursPerWeek = nil, ptr(169) }, false},
+		{"missing end", func(a *entity.Allocation) { a.EndDate = time.Time{} }, false},
+		{"end before start", func(a *entity.Allocation) { a.EndDate = a.StartDate.AddDate(0, 0, -1) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := valid
+			tt.modify(&a)
+			repo := &fakeRepository{}
+			err := NewService(repo, logger.New().Prefix("Allocation Test")).InsertAllocationByStruct(context.Background(), &Allocation{a})
+
+			if tt.valid && (err != nil || repo.saved != 1) {
+				t.Fatalf("err = %v, saved = %d, want it saved", err, repo.saved)
+			}
+			if !tt.valid && (!errors.Is(err, ErrInvalidAllocation) || repo.saved != 0) {
+				t.Fatalf("err = %v, saved = %d, want ErrInvalidAllocation", err, repo.saved)
+			}
+		})
+	}
+}
+
+func TestGetAllocationConflictsNeverNull(t *testing.T) {
+	conflicts, err := NewService(&fakeRepository{}, logger.New().Prefix("Allocation Test")).GetAllocationConflicts(context.Background(), entity.AllocationFilter{})
+	if err != nil || conflicts == nil {
+		t.Fatalf("conflicts = %v, err = %v, want an empty slice", conflicts, err)
+	}
+}
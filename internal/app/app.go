@@ -0,0 +1,115 @@
+package app
+
+import (
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/allocation"
+	"github.com/renniemaharaj/project-list-go/internal/auth"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/capacity"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/consultant"
+	"github.com/renniemaharaj/project-list-go/internal/dashboard"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	routes "github.com/renniemaharaj/project-list-go/internal/health"
+	"github.com/renniemaharaj/project-list-go/internal/leave"
+	"github.com/renniemaharaj/project-list-go/internal/meta"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
+	"github.com/renniemaharaj/project-list-go/internal/notification"
+	"github.com/renniemaharaj/project-list-go/internal/project"
+	"github.com/renniemaharaj/project-list-go/internal/schema"
+	"github.com/renniemaharaj/project-list-go/internal/status"
+	"github.com/renniemaharaj/project-list-go/internal/task"
+	internalTime "github.com/renniemaharaj/project-list-go/internal/time"
+	"github.com/renniemaharaj/project-list-go/internal/timeline"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
+)
+
+// App is the container of the http handlers, wired once at startup to their services and
+// repositories. Handlers only depend on service interfaces, so they can be served by mocks.
+type App struct {
+	Health        *routes.Handler
+	Meta          *meta.Handler
+	Projects      *project.Handler
+	Dashboard     *dashboard.Handler
+	Timeline      *timeline.Handler
+	Capacity      *capacity.Handler
+	Allocations   *allocation.Handler
+	Leaves        *leave.Handler
+	Time          *internalTime.Handler
+	Events        *events.Handler
+	Webhooks      *webhook.Handler
+	Notifications *notification.Handler
+}
+
+// New wires the handlers of the api, writes and reads that must see them go to primary, heavy reads
+// tolerating replication lag go to replica. Reads are cached and project events fanned out through c.
+func New(cfg config.Config, primary, replica *database.DBContext, c *cache.Cache) *App {
+	var (
+		projectLogger      = logger.New().Prefix("Projects Router")
+		milestoneLogger    = logger.New().Prefix("Milestones Router")
+		taskLogger         = logger.New().Prefix("Tasks Router")
+		metaLogger         = logger.New().Prefix("Meta Logger")
+		dashboardLogger    = logger.New().Prefix("Dash Router")
+		timelineLogger     = logger.New().Prefix("Timeline Router")
+		capacityLogger     = logger.New().Prefix("Capacity Router")
+		allocationLogger   = logger.New().Prefix("Allocations Router")
+		leaveLogger        = logger.New().Prefix("Leave Router")
+		timeLogger         = logger.New().Prefix("Time Router")
+		webhookLogger      = logger.New().Prefix("Webhooks Router")
+		notificationLogger = logger.New().Prefix("Notifications Router")
+		healthLogger       = logger.New().Prefix("Health")
+	)
+
+	// repositories shared by several services
+	projects := project.NewRepository(primary, projectLogger)
+	milestones := milestone.NewRepository(primary, milestoneLogger)
+	consultants := consultant.NewRepository(primary, metaLogger)
+	capacities := capacity.NewRepository(primary, consultants, cfg.TimeEntryTypes, capacityLogger)
+
+	metaRepository := meta.NewRepository(
+		internalTime.NewRepository(primary, metaLogger),
+		status.NewRepository(primary, metaLogger),
+		projects,
+		consultants,
+		milestones,
+		metaLogger,
+	)
+
+	return &App{
+		Health: routes.NewHandler(map[string]routes.Check{
+			"postgres": primary.Ping,
+			"redis":    c.Ping,
+			"schema":   routes.SchemaCheck(schema.NewRepository(primary, healthLogger)),
+		}),
+		Meta: meta.NewHandler(meta.NewService(metaRepository, metaLogger), c),
+		Projects: project.NewHandler(
+			project.NewService(projects, projectLogger),
+			project.NewService(project.NewRepository(replica, projectLogger), projectLogger),
+			cfg.Pagination,
+			milestone.NewHandler(milestone.NewService(milestones, milestoneLogger), c),
+			task.NewHandler(task.NewService(task.NewRepository(primary, cfg.TimeEntryTypes, taskLogger), capacities, taskLogger), c),
+			c,
+		),
+		Dashboard: dashboard.NewHandler(
+			dashboard.NewService(dashboard.NewRepository(replica, dashboardLogger), dashboardLogger),
+			dashboard.NewService(dashboard.NewRepository(primary, dashboardLogger), dashboardLogger),
+			dashboard.NewConfig(cfg.Dashboard, cfg.TimeEntryTypes),
+			c,
+		),
+		Timeline: timeline.NewHandler(timeline.NewService(
+			timeline.NewRepository(primary, projects, milestones, timelineLogger), timelineLogger), c),
+		Capacity: capacity.NewHandler(capacity.NewService(capacities, capacityLogger), c),
+		Allocations: allocation.NewHandler(allocation.NewService(
+			allocation.NewRepository(primary, cfg.TimeEntryTypes, allocationLogger), allocationLogger)),
+		Leaves: leave.NewHandler(leave.NewService(
+			leave.NewRepository(primary, leaveLogger), leaveLogger)),
+		Time: internalTime.NewHandler(internalTime.NewService(
+			internalTime.NewRepository(primary, timeLogger), cfg.TimeEntryTypes, c, timeLogger), c),
+		Events: events.NewHandler(cfg.CORS.API, auth.NewTokens(cfg.Auth), c),
+		Webhooks: webhook.NewHandler(webhook.NewService(
+			webhook.NewRepository(primary, webhookLogger), webhookLogger)),
+		Notifications: notification.NewHandler(notification.NewService(
+			notification.NewRepository(primary, notificationLogger), notificationLogger)),
+	}
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+)
+
+func TestNewWiresEveryHandler(t *testing.T) {
+	// wiring does not connect, the unconfigured testing database is never resolved
+	db := database.New(config.Database{}).Testing
+	api := New(config.Default(), db, db, nil)
+
+	v := reflect.ValueOf(api).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsNil() {
+			t.Errorf("%s is not wired", v.Type().Field(i).Name)
+		}
+	}
+
+	// chi panics on conflicting routes
+	r := chi.NewRouter()
+	r.Get("/readyz", api.Health.Readiness)
+	r.Route("/meta", api.Meta.Routes)
+	r.Route("/project", api.Projects.Routes)
+	r.Route("/dashboard", api.Dashboard.Routes)
+	r.Route("/timeline", api.Timeline.Routes)
+	r.Route("/consultant", api.Capacity.Routes)
+	r.Route("/allocation", api.Allocations.Routes)
+	r.Route("/leave", api.Leaves.Routes)
+	r.Route("/time", api.Time.Routes)
+	r.Route("/events", api.Events.Routes)
+	r.Route("/webhooks", api.Webhooks.Routes)
+	r.Route("/notifications", api.Notifications.Routes)
+
+	for _, route := range []string{"/project/{projectID}/milestones/{milestoneID}", "/project/{projectID}/tasks/forecast", "/dashboard/stream", "/events/", "/time/{timeEntryID}", "/time/project/{projectID}"} {
+		found := false
+		_ = chi.Walk(r, func(method, pattern string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			found = found || pattern == route
+			return nil
+		})
+		if !found {
+			t.Errorf("route %s not mounted", route)
+		}
+	}
+}
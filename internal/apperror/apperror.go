@@ -0,0 +1,125 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind classifies a domain error, the http layer answers every kind with one status
+type Kind string
+
+// Error kinds, errors of no kind are internal
+const (
+	KindInternal     Kind = "internal"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindUnavailable  Kind = "unavailable"
+)
+
+// FieldError details why one input field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error raised by repositories and services. Packages declare their errors as
+// sentinels and refine them per occurrence with WithField, refined errors still match the sentinel
+// with errors.Is.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  []FieldError
+	// parent is the sentinel this error refines
+	parent error
+}
+
+// New returns a domain error of kind
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// NotFound returns an error for a missing resource
+func NotFound(format string, args ...any) *Error {
+	return New(KindNotFound, fmt.Sprintf(format, args...))
+}
+
+// Conflict returns an error for a request conflicting with the current state of a resource
+func Conflict(format string, args ...any) *Error {
+	return New(KindConflict, fmt.Sprintf(format, args...))
+}
+
+// Unauthorized returns an error for a request without valid credentials
+func Unauthorized(format string, args ...any) *Error {
+	return New(KindUnauthorized, fmt.Sprintf(format, args...))
+}
+
+// Forbidden returns an error for a request the caller may not make
+func Forbidden(format string, args ...any) *Error {
+	return New(KindForbidden, fmt.Sprintf(format, args...))
+}
+
+// Unavailable returns an error for a request that cannot be served right now
+func Unavailable(format string, args ...any) *Error {
+	return New(KindUnavailable, fmt.Sprintf(format, args...))
+}
+
+// Validation returns an error for an invalid request, such as a malformed body
+func Validation(format string, args ...any) *Error {
+	return New(KindValidation, fmt.Sprintf(format, args...))
+}
+
+// Invalid returns a validation error of one request field, message is a complete sentence such as
+// "projectID must be an integer"
+func Invalid(field, message string) *Error {
+	return New(KindValidation, "invalid request").WithField(field, message)
+}
+
+// WithField returns a refinement of e detailing why field is invalid
+func (e *Error) WithField(field, message string) *Error {
+	return &Error{
+		Kind:    e.Kind,
+		Message: e.Message,
+		Fields:  append(slices.Clone(e.Fields), FieldError{Field: field, Message: message}),
+		parent:  e,
+	}
+}
+
+// Error returns the message followed by the field details, e.g. "invalid task: title is required"
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	details := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		details[i] = f.Message
+	}
+	return e.Message + ": " + strings.Join(details, "; ")
+}
+
+// Unwrap returns the sentinel e refines
+func (e *Error) Unwrap() error {
+	return e.parent
+}
+
+// KindOf returns the kind of the first domain error in the chain of err, internal without one
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// FieldsOf returns the field details of the first domain error in the chain of err
+func FieldsOf(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
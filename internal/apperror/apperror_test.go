@@ -0,0 +1,65 @@
+package apperror
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var errInvalidTask = New(KindValidation, "invalid task")
+
+func TestWithFieldRefinesSentinel(t *testing.T) {
+	err := errInvalidTask.WithField("title", "title is required").WithField("estimateHours", "estimateHours must not be negative")
+
+	if !errors.Is(err, errInvalidTask) {
+		t.Fatal("refined error does not match its sentinel")
+	}
+	if err.Error() != "invalid task: title is required; estimateHours must not be negative" {
+		t.Fatalf("message = %q", err.Error())
+	}
+	want := []FieldError{{"title", "title is required"}, {"estimateHours", "estimateHours must not be negative"}}
+	if !reflect.DeepEqual(FieldsOf(err), want) {
+		t.Fatalf("fields = %+v, want %+v", FieldsOf(err), want)
+	}
+	// refining does not alter the sentinel
+	if len(errInvalidTask.Fields) != 0 || errInvalidTask.Error() != "invalid task" {
+		t.Fatalf("sentinel = %+v", errInvalidTask)
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Kind
+	}{
+		{NotFound("project %d not found", 1), KindNotFound},
+		{Conflict("already decided"), KindConflict},
+		{Validation("invalid body"), KindValidation},
+		{Invalid("projectID", "projectID must be an integer"), KindValidation},
+		{Unauthorized("invalid token"), KindUnauthorized},
+		{Forbidden("not an approver"), KindForbidden},
+		{Unavailable("redis down"), KindUnavailable},
+		// the kind survives wrapping
+		{fmt.Errorf("loading: %w", NotFound("task 2 not found")), KindNotFound},
+		{errors.Join(errors.New("rollback failed"), Conflict("duplicate")), KindConflict},
+		{sql.ErrNoRows, KindInternal},
+		{nil, KindInternal},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.want {
+			t.Errorf("KindOf(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid("projectID", "projectID must be an integer")
+	if err.Error() != "invalid request: projectID must be an integer" || FieldsOf(err)[0].Field != "projectID" {
+		t.Fatalf("err = %v, fields %+v", err, FieldsOf(err))
+	}
+	if FieldsOf(errors.New("plain")) != nil {
+		t.Fatal("fields of an error of no kind")
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+)
+
+var (
+	authLogger = logger.New().Prefix("Auth")
+)
+
+// Tokens are the accepted API tokens
+type Tokens []string
+
+// NewTokens returns the API tokens of cfg, warning when none is configured as every token is then rejected
+func NewTokens(cfg config.Auth) Tokens {
+	if len(cfg.APITokens) == 0 {
+		authLogger.Warning("No API tokens configured (API_TOKENS), the /events WebSocket rejects every connection")
+	}
+	return Tokens(cfg.APITokens)
+}
+
+// TokenFromRequest returns the bearer token of the Authorization header, or the `token` query param
+// for clients such as browsers opening WebSockets that cannot set headers
+func TokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// Valid reports whether token is one of the accepted API tokens. While none is configured every
+// token is rejected.
+func (tokens Tokens) Valid(token string) bool {
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactToken returns r, or a shallow copy of it when its URL carries the `token` query param, with
+// the value of the param redacted
+func RedactToken(r *http.Request) *http.Request {
+	query := r.URL.Query()
+	if !query.Has("token") {
+		return r
+	}
+	query.Set("token", "REDACTED")
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	redacted := *r
+	redacted.URL = &u
+	redacted.RequestURI = u.RequestURI()
+	return &redacted
+}
+
+// logFormatter formats the log entries of requests with their token redacted
+type logFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f logFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	return f.LogFormatter.NewLogEntry(RedactToken(r))
+}
+
+// LogFormatter wraps f so the `token` query param of requests never reaches the access log
+func LogFormatter(f middleware.LogFormatter) middleware.LogFormatter {
+	return logFormatter{f}
+}
@@ -0,0 +1,12 @@
+package cache
+
+import "context"
+
+// Invalidate removes cached values so that the next Use call fetches fresh data, it does nothing
+// without redis
+func (c *Cache) Invalidate(keys ...string) {
+	if c == nil || len(keys) == 0 {
+		return
+	}
+	c.client.Del(context.Background(), keys...)
+}
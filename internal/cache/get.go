@@ -1,15 +1,25 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
-// getItem tries to fetch a cached value
-func getItem[T any](key string) (T, bool) {
+// getItem tries to fetch a cached value, every key misses without redis
+func getItem[T any](ctx context.Context, c *Cache, key string) (T, bool) {
+	if c == nil {
+		var zero T
+		return zero, false
+	}
+
+	ctx, span := tracing.Start(ctx, "redis.GET")
+	defer span.End()
+
 	var v T
-	raw, err := client.Get(ctx, key).Result()
+	raw, err := c.client.Get(ctx, key).Result()
 	if err == redis.Nil || err != nil {
 		var zero T
 		return zero, false
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// Publish sends a message to every subscriber of the channel, on every instance sharing the redis
+func (c *Cache) Publish(ctx context.Context, channel string, payload []byte) error {
+	if c == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	return c.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe delivers the messages of the channel until ctx is done, the returned channel is then closed
+func (c *Cache) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	if c == nil {
+		return nil, fmt.Errorf("redis not initialized")
+	}
+
+	sub := c.client.Subscribe(ctx, channel)
+	// wait for the subscription to be confirmed so no message published afterwards is missed
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		defer sub.Close()
+
+		in := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
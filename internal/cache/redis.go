@@ -3,48 +3,51 @@ package cache
 import (
 	"context"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/renniemaharaj/project-list-go/internal/config"
 )
 
-var (
-	// l      = logger.New().Prefix("Cache Logger")
+// Cache caches values and fans messages out through redis. A nil *Cache is a cache without redis,
+// e.g. in tests of the http layer, every Use fetches and Invalidate does nothing.
+type Cache struct {
 	client *redis.Client
-	ctx    = context.Background()
-	ttl    = 5 * time.Minute
-)
-
-// InitializeRedis initializes Redis connection using env vars.
-// Required: REDIS_HOST, REDIS_PORT, REDIS_PASSWORD, REDIS_DB
-func InitializeRedis() error {
-	host := os.Getenv("REDIS_HOST")
-	port := os.Getenv("REDIS_PORT")
-	pass := os.Getenv("REDIS_PASSWORD")
-	dbStr := os.Getenv("REDIS_DB")
+	ttl    time.Duration
+}
 
-	if host == "" || port == "" || dbStr == "" {
-		return fmt.Errorf("missing required Redis env vars (REDIS_HOST, REDIS_PORT, REDIS_DB)")
+// New returns the cache of cfg, the connection is only made once Connect succeeds
+func New(cfg config.Redis) *Cache {
+	return &Cache{
+		client: redis.NewClient(&redis.Options{
+			Addr:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}),
+		ttl: cfg.TTL,
 	}
+}
 
-	db, err := strconv.Atoi(dbStr)
-	if err != nil {
-		return fmt.Errorf("invalid REDIS_DB: %w", err)
+// Connect tests the redis connection within ctx
+func (c *Cache) Connect(ctx context.Context) error {
+	if err := c.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
+	return nil
+}
 
-	client = redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", host, port),
-		Password: pass,
-		DB:       db,
-	})
-
-	// Test connection
-	_, err = client.Ping(ctx).Result()
-	if err != nil {
-		return fmt.Errorf("failed to connect to Redis: %w", err)
+// Close closes the redis connection pool
+func (c *Cache) Close() error {
+	if c == nil {
+		return nil
 	}
+	return c.client.Close()
+}
 
-	return nil
+// Ping checks the redis connection within ctx
+func (c *Cache) Ping(ctx context.Context) error {
+	if c == nil {
+		return fmt.Errorf("redis not initialized")
+	}
+	return c.client.Ping(ctx).Err()
 }
@@ -1,15 +1,24 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
-	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
-// setItem stores a value in cache
-func setItem[T any](key string, value T, ttl time.Duration) {
+// setItem stores a value in cache, it does nothing without redis
+func setItem[T any](ctx context.Context, c *Cache, key string, value T) {
+	if c == nil {
+		return
+	}
+
+	ctx, span := tracing.Start(ctx, "redis.SET")
+	defer span.End()
+
 	data, err := json.Marshal(value)
 	if err != nil {
 		return
 	}
-	client.Set(ctx, key, data, ttl)
+	c.client.Set(ctx, key, data, c.ttl)
 }
@@ -1,19 +1,41 @@
 package cache
 
-// Use handles cache-or-fetch logic
-func Use[T any](key string, fetch func() (T, error)) (T, error) {
+import (
+	"context"
+
+	"github.com/renniemaharaj/project-list-go/internal/metrics"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Use handles cache-or-fetch logic through c, fetch runs under the cache span of ctx. Without redis,
+// a nil c, every call fetches.
+func Use[T any](ctx context.Context, c *Cache, key string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, "cache.Use", attribute.String("cache.key", key))
+	defer span.End()
+
+	if c == nil {
+		return fetch(ctx)
+	}
+
 	// 1. Try cache
-	if v, found := getItem[T](key); found {
+	if v, found := getItem[T](ctx, c, key); found {
+		metrics.ObserveCache(key, metrics.CacheHit)
+		span.SetAttributes(attribute.Bool("cache.hit", true))
 		return v, nil
 	}
+	metrics.ObserveCache(key, metrics.CacheMiss)
+	span.SetAttributes(attribute.Bool("cache.hit", false))
 
 	// 2. Fetch fresh
-	val, err := fetch()
+	val, err := fetch(ctx)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return val, err
 	}
 
 	// 3. Store and return
-	setItem(key, val, ttl)
+	setItem(ctx, c, key, val)
+	metrics.ObserveCache(key, metrics.CacheFill)
 	return val, nil
 }
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNilCacheFetches(t *testing.T) {
+	var c *Cache
+	ctx := context.Background()
+
+	// without redis every call fetches and nothing is kept
+	fetches := 0
+	for range 2 {
+		v, err := Use(ctx, c, "key", func(ctx context.Context) (int, error) {
+			fetches++
+			return 7, nil
+		})
+		if err != nil || v != 7 {
+			t.Fatalf("Use = %v, %v, want the fetched value", v, err)
+		}
+	}
+	if fetches != 2 {
+		t.Fatalf("%d fetches, want one per call", fetches)
+	}
+
+	c.Invalidate("key")
+	if err := c.Publish(ctx, "channel", nil); err == nil {
+		t.Fatal("published without redis")
+	}
+	if _, err := c.Subscribe(ctx, "channel"); err == nil {
+		t.Fatal("subscribed without redis")
+	}
+	if err := c.Ping(ctx); err == nil {
+		t.Fatal("pinged without redis")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
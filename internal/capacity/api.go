@@ -0,0 +1,213 @@
+package capacity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+const (
+	// dateLayout is the layout of the from and to query parameters
+	dateLayout = "2006-01-02"
+	// maxWindow caps the utilization window
+	maxWindow = 2 * 366 * 24 * time.Hour
+)
+
+// Handler serves the capacity routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the capacity routes through service, caching reads in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
+
+// Routes registers the capacity routes, mounted under /consultant
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/utilization", h.GetTeamUtilization)
+	r.Get("/holidays", h.GetHolidays)
+	r.Post("/holidays", h.CreateHoliday)
+	r.Delete("/holidays/{holidayID}", h.DeleteHoliday)
+	r.Get("/{consultantID}/utilization", h.GetConsultantUtilization)
+	r.Get("/{consultantID}/capacity", h.GetCapacity)
+	r.Put("/{consultantID}/capacity", h.UpdateCapacity)
+}
+
+// Gets the inclusive from and to dates from query parameters, defaulting to the last 12 weeks
+func getWindowFromRequest(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	to := time.Now().Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -12*7)
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("from", "invalid from, expected "+dateLayout))
+			return from, to, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("to", "invalid to, expected "+dateLayout))
+			return from, to, err
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxWindow {
+		problem.Write(w, r, apperror.Invalid("to", "to must not be before from and the window must not exceed 2 years"))
+		return from, to, fmt.Errorf("invalid window")
+	}
+	return from, to, nil
+}
+
+// Gets the utilization period from query parameters, defaulting to week
+func getPeriodFromRequest(r *http.Request) string {
+	if period := r.URL.Query().Get("period"); period != "" {
+		return period
+	}
+	return PeriodWeek
+}
+
+// GetConsultantUtilization returns logged credit hours over available hours of a consultant per period
+func (h *Handler) GetConsultantUtilization(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+	from, to, err := getWindowFromRequest(w, r)
+	if err != nil {
+		return
+	}
+	period := getPeriodFromRequest(r)
+
+	key := fmt.Sprintf("consultants:utilization:%d:%s:%s:%s", consultantID, from.Format(dateLayout), to.Format(dateLayout), period)
+	utilization, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) (*ConsultantUtilization, error) {
+		return h.service.GetConsultantUtilization(ctx, consultantID, from, to, period)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(utilization)
+}
+
+// GetTeamUtilization returns the utilization report of every consultant per period
+func (h *Handler) GetTeamUtilization(w http.ResponseWriter, r *http.Request) {
+	from, to, err := getWindowFromRequest(w, r)
+	if err != nil {
+		return
+	}
+	period := getPeriodFromRequest(r)
+
+	key := fmt.Sprintf("consultants:utilization:team:%s:%s:%s", from.Format(dateLayout), to.Format(dateLayout), period)
+	report, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) (*UtilizationReport, error) {
+		return h.service.GetTeamUtilization(ctx, from, to, period)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// GetCapacity returns the capacity of a consultant
+func (h *Handler) GetCapacity(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	capacity, err := h.service.GetCapacityByConsultantID(r.Context(), consultantID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(capacity)
+}
+
+// UpdateCapacity replaces the capacity of a consultant with the request body
+func (h *Handler) UpdateCapacity(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	var c ConsultantCapacity
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid capacity body"))
+		return
+	}
+	c.ConsultantID = consultantID
+
+	if err := h.service.UpsertCapacityByStruct(r.Context(), &c); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(c)
+}
+
+// GetHolidays returns the holidays within the window
+func (h *Handler) GetHolidays(w http.ResponseWriter, r *http.Request) {
+	from, to, err := getWindowFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	holidays, err := h.service.GetHolidaysInRange(r.Context(), from, to)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(holidays)
+}
+
+// CreateHoliday inserts a holiday from the request body
+func (h *Handler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	var holiday Holiday
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid holiday body"))
+		return
+	}
+
+	if err := h.service.InsertHolidayByStruct(r.Context(), &holiday); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(holiday)
+}
+
+// DeleteHoliday removes a holiday
+func (h *Handler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	holidayID, err := problem.IntParam(w, r, "holidayID")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteHolidayByID(r.Context(), holidayID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
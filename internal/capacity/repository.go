@@ -0,0 +1,208 @@
+package capacity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/consultant"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+const (
+	// DefaultWeeklyHours applies to consultants without a consultant_capacities row
+	DefaultWeeklyHours = 40
+	// DefaultPartTimePercent applies to consultants without a consultant_capacities row
+	DefaultPartTimePercent = 100
+)
+
+type Repository interface {
+	UpsertCapacityByStruct(ctx context.Context, c *entity.ConsultantCapacity) error
+	GetCapacityByConsultantID(ctx context.Context, consultantID int) (*entity.ConsultantCapacity, error)
+	InsertHolidayByStruct(ctx context.Context, h *entity.Holiday) error
+	GetHolidaysInRange(ctx context.Context, from, to time.Time) ([]entity.Holiday, error)
+	DeleteHolidayByID(ctx context.Context, holidayID int) error
+	GetUtilizationByConsultantIDS(ctx context.Context, consultantIDS []int, from, to time.Time, period string) ([]entity.UtilizationPeriod, error)
+	GetConsultantsByIDS(ctx context.Context, consultantIDS []int) ([]entity.Consultant, error)
+}
+
+type repository struct {
+	dbContext   *database.DBContext
+	consultants consultant.Repository
+	types       config.TimeEntryTypes
+	logger      *logger.Logger
+}
+
+// NewRepository returns a capacity repository counting hours of the credit type of types as logged
+func NewRepository(dbContext *database.DBContext, consultants consultant.Repository, types config.TimeEntryTypes, _l *logger.Logger) Repository {
+	return &repository{dbContext, consultants, types, _l}
+}
+
+// UpsertCapacityByStruct will insert or replace the capacity of a consultant
+func (r *repository) UpsertCapacityByStruct(ctx context.Context, c *entity.ConsultantCapacity) error {
+	ctx, span := tracing.Start(ctx, "capacity.repository.UpsertCapacityByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.NewQuery(`INSERT INTO consultant_capacities (consultant_id, weekly_hours, part_time_percent)
+			VALUES ({:consultant_id}, {:weekly_hours}, {:part_time_percent})
+			ON CONFLICT (consultant_id) DO UPDATE
+			SET weekly_hours = EXCLUDED.weekly_hours, part_time_percent = EXCLUDED.part_time_percent`).
+			Bind(dbx.Params{
+				"consultant_id":     c.ConsultantID,
+				"weekly_hours":      c.WeeklyHours,
+				"part_time_percent": c.PartTimePercent,
+			}).Execute()
+		return err
+	})
+}
+
+// GetCapacityByConsultantID will return the capacity of a consultant, falling back to the defaults
+// when none was recorded
+func (r *repository) GetCapacityByConsultantID(ctx context.Context, consultantID int) (*entity.ConsultantCapacity, error) {
+	ctx, span := tracing.Start(ctx, "capacity.repository.GetCapacityByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	c := entity.ConsultantCapacity{ConsultantID: consultantID}
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`SELECT c.id AS consultant_id,
+			COALESCE(cc.weekly_hours, {:weekly_hours}) AS weekly_hours,
+			COALESCE(cc.part_time_percent, {:part_time_percent}) AS part_time_percent
+		FROM consultants c
+		LEFT JOIN consultant_capacities cc ON cc.consultant_id = c.id
+		WHERE c.id = {:consultant_id}`).
+		Bind(dbx.Params{
+			"consultant_id":     consultantID,
+			"weekly_hours":      DefaultWeeklyHours,
+			"part_time_percent": DefaultPartTimePercent,
+		}).One(&c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("consultant %d not found", consultantID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// InsertHolidayByStruct will insert a holiday, replacing the name when the date already exists
+func (r *repository) InsertHolidayByStruct(ctx context.Context, h *entity.Holiday) error {
+	ctx, span := tracing.Start(ctx, "capacity.repository.InsertHolidayByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`INSERT INTO holidays (holiday_date, name)
+			VALUES ({:holiday_date}, {:name})
+			ON CONFLICT (holiday_date) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id`).
+			Bind(dbx.Params{"holiday_date": h.HolidayDate, "name": h.Name}).
+			Row(&h.ID)
+	})
+}
+
+// GetHolidaysInRange will return the holidays within [from, to]
+func (r *repository) GetHolidaysInRange(ctx context.Context, from, to time.Time) ([]entity.Holiday, error) {
+	ctx, span := tracing.Start(ctx, "capacity.repository.GetHolidaysInRange")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Holiday
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("holidays").
+		Where(dbx.Between("holiday_date", from, to)).
+		OrderBy("holiday_date ASC").
+		All(&list)
+	return list, err
+}
+
+// DeleteHolidayByID will delete a holiday by ID
+func (r *repository) DeleteHolidayByID(ctx context.Context, holidayID int) error {
+	ctx, span := tracing.Start(ctx, "capacity.repository.DeleteHolidayByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("holidays", dbx.HashExp{"id": holidayID}).Execute()
+		return err
+	})
+}
+
+// GetUtilizationByConsultantIDS will aggregate, per consultant and week or month, the available hours
+// (consultant_availability: weekdays without holidays or approved leave) and the logged credit hours.
+// An empty consultantIDS slice selects every consultant.
+func (r *repository) GetUtilizationByConsultantIDS(ctx context.Context, consultantIDS []int, from, to time.Time, period string) ([]entity.UtilizationPeriod, error) {
+	ctx, span := tracing.Start(ctx, "capacity.repository.GetUtilizationByConsultantIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.UtilizationPeriod
+
+	// Convert []int -> pq.Int64Array for = ANY
+	ids := make(pq.Int64Array, len(consultantIDS))
+	for i, id := range consultantIDS {
+		ids[i] = int64(id)
+	}
+
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH selected AS (
+			SELECT c.id AS consultant_id
+			FROM consultants c
+			WHERE cardinality({:ids}::bigint[]) = 0 OR c.id = ANY({:ids}::bigint[])
+		),
+		available AS (
+			SELECT av.consultant_id, date_trunc({:period}, av.day::timestamp)::date AS period_start,
+				SUM(av.available_hours) AS available_hours
+			FROM consultant_availability({:from}::date, {:to}::date, {:weekly_hours}, {:part_time_percent}) AS av
+			INNER JOIN selected sel ON sel.consultant_id = av.consultant_id
+			GROUP BY 1, 2
+		),
+		logged AS (
+			SELECT te.consultant_id, date_trunc({:period}, te.entry_date)::date AS period_start,
+				SUM(te.hours) AS logged_hours
+			FROM project_time_entries te
+			INNER JOIN selected sel ON sel.consultant_id = te.consultant_id
+			WHERE te.type = {:credit_type}
+				AND te.entry_date >= {:from}::date AND te.entry_date < {:to}::date + 1
+			GROUP BY 1, 2
+		)
+		SELECT COALESCE(a.consultant_id, l.consultant_id) AS consultant_id,
+			COALESCE(a.period_start, l.period_start) AS period_start,
+			COALESCE(a.available_hours, 0) AS available_hours,
+			COALESCE(l.logged_hours, 0) AS logged_hours,
+			CASE WHEN COALESCE(a.available_hours, 0) > 0
+				THEN COALESCE(l.logged_hours, 0) / a.available_hours
+				ELSE 0 END AS utilization
+		FROM available a
+		FULL OUTER JOIN logged l ON l.consultant_id = a.consultant_id AND l.period_start = a.period_start
+		ORDER BY 1, 2`).
+		Bind(dbx.Params{
+			"ids":               ids,
+			"from":              from,
+			"to":                to,
+			"period":            period,
+			"weekly_hours":      DefaultWeeklyHours,
+			"part_time_percent": DefaultPartTimePercent,
+			"credit_type":       r.types.Credit,
+		}).All(&list)
+	return list, err
+}
+
+// GetConsultantsByIDS will return the consultants with the given IDs, or every consultant when empty
+func (r *repository) GetConsultantsByIDS(ctx context.Context, consultantIDS []int) ([]entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "capacity.repository.GetConsultantsByIDS")
+	defer span.End()
+
+	if len(consultantIDS) == 0 {
+		return r.consultants.GetAllConsultants(ctx)
+	}
+	return r.consultants.GetConsultantDataByIDS(ctx, consultantIDS)
+}
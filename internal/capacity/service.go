@@ -0,0 +1,177 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// ErrInvalidCapacity is returned when capacity, holiday or utilization input fails validation
+var ErrInvalidCapacity = apperror.New(apperror.KindValidation, "invalid capacity")
+
+// Utilization periods accepted by date_trunc
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+type Service interface {
+	UpsertCapacityByStruct(ctx context.Context, c *ConsultantCapacity) error
+	GetCapacityByConsultantID(ctx context.Context, consultantID int) (*ConsultantCapacity, error)
+	InsertHolidayByStruct(ctx context.Context, h *Holiday) error
+	GetHolidaysInRange(ctx context.Context, from, to time.Time) ([]Holiday, error)
+	DeleteHolidayByID(ctx context.Context, holidayID int) error
+	GetConsultantUtilization(ctx context.Context, consultantID int, from, to time.Time, period string) (*ConsultantUtilization, error)
+	GetTeamUtilization(ctx context.Context, from, to time.Time, period string) (*UtilizationReport, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type ConsultantCapacity struct {
+	entity.ConsultantCapacity
+}
+
+type Holiday struct {
+	entity.Holiday
+}
+
+type ConsultantUtilization struct {
+	entity.ConsultantUtilization
+}
+
+type UtilizationReport struct {
+	entity.UtilizationReport
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+func (s *service) UpsertCapacityByStruct(ctx context.Context, c *ConsultantCapacity) error {
+	if c.WeeklyHours < 0 || c.WeeklyHours > 168 {
+		return ErrInvalidCapacity.WithField("weeklyHours", "weeklyHours must be between 0 and 168")
+	}
+	if c.PartTimePercent < 0 || c.PartTimePercent > 100 {
+		return ErrInvalidCapacity.WithField("partTimePercent", "partTimePercent must be between 0 and 100")
+	}
+	return s.repo.UpsertCapacityByStruct(ctx, &c.ConsultantCapacity)
+}
+
+func (s *service) GetCapacityByConsultantID(ctx context.Context, consultantID int) (*ConsultantCapacity, error) {
+	c, err := s.repo.GetCapacityByConsultantID(ctx, consultantID)
+	if err != nil {
+		return &ConsultantCapacity{}, err
+	}
+	return &ConsultantCapacity{*c}, nil
+}
+
+func (s *service) InsertHolidayByStruct(ctx context.Context, h *Holiday) error {
+	h.Name = strings.TrimSpace(h.Name)
+	if h.Name == "" {
+		return ErrInvalidCapacity.WithField("name", "name is required")
+	}
+	if h.HolidayDate.IsZero() {
+		return ErrInvalidCapacity.WithField("holidayDate", "holidayDate is required")
+	}
+	return s.repo.InsertHolidayByStruct(ctx, &h.Holiday)
+}
+
+func (s *service) GetHolidaysInRange(ctx context.Context, from, to time.Time) ([]Holiday, error) {
+	holidays, err := s.repo.GetHolidaysInRange(ctx, from, to)
+	if err != nil {
+		return []Holiday{}, err
+	}
+	results := []Holiday{}
+	for _, h := range holidays {
+		results = append(results, Holiday{h})
+	}
+	return results, nil
+}
+
+func (s *service) DeleteHolidayByID(ctx context.Context, holidayID int) error {
+	return s.repo.DeleteHolidayByID(ctx, holidayID)
+}
+
+// validatePeriod rejects anything date_trunc should not receive
+func validatePeriod(period string) error {
+	if period != PeriodWeek && period != PeriodMonth {
+		return ErrInvalidCapacity.WithField("period", fmt.Sprintf("period must be %s or %s", PeriodWeek, PeriodMonth))
+	}
+	return nil
+}
+
+// summarize folds SQL-aggregated periods into one utilization per consultant, in consultant order
+func summarize(consultants []entity.Consultant, periods []entity.UtilizationPeriod) []entity.ConsultantUtilization {
+	byConsultant := make(map[int]*entity.ConsultantUtilization, len(consultants))
+	results := make([]entity.ConsultantUtilization, len(consultants))
+	for i, c := range consultants {
+		results[i] = entity.ConsultantUtilization{Consultant: c, Periods: []entity.UtilizationPeriod{}}
+		byConsultant[c.ID] = &results[i]
+	}
+
+	for _, p := range periods {
+		u, ok := byConsultant[p.ConsultantID]
+		if !ok {
+			continue
+		}
+		u.Periods = append(u.Periods, p)
+		u.AvailableHours += p.AvailableHours
+		u.LoggedHours += p.LoggedHours
+	}
+
+	for i := range results {
+		if results[i].AvailableHours > 0 {
+			results[i].Utilization = results[i].LoggedHours / results[i].AvailableHours
+		}
+	}
+	return results
+}
+
+// GetConsultantUtilization returns the utilization of one consultant per week or month
+func (s *service) GetConsultantUtilization(ctx context.Context, consultantID int, from, to time.Time, period string) (*ConsultantUtilization, error) {
+	if err := validatePeriod(period); err != nil {
+		return &ConsultantUtilization{}, err
+	}
+	consultants, err := s.repo.GetConsultantsByIDS(ctx, []int{consultantID})
+	if err != nil {
+		return &ConsultantUtilization{}, err
+	}
+	if len(consultants) == 0 {
+		return &ConsultantUtilization{}, apperror.NotFound("consultant %d not found", consultantID)
+	}
+	periods, err := s.repo.GetUtilizationByConsultantIDS(ctx, []int{consultantID}, from, to, period)
+	if err != nil {
+		return &ConsultantUtilization{}, err
+	}
+	return &ConsultantUtilization{summarize(consultants, periods)[0]}, nil
+}
+
+// GetTeamUtilization returns the utilization of every consultant per week or month
+func (s *service) GetTeamUtilization(ctx context.Context, from, to time.Time, period string) (*UtilizationReport, error) {
+	if err := validatePeriod(period); err != nil {
+		return &UtilizationReport{}, err
+	}
+	consultants, err := s.repo.GetConsultantsByIDS(ctx, nil)
+	if err != nil {
+		return &UtilizationReport{}, err
+	}
+	periods, err := s.repo.GetUtilizationByConsultantIDS(ctx, nil, from, to, period)
+	if err != nil {
+		return &UtilizationReport{}, err
+	}
+	return &UtilizationReport{entity.UtilizationReport{
+		From:        from,
+		To:          to,
+		Period:      period,
+		Consultants: summarize(consultants, periods),
+	}}, nil
+}
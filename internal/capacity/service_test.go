@@ -0,0 +1,123 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// fakeRepository knows consultants 1 and 2, with the utilization periods it is given
+type fakeRepository struct {
+	Repository
+	periods []entity.UtilizationPeriod
+	saved   int
+}
+
+func (f *fakeRepository) UpsertCapacityByStruct(ctx context.Context, c *entity.ConsultantCapacity) error {
+	f.saved++
+	return nil
+}
+
+func (f *fakeRepository) InsertHolidayByStruct(ctx context.Context, h *entity.Holiday) error {
+	f.saved++
+	return nil
+}
+
+func (f *fakeRepository) GetConsultantsByIDS(ctx context.Context, consultantIDS []int) ([]entity.Consultant, error) {
+	consultants := []entity.Consultant{}
+	for _, id := range []int{1, 2} {
+		if consultantIDS == nil || consultantIDS[0] == id {
+			consultants = append(consultants, entity.Consultant{ID: id})
+		}
+	}
+	return consultants, nil
+}
+
+func (f *fakeRepository) GetUtilizationByConsultantIDS(ctx context.Context, consultantIDS []int, from, to time.Time, period string) ([]entity.UtilizationPeriod, error) {
+	return f.periods, nil
+}
+
+func newTestService(repo Repository) Service {
+	return NewService(repo, logger.New().Prefix("Capacity Test"))
+}
+
+func TestUpsertCapacityValidates(t *testing.T) {
+	tests := []struct {
+		capacity entity.ConsultantCapacity
+		wantErr  bool
+	}{
+		{entity.ConsultantCapacity{ConsultantID: 1, WeeklyHours: 40, PartTimePercent: 100}, false},
+		{entity.ConsultantCapacity{ConsultantID: 1, WeeklyHours: 0, PartTimePercent: 0}, false},
+		{entity.ConsultantCapacity{ConsultantID: 1, WeeklyHours: -1, PartTimePercent: 100}, true},
+		{entity.ConsultantCapacity{ConsultantID: 1, WeeklyHours: 169, PartTimePercent: 100}, true},
+		{entity.ConsultantCapacity{ConsultantID: 1, WeeklyHours: 40, PartTimePercent: 101}, true},
+	}
+	for _, tt := range tests {
+		repo := &fakeRepository{}
+		err := newTestService(repo).UpsertCapacityByStruct(context.Background(), &ConsultantCapacity{tt.capacity})
+		if tt.wantErr != errors.Is(err, ErrInvalidCapacity) || (tt.wantErr && repo.saved != 0) {
+			t.Errorf("%+v: err = %v, saved = %d", tt.capacity, err, repo.saved)
+		}
+	}
+}
+
+func TestInsertHolidayValidates(t *testing.T) {
+	day := time.Date(2026, 12, 25, 0, 0, 0, 0, time.UTC)
+	for _, h := range []entity.Holiday{{Name: " ", HolidayDate: day}, {Name: "Christmas"}} {
+		if err := newTestService(&fakeRepository{}).InsertHolidayByStruct(context.Background(), &Holiday{h}); !errors.Is(err, ErrInvalidCapacity) {
+			t.Errorf("%+v: err = %v, want ErrInvalidCapacity", h, err)
+		}
+	}
+
+	h := &Holiday{entity.Holiday{Name: " Christmas ", HolidayDate: day}}
+	if err := newTestService(&fakeRepository{}).InsertHolidayByStruct(context.Background(), h); err != nil || h.Name != "Christmas" {
+		t.Fatalf("err = %v, name = %q", err, h.Name)
+	}
+}
+
+func TestGetTeamUtilizationSummarizesPeriods(t *testing.T) {
+	week := time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepository{periods: []entity.UtilizationPeriod{
+		{ConsultantID: 1, PeriodStart: week, AvailableHours: 40, LoggedHours: 30},
+		{ConsultantID: 1, PeriodStart: week.AddDate(0, 0, 7), AvailableHours: 32, LoggedHours: 42},
+		{ConsultantID: 9, PeriodStart: week, AvailableHours: 40, LoggedHours: 40}, // unknown consultant
+	}}
+
+	report, err := newTestService(repo).GetTeamUtilization(context.Background(), week, week.AddDate(0, 0, 14), PeriodWeek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Consultants) != 2 {
+		t.Fatalf("consultants = %d, want 2", len(report.Consultants))
+	}
+
+	busy, idle := report.Consultants[0], report.Consultants[1]
+	if len(busy.Periods) != 2 || busy.AvailableHours != 72 || busy.LoggedHours != 72 || busy.Utilization != 1 {
+		t.Fatalf("consultant 1 = %+v, want 72 of 72 hours over 2 weeks", busy)
+	}
+	// without available hours utilization stays 0 instead of dividing by zero
+	if len(idle.Periods) != 0 || idle.Utilization != 0 {
+		t.Fatalf("consultant 2 = %+v, want no periods", idle)
+	}
+}
+
+func TestGetConsultantUtilization(t *testing.T) {
+	s := newTestService(&fakeRepository{})
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := s.GetConsultantUtilization(context.Background(), 1, from, from.AddDate(0, 1, 0), "day"); !errors.Is(err, ErrInvalidCapacity) {
+		t.Fatalf("err = %v, want ErrInvalidCapacity for period day", err)
+	}
+	if _, err := s.GetConsultantUtilization(context.Background(), 3, from, from.AddDate(0, 1, 0), PeriodMonth); apperror.KindOf(err) != apperror.KindNotFound {
+		t.Fatalf("err = %v, want not found for an unknown consultant", err)
+	}
+	u, err := s.GetConsultantUtilization(context.Background(), 2, from, from.AddDate(0, 1, 0), PeriodMonth)
+	if err != nil || u.Consultant.ID != 2 {
+		t.Fatalf("utilization = %+v, err = %v", u, err)
+	}
+}
@@ -0,0 +1,55 @@
+package changefeed
+
+import (
+	"context"
+	"time"
+)
+
+// Debounce collapses bursts of changes into batches. A batch is emitted once no change arrived for
+// quiet, or at the latest maxDelay after its first change so a steady stream of writes still produces
+// updates. The returned channel is closed when changes is closed or ctx is done.
+func Debounce(ctx context.Context, changes <-chan Change, quiet, maxDelay time.Duration) <-chan []Change {
+	batches := make(chan []Change)
+	go func() {
+		defer close(batches)
+
+		var (
+			batch    []Change
+			timer    *time.Timer
+			deadline time.Time
+			fire     <-chan time.Time // nil while no batch is pending
+		)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case change, ok := <-changes:
+				if !ok {
+					return
+				}
+				if len(batch) == 0 {
+					deadline = time.Now().Add(maxDelay)
+				}
+				batch = append(batch, change)
+
+				wait := min(quiet, time.Until(deadline))
+				if timer == nil {
+					timer = time.NewTimer(wait)
+				} else {
+					timer.Reset(wait)
+				}
+				fire = timer.C
+			case <-fire:
+				select {
+				case batches <- batch:
+				case <-ctx.Done():
+					return
+				}
+				batch = nil
+				fire = nil
+			}
+		}
+	}()
+	return batches
+}
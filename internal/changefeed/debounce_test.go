@@ -0,0 +1,86 @@
+package changefeed
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// receive waits up to timeout for the next batch
+func receive(t *testing.T, batches <-chan []Change, timeout time.Duration) ([]Change, bool) {
+	t.Helper()
+	select {
+	case batch, ok := <-batches:
+		return batch, ok
+	case <-time.After(timeout):
+		return nil, false
+	}
+}
+
+func TestDebounceCollapsesBursts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan Change)
+	batches := Debounce(ctx, changes, 50*time.Millisecond, time.Second)
+
+	for _, table := range []string{"projects", "project_statuses", "project_time_entries"} {
+		changes <- Change{Table: table, Op: "INSERT"}
+	}
+	batch, ok := receive(t, batches, time.Second)
+	if !ok || len(batch) != 3 || batch[0].Table != "projects" {
+		t.Fatalf("batch = %+v, want the 3 changes of the burst", batch)
+	}
+
+	// the next change starts a new batch
+	changes <- Change{Table: "projects", Op: "UPDATE"}
+	if batch, ok := receive(t, batches, time.Second); !ok || len(batch) != 1 {
+		t.Fatalf("batch = %+v, want 1 change", batch)
+	}
+}
+
+func TestDebounceEmitsWithinMaxDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan Change)
+	batches := Debounce(ctx, changes, 100*time.Millisecond, 300*time.Millisecond)
+
+	// a change every 20ms never leaves a quiet period, the batch still goes out after maxDelay
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				select {
+				case changes <- Change{Table: "project_time_entries", Op: "INSERT"}:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	if _, ok := receive(t, batches, 2*time.Second); !ok {
+		t.Fatal("no batch while changes kept arriving")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("batch after %s, want about 300ms", elapsed)
+	}
+}
+
+func TestDebounceClosesWithChanges(t *testing.T) {
+	changes := make(chan Change)
+	batches := Debounce(context.Background(), changes, time.Hour, time.Hour)
+	close(changes)
+
+	if _, ok := receive(t, batches, time.Second); ok {
+		t.Fatal("batches still open after changes closed")
+	}
+}
@@ -0,0 +1,85 @@
+package changefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+)
+
+// Channel is the NOTIFY channel written by the notify_project_list_change trigger
+const Channel = "project_list_changes"
+
+// TableAll marks a change of unknown scope, sent after the listener reconnected and notifications may have been missed
+const TableAll = "*"
+
+const (
+	// minReconnect and maxReconnect bound the reconnect backoff of the listener
+	minReconnect = 10 * time.Second
+	maxReconnect = time.Minute
+	// pingInterval checks an idle listener connection
+	pingInterval = 90 * time.Second
+	// bufferSize of the change channel, changes are dropped while it is full
+	bufferSize = 64
+)
+
+// Change is one write statement reported by the change feed trigger
+type Change struct {
+	Table string `json:"table"`
+	Op    string `json:"op"`
+}
+
+// Listen opens a dedicated LISTEN connection and delivers changes until ctx is done. While the
+// consumer lags behind changes are dropped, every consumer recomputes from the database anyway.
+func Listen(ctx context.Context, dbContext *database.DBContext, l *logger.Logger) (<-chan Change, error) {
+	dsn := dbContext.DSN()
+	if dsn == "" {
+		return nil, fmt.Errorf("%s not set", dbContext.EnvVar())
+	}
+
+	listener := pq.NewListener(dsn, minReconnect, maxReconnect, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			l.WarningF("Change feed listener event %d: %s", event, err.Error())
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	changes := make(chan Change, bufferSize)
+	go func() {
+		defer close(changes)
+		defer listener.Close()
+
+		ping := time.NewTicker(pingInterval)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				go func() { _ = listener.Ping() }()
+			case n := <-listener.Notify:
+				// nil after a reconnect, anything may have changed in between
+				change := Change{Table: TableAll}
+				if n != nil {
+					if err := json.Unmarshal([]byte(n.Extra), &change); err != nil {
+						l.WarningF("Ignoring malformed change %q: %s", n.Extra, err.Error())
+						continue
+					}
+				}
+				select {
+				case changes <- change:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
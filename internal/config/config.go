@@ -0,0 +1,345 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config is the whole application configuration. Every leaf is set, in increasing precedence, by its
+// default, the YAML file, the env variable of its env tag (.env included) and the command line flag
+// named after its yaml path, e.g. -redis.host. Fields tagged secret are redacted by Print.
+type Config struct {
+	Server         Server         `yaml:"server"`
+	Database       Database       `yaml:"database"`
+	Redis          Redis          `yaml:"redis"`
+	CORS           CORS           `yaml:"cors"`
+	Pagination     Pagination     `yaml:"pagination"`
+	Demo           Demo           `yaml:"demo"`
+	Leave          Leave          `yaml:"leave"`
+	Dashboard      Dashboard      `yaml:"dashboard"`
+	TimeEntryTypes TimeEntryTypes `yaml:"timeEntryTypes" env:"TIME_ENTRY_TYPE"`
+	Auth           Auth           `yaml:"auth"`
+	Notifications  Notifications  `yaml:"notifications"`
+	Tracing        Tracing        `yaml:"tracing"`
+}
+
+// Server configures the http server and its lifecycle
+type Server struct {
+	Addr string `yaml:"addr" env:"SERVER_ADDR"`
+	// ConnectTimeout bounds connecting to postgres and redis at startup
+	ConnectTimeout time.Duration `yaml:"connectTimeout" env:"SERVER_CONNECT_TIMEOUT"`
+	// DrainDelay lets readiness probes observe stopping before the listener closes
+	DrainDelay time.Duration `yaml:"drainDelay" env:"SERVER_DRAIN_DELAY"`
+	// ShutdownTimeout bounds waiting for in-flight requests, and then for background workers
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+}
+
+// Database holds the DSN of every database profile, the automatic profile uses the first reachable
+// of production, docker and developer
+type Database struct {
+	ProductionDSN string `yaml:"productionDSN" env:"PROD_POSTGRES_DSN" secret:"true"`
+	DockerDSN     string `yaml:"dockerDSN" env:"DOCKER_POSTGRES_DSN" secret:"true"`
+	DeveloperDSN  string `yaml:"developerDSN" env:"DEV_POSTGRES_DSN" secret:"true"`
+	TestingDSN    string `yaml:"testingDSN" env:"TEST_POSTGRES_DSN" secret:"true"`
+	// StatementTimeout cancels repository calls and statements on the primary running longer, 0 disables it
+	StatementTimeout time.Duration `yaml:"statementTimeout" env:"POSTGRES_STATEMENT_TIMEOUT"`
+	Pool             Pool          `yaml:"pool" env:"POSTGRES_POOL"`
+	Replica          Replica       `yaml:"replica" env:"REPLICA"`
+}
+
+// Pool configures the connection pool of every database connection
+type Pool struct {
+	MaxOpenConns    int           `yaml:"maxOpenConns" env:"MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" env:"MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" env:"CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" env:"CONN_MAX_IDLE_TIME"`
+}
+
+// Replica configures an optional read replica. Heavy reads that tolerate lag use it while its
+// replication lag stays within MaxLag, and the primary otherwise.
+type Replica struct {
+	DSN string `yaml:"dsn" env:"POSTGRES_DSN" secret:"true"`
+	// StatementTimeout cancels repository calls and statements on the replica running longer, 0 disables it
+	StatementTimeout time.Duration `yaml:"statementTimeout" env:"STATEMENT_TIMEOUT"`
+	MaxLag           time.Duration `yaml:"maxLag" env:"MAX_LAG"`
+	// LagInterval between replication lag checks
+	LagInterval time.Duration `yaml:"lagInterval" env:"LAG_INTERVAL"`
+}
+
+// Redis configures the redis connection and the cache stored in it
+type Redis struct {
+	Host     string `yaml:"host" env:"REDIS_HOST"`
+	Port     int    `yaml:"port" env:"REDIS_PORT"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `yaml:"db" env:"REDIS_DB"`
+	// TTL of cached values
+	TTL time.Duration `yaml:"ttl" env:"CACHE_TTL"`
+}
+
+// CORS configures the cross-origin policy of every route group
+type CORS struct {
+	// API applies to the application routes, its allowed origins also authorize the /events WebSocket
+	API CORSPolicy `yaml:"api" env:"CORS_API"`
+	// Public applies to the health, readiness and metrics routes
+	Public CORSPolicy `yaml:"public" env:"CORS_PUBLIC"`
+}
+
+// CORSPolicy is the cross-origin policy of a route group
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins, origins with a wildcard subdomain such as https://*.example.com,
+	// or "*" for any origin
+	AllowedOrigins []string `yaml:"allowedOrigins" env:"ALLOWED_ORIGINS"`
+	AllowedMethods []string `yaml:"allowedMethods" env:"ALLOWED_METHODS"`
+	// AllowedHeaders are the request headers allowed by preflights, "*" allows any
+	AllowedHeaders []string `yaml:"allowedHeaders" env:"ALLOWED_HEADERS"`
+	// ExposedHeaders are the response headers readable by scripts
+	ExposedHeaders []string `yaml:"exposedHeaders" env:"EXPOSED_HEADERS"`
+	// AllowCredentials lets browsers send cookies and authorization, it requires explicit origins
+	AllowCredentials bool `yaml:"allowCredentials" env:"ALLOW_CREDENTIALS"`
+	// MaxAge is how long browsers cache preflight results, 0 disables caching
+	MaxAge time.Duration `yaml:"maxAge" env:"MAX_AGE"`
+}
+
+// Pagination sets the page sizes of paginated project listings
+type Pagination struct {
+	ProjectPageSize int `yaml:"projectPageSize" env:"PROJECT_PAGE_SIZE"`
+	SearchPageSize  int `yaml:"searchPageSize" env:"SEARCH_PAGE_SIZE"`
+}
+
+// Demo configures demo data seeding
+type Demo struct {
+	Seed bool `yaml:"seed" env:"DEMO_DATA"`
+}
+
+// Leave configures leave and holidays
+type Leave struct {
+	// HolidayCalendarICS is an optional iCalendar file of public holidays imported at startup
+	HolidayCalendarICS string `yaml:"holidayCalendarICS" env:"HOLIDAY_CALENDAR_ICS"`
+}
+
+// Dashboard configures the default dashboard computation, requests may override it
+type Dashboard struct {
+	IdleDays       int `yaml:"idleDays" env:"DASHBOARD_IDLE_DAYS"`
+	EndingSoonDays int `yaml:"endingSoonDays" env:"DASHBOARD_ENDING_SOON_DAYS"`
+	// StatusBuckets maps status titles to active, completed, idle or other, in env as title:bucket pairs
+	StatusBuckets map[string]string `yaml:"statusBuckets" env:"DASHBOARD_STATUS_BUCKETS"`
+}
+
+// TimeEntryTypes names the time entry types counted as debit and credit hours. The dashboard, task
+// actuals, utilization, allocations and notifications all count hours with them. Leave entries record
+// hours away and are never billable.
+type TimeEntryTypes struct {
+	Debit  string `yaml:"debit" env:"DEBIT"`
+	Credit string `yaml:"credit" env:"CREDIT"`
+	Leave  string `yaml:"leave" env:"LEAVE"`
+}
+
+// Auth configures API authentication
+type Auth struct {
+	// APITokens accepted by the /events WebSocket, every connection is rejected while empty
+	APITokens []string `yaml:"apiTokens" env:"API_TOKENS" secret:"true"`
+}
+
+// Notifications configures email notifications
+type Notifications struct {
+	// Transport is smtp, file (appends to File) or log
+	Transport string `yaml:"transport" env:"NOTIFY_TRANSPORT"`
+	File      string `yaml:"file" env:"NOTIFY_FILE"`
+	SMTP      SMTP   `yaml:"smtp"`
+	// DigestHour is the hour of the day, server time, daily digests are sent at
+	DigestHour int `yaml:"digestHour" env:"NOTIFY_DIGEST_HOUR"`
+	// OnHoldStatus is the status title that puts a project on hold
+	OnHoldStatus string `yaml:"onHoldStatus" env:"NOTIFY_ON_HOLD_STATUS"`
+}
+
+// SMTP configures the smtp transport, it authenticates when Username is set
+type SMTP struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+// Tracing selects the trace exporter, the otlp exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* variables
+type Tracing struct {
+	// Exporter is otlp, file (JSON lines to File) or none
+	Exporter string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER"`
+	File     string `yaml:"file" env:"OTEL_TRACES_FILE"`
+}
+
+// Default returns the configuration used for everything not configured
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:            ":8081",
+			ConnectTimeout:  time.Minute,
+			DrainDelay:      5 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+		},
+		Database: Database{
+			StatementTimeout: 30 * time.Second,
+			Pool: Pool{
+				MaxOpenConns:    25,
+				MaxIdleConns:    10,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
+			},
+			Replica: Replica{
+				StatementTimeout: time.Minute,
+				MaxLag:           10 * time.Second,
+				LagInterval:      5 * time.Second,
+			},
+		},
+		Redis: Redis{
+			Host: "localhost",
+			Port: 6379,
+			TTL:  5 * time.Minute,
+		},
+		CORS: CORS{
+			API: CORSPolicy{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+				AllowedHeaders: []string{"Authorization", "Content-Type"},
+				ExposedHeaders: []string{"ETag", "Link", "X-Total-Count"},
+				MaxAge:         10 * time.Minute,
+			},
+			Public: CORSPolicy{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "OPTIONS"},
+				MaxAge:         10 * time.Minute,
+			},
+		},
+		Pagination: Pagination{
+			ProjectPageSize: 10,
+			SearchPageSize:  20,
+		},
+		Demo: Demo{
+			Seed: true,
+		},
+		Dashboard: Dashboard{
+			IdleDays:       7,
+			EndingSoonDays: 7,
+			StatusBuckets: map[string]string{
+				"active":    "active",
+				"completed": "completed",
+			},
+		},
+		TimeEntryTypes: TimeEntryTypes{
+			Debit:  "debit",
+			Credit: "credit",
+			Leave:  "leave",
+		},
+		Notifications: Notifications{
+			Transport:    "log",
+			File:         "notifications.eml",
+			SMTP:         SMTP{Port: 587},
+			DigestHour:   8,
+			OnHoldStatus: "on-hold",
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+			File:     "traces.jsonl",
+		},
+	}
+}
+
+// maxPageSize caps the configurable page sizes
+const maxPageSize = 100
+
+// Validate reports every invalid setting
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, a ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, a...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	check(c.Server.ConnectTimeout > 0, "server.connectTimeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drainDelay must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+
+	check(c.Database.ProductionDSN != "" || c.Database.DockerDSN != "" || c.Database.DeveloperDSN != "",
+		"one of database.productionDSN, database.dockerDSN or database.developerDSN is required")
+
+	check(c.Database.StatementTimeout >= 0, "database.statementTimeout must not be negative")
+	check(c.Database.Pool.MaxOpenConns >= 0, "database.pool.maxOpenConns must not be negative, 0 is unlimited")
+	check(c.Database.Pool.MaxIdleConns >= 0, "database.pool.maxIdleConns must not be negative")
+	check(c.Database.Pool.MaxOpenConns == 0 || c.Database.Pool.MaxIdleConns <= c.Database.Pool.MaxOpenConns,
+		"database.pool.maxIdleConns must not exceed database.pool.maxOpenConns")
+	check(c.Database.Pool.ConnMaxLifetime >= 0 && c.Database.Pool.ConnMaxIdleTime >= 0,
+		"database.pool.connMaxLifetime and database.pool.connMaxIdleTime must not be negative, 0 keeps connections forever")
+	check(c.Database.Replica.StatementTimeout >= 0, "database.replica.statementTimeout must not be negative")
+	check(c.Database.Replica.MaxLag > 0, "database.replica.maxLag must be positive")
+	check(c.Database.Replica.LagInterval > 0, "database.replica.lagInterval must be positive")
+
+	check(c.Redis.Host != "", "redis.host is required")
+	check(c.Redis.Port > 0 && c.Redis.Port < 65536, "redis.port %d is not a port", c.Redis.Port)
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+	check(c.Redis.TTL > 0, "redis.ttl must be positive")
+
+	for name, policy := range map[string]CORSPolicy{"cors.api": c.CORS.API, "cors.public": c.CORS.Public} {
+		for _, origin := range policy.AllowedOrigins {
+			check(origin == "*" || validOrigin(origin), "%s.allowedOrigins: %q is not an origin such as https://app.example.com or https://*.example.com", name, origin)
+			check(!(origin == "*" && policy.AllowCredentials), "%s.allowCredentials requires explicit allowedOrigins instead of *", name)
+		}
+		check(len(policy.AllowedMethods) > 0, "%s.allowedMethods is required", name)
+		check(policy.MaxAge >= 0, "%s.maxAge must not be negative", name)
+	}
+
+	check(c.Pagination.ProjectPageSize > 0 && c.Pagination.ProjectPageSize <= maxPageSize,
+		"pagination.projectPageSize must be between 1 and %d", maxPageSize)
+	check(c.Pagination.SearchPageSize > 0 && c.Pagination.SearchPageSize <= maxPageSize,
+		"pagination.searchPageSize must be between 1 and %d", maxPageSize)
+
+	check(c.Dashboard.IdleDays >= 0, "dashboard.idleDays must not be negative")
+	check(c.Dashboard.EndingSoonDays >= 0, "dashboard.endingSoonDays must not be negative")
+	for title, bucket := range c.Dashboard.StatusBuckets {
+		check(bucket == "active" || bucket == "completed" || bucket == "idle" || bucket == "other",
+			"dashboard.statusBuckets: %q maps to %q, expected active, completed, idle or other", title, bucket)
+	}
+
+	check(c.TimeEntryTypes.Debit != "" && c.TimeEntryTypes.Credit != "", "timeEntryTypes.debit and timeEntryTypes.credit are required")
+	check(c.TimeEntryTypes.Debit != c.TimeEntryTypes.Credit, "timeEntryTypes.debit and timeEntryTypes.credit must differ")
+	check(c.TimeEntryTypes.Leave != "", "timeEntryTypes.leave is required")
+	check(c.TimeEntryTypes.Leave != c.TimeEntryTypes.Debit && c.TimeEntryTypes.Leave != c.TimeEntryTypes.Credit,
+		"timeEntryTypes.leave must differ from debit and credit, leave is never billable")
+
+	switch c.Notifications.Transport {
+	case "smtp":
+		check(c.Notifications.SMTP.Host != "" && c.Notifications.SMTP.From != "",
+			"notifications.smtp.host and notifications.smtp.from are required by the smtp transport")
+		check(c.Notifications.SMTP.Port > 0 && c.Notifications.SMTP.Port < 65536,
+			"notifications.smtp.port %d is not a port", c.Notifications.SMTP.Port)
+	case "file":
+		check(c.Notifications.File != "", "notifications.file is required by the file transport")
+	case "log":
+	default:
+		check(false, "notifications.transport %q, expected smtp, file or log", c.Notifications.Transport)
+	}
+	check(c.Notifications.DigestHour >= 0 && c.Notifications.DigestHour <= 23, "notifications.digestHour must be between 0 and 23")
+	check(c.Notifications.OnHoldStatus != "", "notifications.onHoldStatus is required")
+
+	switch c.Tracing.Exporter {
+	case "otlp", "none":
+	case "file":
+		check(c.Tracing.File != "", "tracing.file is required by the file exporter")
+	default:
+		check(false, "tracing.exporter %q, expected otlp, file or none", c.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validOrigin reports whether origin is a scheme and host without path, the host may start with a
+// wildcard subdomain
+func validOrigin(origin string) bool {
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
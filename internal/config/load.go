@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v2"
+)
+
+// FileEnv names the YAML configuration file when the -config flag is not given
+const FileEnv = "CONFIG_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load resolves the configuration from the defaults, the YAML file, the environment and .env, and
+// the flags in args, in increasing precedence. It does not validate the result.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	file := fs.String("config", "", "YAML configuration file, defaults to $"+FileEnv)
+	// flags are applied after the file and the environment, keep them in order until then
+	type override struct{ path, value string }
+	var overrides []override
+	walk(reflect.ValueOf(&cfg).Elem(), "", "", func(path, _ string, _ reflect.StructField, _ reflect.Value) {
+		fs.Func(path, "", func(value string) error {
+			overrides = append(overrides, override{path, value})
+			return nil
+		})
+	})
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	// variables already in the environment take precedence over .env
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, fmt.Errorf("loading .env: %w", err)
+	}
+
+	path := *file
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("reading configuration file: %w", err)
+		}
+		// yaml merges into maps, clear them so the file replaces them like every other setting
+		defaults := map[string]reflect.Value{}
+		walk(reflect.ValueOf(&cfg).Elem(), "", "", func(path, _ string, _ reflect.StructField, v reflect.Value) {
+			if v.Kind() == reflect.Map {
+				defaults[path] = reflect.ValueOf(v.Interface())
+				v.SetZero()
+			}
+		})
+		if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		walk(reflect.ValueOf(&cfg).Elem(), "", "", func(path, _ string, _ reflect.StructField, v reflect.Value) {
+			if d, ok := defaults[path]; ok && v.IsNil() {
+				v.Set(d)
+			}
+		})
+	}
+
+	var errs []error
+	walk(reflect.ValueOf(&cfg).Elem(), "", "", func(_, env string, _ reflect.StructField, v reflect.Value) {
+		if env == "" {
+			return
+		}
+		if value, ok := os.LookupEnv(env); ok && value != "" {
+			if err := set(v, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", env, err))
+			}
+		}
+	})
+	leaves := map[string]reflect.Value{}
+	walk(reflect.ValueOf(&cfg).Elem(), "", "", func(path, _ string, _ reflect.StructField, v reflect.Value) {
+		leaves[path] = v
+	})
+	for _, o := range overrides {
+		if err := set(leaves[o.path], o.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", o.path, err))
+		}
+	}
+	return cfg, errors.Join(errs...)
+}
+
+// walk calls fn for every leaf setting of v, with its dotted yaml path and env variable. The env tag
+// of a struct setting prefixes the env variables of its leaves, so a settings type can be reused.
+func walk(v reflect.Value, prefix, envPrefix string, fn func(path, env string, field reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		path := prefix + name
+		env := field.Tag.Get("env")
+		if env != "" {
+			env = envPrefix + env
+		}
+		if field.Type.Kind() == reflect.Struct {
+			childPrefix := envPrefix
+			if env != "" {
+				childPrefix = env + "_"
+			}
+			walk(v.Field(i), path+".", childPrefix, fn)
+			continue
+		}
+		fn(path, env, field, v.Field(i))
+	}
+}
+
+// set parses value into the setting v. Lists are comma separated and maps are comma separated
+// key:value pairs, both replace the whole setting.
+func set(v reflect.Value, value string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice:
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case v.Kind() == reflect.Map:
+		m := map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid %q, expected key:value pairs", value)
+			}
+			m[key] = val
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
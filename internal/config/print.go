@@ -0,0 +1,61 @@
+package config
+
+import (
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// redacted replaces the value of secrets that are set
+const redacted = "[redacted]"
+
+// Print writes cfg as YAML, in the layout of the configuration file, with secrets redacted
+func Print(w io.Writer, cfg Config) error {
+	out, err := yaml.Marshal(tree(reflect.ValueOf(cfg)))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// tree renders a settings struct as an ordered YAML mapping
+func tree(v reflect.Value) yaml.MapSlice {
+	t := v.Type()
+	out := yaml.MapSlice{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		fv := v.Field(i)
+
+		var value any
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			value = tree(fv)
+		case field.Tag.Get("secret") == "true" && !fv.IsZero() && fv.Len() > 0:
+			value = redacted
+		case fv.Type() == durationType:
+			value = fv.Interface().(interface{ String() string }).String()
+		case fv.Kind() == reflect.Map:
+			// sorted for a stable output
+			m := fv.Interface().(map[string]string)
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			ordered := yaml.MapSlice{}
+			for _, k := range keys {
+				ordered = append(ordered, yaml.MapItem{Key: k, Value: m[k]})
+			}
+			value = ordered
+		default:
+			value = fv.Interface()
+		}
+		out = append(out, yaml.MapItem{Key: name, Value: value})
+	}
+	return out
+}
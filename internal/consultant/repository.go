@@ -2,11 +2,17 @@ package consultant
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 )
 
 type Repository interface {
@@ -33,6 +39,9 @@ func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository
 
 // InsertConsultantByStruct will insert a consultant into consultans table from consultant struct
 func (r *repository) InsertConsultantByStruct(ctx context.Context, c *entity.Consultant) error {
+	ctx, span := tracing.Start(ctx, "consultant.repository.InsertConsultantByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Insert("consultants", dbx.Params{
 			"first_name":      c.FirstName,
@@ -46,6 +55,11 @@ func (r *repository) InsertConsultantByStruct(ctx context.Context, c *entity.Con
 
 // GetConsultantDataByIDS will get and return consultants by a list of IDs
 func (r *repository) GetConsultantDataByIDS(ctx context.Context, consultantIDS []int) ([]entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetConsultantDataByIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.Consultant
 
 	// Convert []int -> []interface{} for dbx.In
@@ -65,8 +79,16 @@ func (r *repository) GetConsultantDataByIDS(ctx context.Context, consultantIDS [
 
 // GetConsultantDataByID will get and return consultant by id
 func (r *repository) GetConsultantDataByID(ctx context.Context, consultantID int) (*entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetConsultantDataByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var c entity.Consultant
 	err := r.dbContext.Get().WithContext(ctx).Select().From("consultants").Where(dbx.HashExp{"id": consultantID}).One(&c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("consultant %d not found", consultantID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +97,11 @@ func (r *repository) GetConsultantDataByID(ctx context.Context, consultantID int
 
 // GetConsultantsByProjectID gets and returns project consultants
 func (r *repository) GetConsultantsByProjectID(ctx context.Context, projectID int) ([]entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetConsultantsByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var consultants []entity.Consultant
 
 	err := r.dbContext.Get().WithContext(ctx).Select("c.*").
@@ -93,6 +120,11 @@ func (r *repository) GetConsultantsByProjectID(ctx context.Context, projectID in
 // GetRelatedConsultantsByProjectsIDS gets and returns project consultants for multiple project IDs
 // Includes consultants linked via project_consultants and project_time_entries
 func (r *repository) GetRelatedConsultantsByProjectsIDS(ctx context.Context, projectIDs []int) ([]entity.ProjectConsultantLink, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetRelatedConsultantsByProjectsIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var consultants []entity.ProjectConsultantLink
 
 	// Convert []int -> []interface{} for dbx.In
@@ -125,6 +157,11 @@ func (r *repository) GetRelatedConsultantsByProjectsIDS(ctx context.Context, pro
 // GetRelatedConsultantsByProjectID gets and returns project consultants
 // Includes consultants linked via project_consultants and project_time_entries
 func (r *repository) GetRelatedConsultantsByProjectID(ctx context.Context, projectID int) ([]entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetRelatedConsultantsByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var consultants []entity.Consultant
 
 	q := r.dbContext.Get().WithContext(ctx).Select("c.*").
@@ -146,6 +183,11 @@ func (r *repository) GetRelatedConsultantsByProjectID(ctx context.Context, proje
 
 // GetAllConsultants will get and return all consultants from consultants table
 func (r *repository) GetAllConsultants(ctx context.Context) ([]entity.Consultant, error) {
+	ctx, span := tracing.Start(ctx, "consultant.repository.GetAllConsultants")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.Consultant
 	err := r.dbContext.Get().WithContext(ctx).Select().From("consultants").All(&list)
 	return list, err
@@ -153,6 +195,9 @@ func (r *repository) GetAllConsultants(ctx context.Context) ([]entity.Consultant
 
 // UpdateConsultantByStruct will update a consultant from consultants table
 func (r *repository) UpdateConsultantByStruct(ctx context.Context, c *entity.Consultant) error {
+	ctx, span := tracing.Start(ctx, "consultant.repository.UpdateConsultantByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Update("consultants", dbx.Params{
 			"first_name":      c.FirstName,
@@ -166,6 +211,9 @@ func (r *repository) UpdateConsultantByStruct(ctx context.Context, c *entity.Con
 
 // DeleteConsultantByID will delete a consultant by id from consultants table
 func (r *repository) DeleteConsultantByID(ctx context.Context, consultantID int) error {
+	ctx, span := tracing.Start(ctx, "consultant.repository.DeleteConsultantByID")
+	defer span.End()
+
 	// Delete will be done in a transaction which can be rolled back on returning error
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		// 1. remove consultant from consultants tanle
@@ -189,12 +237,18 @@ func (r *repository) DeleteConsultantByID(ctx context.Context, consultantID int)
 
 // InsertProjectConsultantByStruct adds a consultant to project
 func (r *repository) InsertProjectConsultantByStruct(ctx context.Context, projectConsultant entity.ProjectConsultant) error {
+	ctx, span := tracing.Start(ctx, "consultant.repository.InsertProjectConsultantByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Insert("project_consultants", dbx.Params{
 			"consultant_id": projectConsultant.ConsultantID,
 			"project_id":    projectConsultant.ProjectID,
 			"role":          projectConsultant.Role,
 		}).Execute()
-		return err
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeConsultantAssigned, projectConsultant.ProjectID, projectConsultant)
 	})
 }
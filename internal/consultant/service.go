@@ -2,9 +2,12 @@ package consultant
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
 )
 
 // Consultant service interface
@@ -39,12 +42,13 @@ type ProjectConsultant struct {
 // Service
 type service struct {
 	repo   Repository
+	cache  *cache.Cache
 	logger *logger.Logger
 }
 
-// NewService creates a new consultant service
-func NewService(repo Repository, logger *logger.Logger) Service {
-	return &service{repo, logger}
+// NewService creates a new consultant service publishing project events through c
+func NewService(repo Repository, c *cache.Cache, logger *logger.Logger) Service {
+	return &service{repo, c, logger}
 }
 
 // Inserts a consultant from a consultant struct
@@ -111,5 +115,11 @@ func (s *service) DeleteConsultantByID(ctx context.Context, consultantID int) er
 }
 
 func (s *service) InsertProjectConsultantByStruct(ctx context.Context, projectConsultant ProjectConsultant) error {
-	return s.repo.InsertProjectConsultantByStruct(ctx, projectConsultant.ProjectConsultant)
+	if err := s.repo.InsertProjectConsultantByStruct(ctx, projectConsultant.ProjectConsultant); err != nil {
+		return err
+	}
+	if err := events.Publish(ctx, s.cache, events.TypeConsultantAssigned, projectConsultant.ProjectID, projectConsultant.ProjectConsultant); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to publish %s: %s", events.TypeConsultantAssigned, err.Error()))
+	}
+	return nil
 }
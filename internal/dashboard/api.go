@@ -5,222 +5,264 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"runtime"
-	"sync"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/cache"
-	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
-	internalMeta "github.com/renniemaharaj/project-list-go/internal/meta"
-	internalProject "github.com/renniemaharaj/project-list-go/internal/project"
-	"github.com/renniemaharaj/project-list-go/internal/utils"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+	"golang.org/x/sync/singleflight"
 )
 
-// Metrics struct for dashboard workers
-type metrics struct {
-	Completed            int
-	Active               int
-	Idle                 int
-	OutOfBudget          int
-	TotalDebit           float64
-	TotalCredit          float64
-	CreditOverDebitRatio float64 // valid only if HasRatio
-	HasRatio             bool
-}
-
-var (
-	dashboardLogger = logger.New().Prefix("Dash Router")
-	// Prevents duplicate recomputes of the same dashboard concurrently.
-	dashboardBuildLock sync.Mutex
-)
+var dashboardLogger = logger.New().Prefix("Dash Router")
 
-// Internal cache projects function to reuse dashboard-fetched projects
-func proactivelyCacheProjectData(projects []internalProject.Project) {
-	for _, p := range projects {
-		// Cache each project under its own key
-		_, _ = cache.Use(fmt.Sprintf("projects:one:%d", p.ID), func() (internalProject.Project, error) {
-			// Return the already-fetched value; no DB call here.
-			return p, nil
-		})
-	}
+// Handler serves the dashboard routes, reads go through service and the live dashboard is refreshed
+// through primary
+type Handler struct {
+	service Service
+	primary Service
+	config  entity.DashboardConfig
+	cache   *cache.Cache
+	// builds shares one computation between concurrent requests of the same dashboard
+	builds singleflight.Group
+	// refreshes shares one primary computation between concurrent live refreshes, they must never
+	// join a replica build started before the changes that triggered them
+	refreshes singleflight.Group
+	// generation counts the live refreshes, builds started before a refresh do not stay cached
+	generation atomic.Uint64
+	// live fans the portfolio dashboard out to the /stream clients
+	live *liveHub
 }
 
-func Dashboard(r chi.Router) {
-	r.Get("/", GetMetricsDashboard)
+// NewHandler returns a dashboard handler caching dashboards in c, service may read from a replica as
+// aggregations tolerate replication lag, primary serves the live refreshes which must see the changes
+// that triggered them
+func NewHandler(service, primary Service, cfg entity.DashboardConfig, c *cache.Cache) *Handler {
+	return &Handler{service: service, primary: primary, config: cfg, cache: c, live: newLiveHub()}
 }
 
-type MetricsDashboard struct {
-	entity.MetricsDashboard
+// Routes registers the dashboard routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.GetMetricsDashboard)
+	r.Get("/stream", h.StreamMetricsDashboard)
+	r.Get("/series", h.GetMetricsSeries)
+	r.Get("/managers", h.GetManagerLeaderboard)
+	r.Get("/consultants", h.GetConsultantLeaderboard)
 }
 
-func GetMetricsDashboard(w http.ResponseWriter, r *http.Request) {
-	// Guard: ensures that waiting clients must hit cache instead of unnecessarily computing
-	dashboardBuildLock.Lock()
-	defer dashboardBuildLock.Unlock()
+const (
+	// dateLayout is the layout of the from and to query parameters
+	dateLayout = "2006-01-02"
+	// maxSeriesWindow caps series windows
+	maxSeriesWindow = 3 * 366 * 24 * time.Hour
+)
 
-	ctx := r.Context()
-	// We wrap dashboard compute in a use cache interface which auto caches return values
-	dashboardMetrics, err := cache.Use("metrics_dashboard", func() (MetricsDashboard, error) {
+// Gets the dashboard filter from the managerID, tag, consultantID, from and to query parameters
+func getFilterFromRequest(r *http.Request) (entity.DashboardFilter, error) {
+	query := r.URL.Query()
+	filter := entity.DashboardFilter{Tag: query.Get("tag")}
 
-		// 1) Fetch project IDs (cheap)
-		// repository does not offer get all projects in complete (more performant to return ids)
-		projectIDS, err := internalProject.NewService(internalProject.NewRepository(database.Automatic, dashboardLogger), dashboardLogger).GetAllProjectIDS(ctx)
-		if err != nil {
-			return MetricsDashboard{}, err
+	var err error
+	if v := query.Get("managerID"); v != "" {
+		if filter.ManagerID, err = strconv.Atoi(v); err != nil {
+			return filter, apperror.Invalid("managerID", "invalid managerID")
 		}
-		if len(projectIDS) == 0 {
-			return MetricsDashboard{}, nil
+	}
+	if v := query.Get("consultantID"); v != "" {
+		if filter.ConsultantID, err = strconv.Atoi(v); err != nil {
+			return filter, apperror.Invalid("consultantID", "invalid consultantID")
 		}
-
-		projectMetas, projects, err := internalMeta.NewService(internalMeta.NewRepository(database.Automatic, dashboardLogger), dashboardLogger).GetProjectsMetaByProjectIDS(ctx, projectIDS, true)
-		// 2) Fetch project rows in bulk (cheap relative to per-project meta)
-		// projects, err := internalProject.NewRepository(database.Automatic, dashboardLogger).GetProjectsDataByIDS(ctx, projectIDS)
-		if err != nil {
-			return MetricsDashboard{}, err
+	}
+	if v := query.Get("from"); v != "" {
+		if filter.From, err = time.Parse(dateLayout, v); err != nil {
+			return filter, apperror.Invalid("from", fmt.Sprintf("invalid from, expected %s", dateLayout))
 		}
-		// 2a) Proactively cache each project record (async fire-and-forget)
-		go proactivelyCacheProjectData(projects)
-
-		const idleThreshold = 7 * 24 * time.Hour
-		now := time.Now()
-		// 3) Pre-compute ending soon from projects (no need to involve workers)
-		result := MetricsDashboard{}
-		result.Projects = len(projects)
-		for _, p := range projects {
-			if p.EndDate.After(now) && p.EndDate.Before(now.Add(idleThreshold)) {
-				result.EndingSoon++
-			}
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return filter, apperror.Invalid("to", fmt.Sprintf("invalid to, expected %s", dateLayout))
 		}
+		// include the whole last day
+		filter.To = to.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, apperror.Invalid("to", "to must not be before from")
+	}
+	return filter, nil
+}
 
-		// 4) Fan-out: fetch meta + compute per-project partials in a worker pool
-		jobs := make(chan int)        // project IDs
-		results := make(chan metrics) // per-project partials
-		errs := make(chan error, 1)   // first error wins
-
-		// Prevents < 4 and uncaps
-		workerCount := utils.MinMax(200, runtime.NumCPU()*4, 0)
-		worker := func(ctx context.Context) {
-			for id := range jobs {
-				// Use cache.Use around meta fetch
-				meta, metaError := cache.Use(fmt.Sprintf("projects:meta:%d", id), func() (internalMeta.ProjectMeta, error) {
-					if pm, ok := projectMetas[id]; ok {
-						return pm, nil
-					}
-
-					pm, err := internalMeta.NewService(internalMeta.NewRepository(database.Automatic, dashboardLogger), dashboardLogger).GetProjectMetaByProjectID(ctx, id)
-					return *pm, err
-				})
-				if metaError != nil {
-					errs <- metaError
-					return
-				}
-
-				var p metrics
-				// Latest status assumed at index 0 if present
-				if len(meta.StatusHistory) > 0 {
-					switch meta.StatusHistory[0].Title {
-					case "completed":
-						p.Completed = 1
-					case "active":
-						p.Active = 1
-					}
-					if now.Sub(meta.StatusHistory[0].DateCreated) > idleThreshold {
-						p.Idle = 1
-					}
-				}
-
-				// Debit/Credit & OOB
-				var debit, credit float64
-				for _, t := range meta.TimeEntries {
-					switch t.Type {
-					case "debit":
-						debit += float64(t.Hours)
-					case "credit":
-						credit += float64(t.Hours)
-					}
-				}
-				p.TotalDebit = debit
-				p.TotalCredit = credit
-				if credit > debit {
-					p.OutOfBudget = 1
-				}
-				if debit > 0 {
-					p.HasRatio = true
-					p.CreditOverDebitRatio = credit / debit
-				}
-
-				// send partial
-				select {
-				case results <- p:
-				case <-ctx.Done():
-					return
-				}
-			}
+// Builds the cache key of a filter set and configuration, the unfiltered dashboard with the
+// configured defaults keeps its global key
+func (h *Handler) dashboardCacheKey(filter entity.DashboardFilter, cfg entity.DashboardConfig) string {
+	if filter.IsZero() && configCacheKey(cfg) == configCacheKey(h.config) {
+		return "metrics_dashboard"
+	}
+	formatDate := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
 		}
+		return t.Format(dateLayout)
+	}
+	return fmt.Sprintf("metrics_dashboard:manager:%d:tag:%s:consultant:%d:from:%s:to:%s:%s",
+		filter.ManagerID, filter.Tag, filter.ConsultantID, formatDate(filter.From), formatDate(filter.To), configCacheKey(cfg))
+}
 
-		// Start workers
-		for i := 0; i < workerCount; i++ {
-			go worker(ctx)
-		}
+// GetMetricsDashboard computes the dashboard metrics, optionally scoped by manager, tag, consultant
+// and a time entry date range, the configured thresholds and status buckets can be overridden per request
+func (h *Handler) GetMetricsDashboard(w http.ResponseWriter, r *http.Request) {
+	filter, err := getFilterFromRequest(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	cfg, err := configFromValues(h.config, r.URL.Query())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	// aggregations tolerate replica lag
+	dashboardMetrics, err := h.computeDashboard(r.Context(), h.service, filter, cfg)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(dashboardMetrics)
+}
+
+// GetMetricsSeries returns weekly or monthly debit/credit hours, active and completed project counts
+// and the credit/debit ratio, the window defaults to the last 12 months by month
+func (h *Handler) GetMetricsSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	today := time.Now().Truncate(24 * time.Hour)
+	from, to := today.AddDate(-1, 0, 0), today
 
-		// Feed jobs
-		go func() {
-			defer close(jobs)
-			for _, p := range projects {
-				select {
-				case jobs <- p.ID:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}()
-
-		// Aggregate (single goroutine, no locks)
-		var (
-			totalRatios float64
-			ratioCount  int
-			received    int
-		)
-
-		for received < len(projects) {
-			select {
-			case err := <-errs:
-				// Drain workers by cancel if you wrap ctx with context.WithCancel here.
-				return MetricsDashboard{}, err
-			case p := <-results:
-				received++
-				result.Completed += p.Completed
-				result.Active += p.Active
-				result.Idle += p.Idle
-				result.OutOfBudget += p.OutOfBudget
-				result.TotalDebit += p.TotalDebit
-				result.TotalCredit += p.TotalCredit
-
-				if p.HasRatio {
-					totalRatios += p.CreditOverDebitRatio
-					ratioCount++
-				}
-			case <-ctx.Done():
-				return MetricsDashboard{}, ctx.Err()
-			}
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("from", "invalid from, expected "+dateLayout))
+			return
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("to", "invalid to, expected "+dateLayout))
+			return
 		}
+	}
+	if to.Sub(from) > maxSeriesWindow {
+		problem.Write(w, r, apperror.Invalid("to", "window must not exceed 3 years"))
+		return
+	}
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = GranularityMonth
+	}
+	cfg, err := configFromValues(h.config, query)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	key := fmt.Sprintf("dashboard:series:%s:%s:%s:%s", from.Format(dateLayout), to.Format(dateLayout), granularity, configCacheKey(cfg))
+	series, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) (*MetricsSeries, error) {
+		return h.service.GetMetricsSeries(ctx, from, to, granularity, cfg)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(series)
+}
 
-		if ratioCount > 0 {
-			result.AverageCreditOverDebit = float32(totalRatios / float64(ratioCount))
+// Gets the leaderboard from the sort, order (asc or desc, default desc) and limit query parameters
+func getLeaderboardFromRequest(r *http.Request) (entity.Leaderboard, error) {
+	query := r.URL.Query()
+	board := entity.Leaderboard{Sort: query.Get("sort"), Descending: true}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		board.Descending = false
+	default:
+		return board, apperror.Invalid("order", "invalid order, expected asc or desc")
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return board, apperror.Invalid("limit", "invalid limit")
 		}
+		board.Limit = limit
+	}
+	return board, nil
+}
+
+// Gets the filter, configuration and leaderboard of a leaderboard request and its cache key
+func (h *Handler) getLeaderboardRequest(r *http.Request, name string) (entity.DashboardFilter, entity.DashboardConfig, entity.Leaderboard, string, error) {
+	filter, err := getFilterFromRequest(r)
+	if err != nil {
+		return filter, entity.DashboardConfig{}, entity.Leaderboard{}, "", err
+	}
+	cfg, err := configFromValues(h.config, r.URL.Query())
+	if err != nil {
+		return filter, cfg, entity.Leaderboard{}, "", err
+	}
+	board, err := getLeaderboardFromRequest(r)
+	if err != nil {
+		return filter, cfg, board, "", err
+	}
+
+	key := fmt.Sprintf("dashboard:%s:sort:%s:desc:%t:limit:%d:%s", name, board.Sort, board.Descending, board.Limit, h.dashboardCacheKey(filter, cfg))
+	return filter, cfg, board, key, nil
+}
+
+// GetManagerLeaderboard returns per manager project counts, budget overruns and hours, sortable by
+// projects, active, completed, outOfBudget, totalDebit or totalCredit
+func (h *Handler) GetManagerLeaderboard(w http.ResponseWriter, r *http.Request) {
+	filter, cfg, board, key, err := h.getLeaderboardRequest(r, "managers")
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
 
-		return result, nil
+	managers, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) ([]entity.ManagerMetrics, error) {
+		return h.service.GetManagerLeaderboard(ctx, filter, cfg, board)
 	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(managers)
+}
+
+// GetConsultantLeaderboard returns per consultant hours, project counts and credit share, sortable by
+// projects, totalHours, totalDebit, totalCredit or creditShare
+func (h *Handler) GetConsultantLeaderboard(w http.ResponseWriter, r *http.Request) {
+	filter, cfg, board, key, err := h.getLeaderboardRequest(r, "consultants")
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
 
+	consultants, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) ([]entity.ConsultantMetrics, error) {
+		return h.service.GetConsultantLeaderboard(ctx, filter, cfg, board)
+	})
 	if err != nil {
-		http.Error(w, "Failed to fetch dashboard metrics", http.StatusInternalServerError)
-		dashboardLogger.Error(err.Error())
+		problem.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(dashboardMetrics)
+	_ = json.NewEncoder(w).Encode(consultants)
 }
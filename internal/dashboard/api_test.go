@@ -0,0 +1,92 @@
+package dashboard
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+func TestGetFilterFromRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"managerID=2&tag=cloud&consultantID=3&from=2026-01-01&to=2026-03-31", false},
+		{"from=2026-01-01", false}, // open ended
+		{"to=2026-01-01&from=2026-01-01", false},
+		{"managerID=x", true},
+		{"consultantID=x", true},
+		{"from=2026-1-1", true},
+		{"to=31.03.2026", true},
+		{"from=2026-03-01&to=2026-01-01", true},
+	}
+	for _, tt := range tests {
+		filter, err := getFilterFromRequest(httptest.NewRequest("GET", "/dashboard?"+tt.query, nil))
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%q: filter = %+v, err = %v", tt.query, filter, err)
+		}
+	}
+
+	filter, _ := getFilterFromRequest(httptest.NewRequest("GET", "/dashboard?managerID=2&tag=cloud&consultantID=3&to=2026-03-31", nil))
+	want := time.Date(2026, 3, 31, 23, 59, 59, 999999999, time.UTC)
+	if filter.ManagerID != 2 || filter.Tag != "cloud" || filter.ConsultantID != 3 || !filter.From.IsZero() || !filter.To.Equal(want) {
+		t.Fatalf("filter = %+v, want to at the end of the day", filter)
+	}
+}
+
+func TestDashboardCacheKey(t *testing.T) {
+	// the unfiltered dashboard of the configured defaults keeps the key invalidated on every write
+	cfg := DefaultConfig()
+	h := NewHandler(nil, nil, cfg, nil)
+	if key := h.dashboardCacheKey(entity.DashboardFilter{}, cfg); key != "metrics_dashboard" {
+		t.Fatalf("key = %q, want metrics_dashboard", key)
+	}
+	// but not when the request overrides the configuration
+	custom := cfg
+	custom.IdleThreshold *= 2
+	if key := h.dashboardCacheKey(entity.DashboardFilter{}, custom); key == "metrics_dashboard" || key == h.dashboardCacheKey(entity.DashboardFilter{ManagerID: 1}, cfg) {
+		t.Fatalf("key = %q, want a key of its own", key)
+	}
+
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	filters := []entity.DashboardFilter{
+		{ManagerID: 1},
+		{ManagerID: 2},
+		{Tag: "cloud"},
+		{ConsultantID: 1},
+		{From: day},
+		{To: day},
+		{From: day, To: day},
+	}
+	seen := map[string]entity.DashboardFilter{}
+	for _, f := range filters {
+		key := h.dashboardCacheKey(f, cfg)
+		if other, ok := seen[key]; ok {
+			t.Fatalf("filters %+v and %+v share key %q", f, other, key)
+		}
+		seen[key] = f
+	}
+}
+
+func TestGetLeaderboardFromRequest(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    entity.Leaderboard
+		wantErr bool
+	}{
+		{query: "", want: entity.Leaderboard{Descending: true}},
+		{query: "sort=totalDebit&order=asc&limit=5", want: entity.Leaderboard{Sort: "totalDebit", Limit: 5}},
+		{query: "order=desc", want: entity.Leaderboard{Descending: true}},
+		{query: "order=random", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		board, err := getLeaderboardFromRequest(httptest.NewRequest("GET", "/dashboard/managers?"+tt.query, nil))
+		if tt.wantErr != (err != nil) || (!tt.wantErr && board != tt.want) {
+			t.Errorf("%q: board = %+v, err = %v, want %+v", tt.query, board, err, tt.want)
+		}
+	}
+}
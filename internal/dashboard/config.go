@@ -0,0 +1,122 @@
+package dashboard
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// Status buckets a status title can be mapped to
+const (
+	BucketActive    = "active"
+	BucketCompleted = "completed"
+	BucketIdle      = "idle"
+	BucketOther     = "other"
+)
+
+// NewConfig returns the dashboard configuration of cfg counting hours of types, requests start from it
+func NewConfig(cfg config.Dashboard, types config.TimeEntryTypes) entity.DashboardConfig {
+	return entity.DashboardConfig{
+		IdleThreshold:    time.Duration(cfg.IdleDays) * 24 * time.Hour,
+		EndingSoonWindow: time.Duration(cfg.EndingSoonDays) * 24 * time.Hour,
+		StatusBuckets:    maps.Clone(cfg.StatusBuckets),
+		DebitType:        types.Debit,
+		CreditType:       types.Credit,
+	}
+}
+
+// DefaultConfig returns the dashboard configuration used when nothing is configured
+func DefaultConfig() entity.DashboardConfig {
+	return entity.DashboardConfig{
+		IdleThreshold:    7 * 24 * time.Hour,
+		EndingSoonWindow: 7 * 24 * time.Hour,
+		StatusBuckets: map[string]string{
+			"active":    BucketActive,
+			"completed": BucketCompleted,
+		},
+		DebitType:  "debit",
+		CreditType: "credit",
+	}
+}
+
+// configFromValues applies idleDays, endingSoonDays, statusBuckets, debitType and creditType on top of
+// base. statusBuckets is a comma separated list of title:bucket pairs and replaces the whole mapping.
+func configFromValues(base entity.DashboardConfig, values url.Values) (entity.DashboardConfig, error) {
+	cfg := base
+
+	days := func(name string, into *time.Duration) error {
+		v := values.Get(name)
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return apperror.Invalid(name, "invalid "+name)
+		}
+		*into = time.Duration(n) * 24 * time.Hour
+		return nil
+	}
+	if err := days("idleDays", &cfg.IdleThreshold); err != nil {
+		return base, err
+	}
+	if err := days("endingSoonDays", &cfg.EndingSoonWindow); err != nil {
+		return base, err
+	}
+
+	if v := values.Get("statusBuckets"); v != "" {
+		cfg.StatusBuckets = map[string]string{}
+		for _, pair := range strings.Split(v, ",") {
+			title, bucket, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			switch {
+			case !ok || title == "":
+				return base, apperror.Invalid("statusBuckets", "invalid statusBuckets, expected title:bucket pairs")
+			case bucket != BucketActive && bucket != BucketCompleted && bucket != BucketIdle && bucket != BucketOther:
+				return base, apperror.Invalid("statusBuckets", fmt.Sprintf("invalid statusBuckets, %q is not active, completed, idle or other", bucket))
+			}
+			cfg.StatusBuckets[title] = bucket
+		}
+	}
+
+	if v := values.Get("debitType"); v != "" {
+		cfg.DebitType = v
+	}
+	if v := values.Get("creditType"); v != "" {
+		cfg.CreditType = v
+	}
+	return cfg, nil
+}
+
+// configCacheKey renders a configuration deterministically for cache keys
+func configCacheKey(cfg entity.DashboardConfig) string {
+	titles, buckets := bucketArrays(cfg)
+	pairs := make([]string, len(titles))
+	for i := range titles {
+		pairs[i] = titles[i] + ":" + buckets[i]
+	}
+	return fmt.Sprintf("idle:%s:soon:%s:buckets:%s:types:%s:%s",
+		cfg.IdleThreshold, cfg.EndingSoonWindow, strings.Join(pairs, ","), cfg.DebitType, cfg.CreditType)
+}
+
+// bucketArrays flattens the status mapping into parallel arrays, sorted by title, for unnest
+func bucketArrays(cfg entity.DashboardConfig) (pq.StringArray, pq.StringArray) {
+	titles := make(pq.StringArray, 0, len(cfg.StatusBuckets))
+	for title := range cfg.StatusBuckets {
+		titles = append(titles, title)
+	}
+	sort.Strings(titles)
+
+	buckets := make(pq.StringArray, len(titles))
+	for i, title := range titles {
+		buckets[i] = cfg.StatusBuckets[title]
+	}
+	return titles, buckets
+}
@@ -0,0 +1,62 @@
+package dashboard
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestConfigFromValues(t *testing.T) {
+	base := DefaultConfig()
+
+	cfg, err := configFromValues(base, url.Values{
+		"idleDays":       {"14"},
+		"endingSoonDays": {"0"},
+		"statusBuckets":  {"in progress:active, done:completed,paused:idle"},
+		"debitType":      {"budget"},
+		"creditType":     {"logged"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IdleThreshold != 14*24*time.Hour || cfg.EndingSoonWindow != 0 || cfg.DebitType != "budget" || cfg.CreditType != "logged" {
+		t.Fatalf("config = %+v", cfg)
+	}
+	// the bucket mapping is replaced as a whole
+	if len(cfg.StatusBuckets) != 3 || cfg.StatusBuckets["in progress"] != BucketActive || cfg.StatusBuckets["done"] != BucketCompleted ||
+		cfg.StatusBuckets["paused"] != BucketIdle {
+		t.Fatalf("buckets = %v", cfg.StatusBuckets)
+	}
+	// and base is left alone
+	if len(base.StatusBuckets) != 2 || base.StatusBuckets["active"] != BucketActive {
+		t.Fatalf("base buckets = %v", base.StatusBuckets)
+	}
+
+	for _, values := range []url.Values{
+		{"idleDays": {"-1"}},
+		{"endingSoonDays": {"week"}},
+		{"statusBuckets": {"active"}},
+		{"statusBuckets": {":active"}},
+		{"statusBuckets": {"active:running"}},
+	} {
+		if cfg, err := configFromValues(base, values); err == nil {
+			t.Errorf("%v: config = %+v, want an error", values, cfg)
+		}
+	}
+}
+
+func TestConfigCacheKeyIsDeterministic(t *testing.T) {
+	a, b := DefaultConfig(), DefaultConfig()
+	a.StatusBuckets = map[string]string{"active": BucketActive, "done": BucketCompleted, "paused": BucketIdle}
+	b.StatusBuckets = map[string]string{"paused": BucketIdle, "active": BucketActive, "done": BucketCompleted}
+	for range 10 {
+		if configCacheKey(a) != configCacheKey(b) {
+			t.Fatalf("equal configurations have keys %q and %q", configCacheKey(a), configCacheKey(b))
+		}
+	}
+
+	b.StatusBuckets["paused"] = BucketOther
+	if configCacheKey(a) == configCacheKey(b) {
+		t.Fatalf("different bucket mappings share key %q", configCacheKey(a))
+	}
+}
@@ -0,0 +1,234 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/changefeed"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/metrics"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// liveQuiet and liveMaxDelay debounce change bursts into one recompute
+	liveQuiet    = 500 * time.Millisecond
+	liveMaxDelay = 5 * time.Second
+	// liveKeepAlive is the interval of SSE comments keeping idle connections open
+	liveKeepAlive = 15 * time.Second
+	// liveWriteTimeout disconnects clients that stop reading
+	liveWriteTimeout = 10 * time.Second
+)
+
+// liveHub fans the latest dashboard snapshot out to the connected SSE clients. Every client has a
+// buffer of one snapshot, a client that has not consumed the previous snapshot gets it replaced so
+// slow clients skip intermediate states instead of holding up the others.
+type liveHub struct {
+	mu      sync.Mutex
+	clients map[chan []byte]struct{}
+	latest  []byte
+	// closing is closed when the server shuts down
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newLiveHub() *liveHub {
+	return &liveHub{clients: map[chan []byte]struct{}{}, closing: make(chan struct{})}
+}
+
+// CloseStreams ends every /dashboard/stream response, the http server shutdown would otherwise wait
+// for these never ending responses until its timeout
+func (h *Handler) CloseStreams() {
+	h.live.closeOnce.Do(func() { close(h.live.closing) })
+}
+
+// subscribe registers a client and primes it with the latest snapshot
+func (h *liveHub) subscribe() chan []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan []byte, 1)
+	if h.latest != nil {
+		ch <- h.latest
+	}
+	h.clients[ch] = struct{}{}
+	return ch
+}
+
+func (h *liveHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, ch)
+}
+
+// publish replaces the latest snapshot and offers it to every client without blocking
+func (h *liveHub) publish(snapshot []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latest = snapshot
+	for ch := range h.clients {
+		select {
+		case <-ch: // drop the unconsumed snapshot
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+// computeDashboard returns the cached dashboard of the filter and configuration, computing it through
+// service on a miss. Concurrent callers of the same dashboard share one computation, dashboards of
+// other filters and configurations are computed independently.
+func (h *Handler) computeDashboard(ctx context.Context, service Service, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*MetricsDashboard, error) {
+	key := h.dashboardCacheKey(filter, cfg)
+	// the shared computation outlives the caller that started it, the others still wait for it
+	build := h.builds.DoChan(key, func() (any, error) {
+		generation := h.generation.Load()
+		// We wrap dashboard compute in a use cache interface which auto caches return values
+		m, err := cache.Use(context.WithoutCancel(ctx), h.cache, key, func(ctx context.Context) (*MetricsDashboard, error) {
+			start := time.Now()
+			defer func() { metrics.ObserveDashboardBuild(time.Since(start)) }()
+			return service.GetMetricsDashboard(ctx, filter, cfg)
+		})
+		// a live refresh ran meanwhile, what this build cached may predate its changes
+		if err == nil && h.generation.Load() != generation {
+			h.cache.Invalidate(key)
+		}
+		return m, err
+	})
+	return awaitDashboard(ctx, build)
+}
+
+// awaitDashboard waits for a shared dashboard computation or the end of ctx
+func awaitDashboard(ctx context.Context, build <-chan singleflight.Result) (*MetricsDashboard, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-build:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*MetricsDashboard), nil
+	}
+}
+
+// refreshLive recomputes the portfolio dashboard and publishes it to the SSE clients
+func (h *Handler) refreshLive(ctx context.Context) error {
+	key := h.dashboardCacheKey(entity.DashboardFilter{}, h.config)
+	h.generation.Add(1)
+	// requests from now on start a new build instead of joining one that predates the changes
+	h.builds.Forget(key)
+	h.cache.Invalidate(key)
+
+	// read from the primary, the replica may not have replayed the changes yet
+	build := h.refreshes.DoChan(key, func() (any, error) {
+		return cache.Use(context.WithoutCancel(ctx), h.cache, key, func(ctx context.Context) (*MetricsDashboard, error) {
+			start := time.Now()
+			defer func() { metrics.ObserveDashboardBuild(time.Since(start)) }()
+			return h.primary.GetMetricsDashboard(ctx, entity.DashboardFilter{}, h.config)
+		})
+	})
+	m, err := awaitDashboard(ctx, build)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	h.live.publish(snapshot)
+	return nil
+}
+
+// StartLiveUpdates listens to the change feed of db until ctx is done and pushes a fresh portfolio
+// dashboard to the /dashboard/stream clients after every burst of writes. The returned channel is
+// closed once updates stopped.
+func (h *Handler) StartLiveUpdates(ctx context.Context, db *database.DBContext) (<-chan struct{}, error) {
+	changes, err := changefeed.Listen(ctx, db, dashboardLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for batch := range changefeed.Debounce(ctx, changes, liveQuiet, liveMaxDelay) {
+			if err := h.refreshLive(ctx); err != nil {
+				dashboardLogger.ErrorF("Failed to refresh live dashboard after %d changes: %s", len(batch), err.Error())
+			}
+		}
+	}()
+	return done, nil
+}
+
+// StreamMetricsDashboard streams the portfolio dashboard as Server-Sent Events, one dashboard event
+// on connect and after every change
+func (h *Handler) StreamMetricsDashboard(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem.Write(w, r, errors.New("streaming unsupported by the response writer"))
+		return
+	}
+
+	ch := h.live.subscribe()
+	defer h.live.unsubscribe(ch)
+
+	// prime clients connecting before the first change with the cached dashboard, only the change
+	// feed invalidates it and publishes to every client
+	if len(ch) == 0 {
+		m, err := h.computeDashboard(r.Context(), h.service, entity.DashboardFilter{}, h.config)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		snapshot, err := json.Marshal(m)
+		if err != nil {
+			problem.Write(w, r, err)
+			return
+		}
+		// a snapshot published meanwhile is newer, keep it
+		select {
+		case ch <- snapshot:
+		default:
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	rc := http.NewResponseController(w)
+	keepAlive := time.NewTicker(liveKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.live.closing:
+			return
+		case snapshot := <-ch:
+			_ = rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			_, err = fmt.Fprintf(w, "event: dashboard\ndata: %s\n\n", snapshot)
+		case <-keepAlive.C:
+			_ = rc.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,197 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+func TestLiveHub(t *testing.T) {
+	h := &liveHub{clients: map[chan []byte]struct{}{}}
+
+	// nothing to prime a client with before the first snapshot
+	early := h.subscribe()
+	if len(early) != 0 {
+		t.Fatalf("new client primed with %q", <-early)
+	}
+
+	h.publish([]byte("1"))
+	late := h.subscribe()
+	if got := string(<-late); got != "1" {
+		t.Fatalf("late client primed with %q, want the latest snapshot", got)
+	}
+
+	// a client that did not read snapshot 1 skips it for snapshot 2
+	h.publish([]byte("2"))
+	if got := string(<-early); got != "2" || len(early) != 0 {
+		t.Fatalf("slow client got %q, want only the latest snapshot", got)
+	}
+	if got := string(<-late); got != "2" {
+		t.Fatalf("client got %q, want 2", got)
+	}
+
+	h.unsubscribe(early)
+	h.publish([]byte("3"))
+	if len(early) != 0 {
+		t.Fatal("unsubscribed client still receives snapshots")
+	}
+}
+
+// slowService builds dashboards once release is closed, counting the builds it started
+type slowService struct {
+	Service
+	builds  atomic.Int32
+	release chan struct{}
+}
+
+func (s *slowService) GetMetricsDashboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*MetricsDashboard, error) {
+	s.builds.Add(1)
+	<-s.release
+	return &MetricsDashboard{entity.MetricsDashboard{Projects: filter.ManagerID}}, nil
+}
+
+func TestComputeDashboardSharesBuildsPerKey(t *testing.T) {
+	svc := &slowService{release: make(chan struct{})}
+	h := NewHandler(svc, svc, DefaultConfig(), nil)
+
+	var wg sync.WaitGroup
+	results := make([]*MetricsDashboard, 6)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// three requests of manager 1's dashboard, three of manager 2's
+			d, err := h.computeDashboard(context.Background(), svc, entity.DashboardFilter{ManagerID: 1 + i%2}, DefaultConfig())
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = d
+		}()
+	}
+
+	// both dashboards are built at the same time, one waiting caller does not hold up the other key
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.builds.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(svc.release)
+	wg.Wait()
+
+	if n := svc.builds.Load(); n != 2 {
+		t.Fatalf("%d builds, want one per dashboard", n)
+	}
+	for i, d := range results {
+		if d == nil || d.Projects != 1+i%2 {
+			t.Fatalf("request %d got %+v, want the dashboard of its filter", i, d)
+		}
+	}
+}
+
+func TestComputeDashboardReturnsOnCancel(t *testing.T) {
+	svc := &slowService{release: make(chan struct{})}
+	defer close(svc.release)
+	h := NewHandler(svc, svc, DefaultConfig(), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := h.computeDashboard(ctx, svc, entity.DashboardFilter{}, DefaultConfig()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the caller's deadline while the build goes on", err)
+	}
+}
+
+func TestRefreshLiveDoesNotJoinReplicaBuilds(t *testing.T) {
+	replica := &slowService{release: make(chan struct{})}
+	primary := &slowService{release: make(chan struct{})}
+	close(primary.release)
+	h := NewHandler(replica, primary, DefaultConfig(), nil)
+	live := h.live.subscribe()
+	defer h.live.unsubscribe(live)
+
+	// a request started a replica build before the change
+	stale := make(chan error, 1)
+	go func() {
+		_, err := h.computeDashboard(context.Background(), replica, entity.DashboardFilter{}, DefaultConfig())
+		stale <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for replica.builds.Load() < 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the refresh reads the primary instead of waiting for the replica build
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.refreshLive(ctx); err != nil {
+		t.Fatalf("refresh: %v, want the primary dashboard while the replica build is in flight", err)
+	}
+	if primary.builds.Load() != 1 || len(live) != 1 {
+		t.Fatalf("%d primary builds, %d snapshots published, want one each", primary.builds.Load(), len(live))
+	}
+
+	// requests after the refresh start a new build instead of joining the stale one
+	fresh := make(chan error, 1)
+	go func() {
+		_, err := h.computeDashboard(context.Background(), replica, entity.DashboardFilter{}, DefaultConfig())
+		fresh <- err
+	}()
+	for replica.builds.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := replica.builds.Load(); n != 2 {
+		t.Fatalf("%d replica builds, want a new build after the refresh", n)
+	}
+	close(replica.release)
+	if err := <-stale; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-fresh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamPrimesOnlyTheNewClient(t *testing.T) {
+	svc := &slowService{release: make(chan struct{})}
+	close(svc.release)
+	h := NewHandler(svc, svc, DefaultConfig(), nil)
+
+	other := h.live.subscribe()
+	defer h.live.unsubscribe(other)
+
+	s := httptest.NewServer(http.HandlerFunc(h.StreamMetricsDashboard))
+	defer s.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	lines := bufio.NewScanner(res.Body)
+	if !lines.Scan() || lines.Text() != "event: dashboard" || !lines.Scan() || !strings.HasPrefix(lines.Text(), "data: {") {
+		t.Fatalf("stream starts with %q, want a dashboard event", lines.Text())
+	}
+	// the new client was primed from the cached dashboard, the connected ones got nothing
+	if len(other) != 0 || svc.builds.Load() != 1 {
+		t.Fatalf("%d snapshots published to other clients, %d builds", len(other), svc.builds.Load())
+	}
+
+	// closing the streams of the handler ends the response
+	h.CloseStreams()
+	for lines.Scan() {
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatalf("stream ended with %v, want the end of the response", err)
+	}
+}
@@ -0,0 +1,321 @@
+package dashboard
+
+import (
+	"context"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+type Repository interface {
+	GetMetricsByFilter(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*entity.MetricsDashboard, error)
+	GetSeriesPoints(ctx context.Context, from, to time.Time, granularity string, cfg entity.DashboardConfig) ([]entity.SeriesPoint, error)
+	GetManagerMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ManagerMetrics, error)
+	GetConsultantMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ConsultantMetrics, error)
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	l         *logger.Logger
+}
+
+func NewRepository(_db *database.DBContext, _l *logger.Logger) Repository {
+	return &repository{_db, _l}
+}
+
+// scopeSQL selects the projects in scope of a dashboard filter. With a consultant and/or a date range
+// only projects with matching time entries (or the consultant's assignment) are kept.
+const scopeSQL = `SELECT p.id, p.manager_id, p.end_date FROM projects p
+	WHERE ({:manager_id} = 0 OR p.manager_id = {:manager_id})
+		AND ({:tag}::text = '' OR EXISTS (SELECT 1 FROM project_tags tg WHERE tg.project_id = p.id AND tg.tag = {:tag}))
+		AND CASE
+			WHEN {:has_from} OR {:has_to} THEN EXISTS (` + entriesInScopeSQL + `)
+			WHEN {:consultant_id} <> 0 THEN
+				EXISTS (SELECT 1 FROM project_consultants pc WHERE pc.project_id = p.id AND pc.consultant_id = {:consultant_id})
+				OR EXISTS (` + entriesInScopeSQL + `)
+			ELSE TRUE
+		END`
+
+// entriesInScopeSQL selects the time entries of project p counted by a dashboard filter, an unset
+// bound leaves that side open
+const entriesInScopeSQL = `SELECT 1 FROM project_time_entries te WHERE te.project_id = p.id
+	AND ({:consultant_id} = 0 OR te.consultant_id = {:consultant_id})
+	AND (NOT {:has_from} OR te.entry_date >= {:from})
+	AND (NOT {:has_to} OR te.entry_date <= {:to})`
+
+// bucketsSQL maps status titles to buckets, see configParams
+const bucketsSQL = `SELECT * FROM unnest({:bucket_titles}::text[], {:bucket_names}::text[]) AS b(title, bucket)`
+
+// projectCTEs follows a scope CTE with the latest status bucket (latest) and the debit and credit
+// hours in scope (hours) per project
+const projectCTEs = `buckets AS (` + bucketsSQL + `),
+		latest AS (
+			SELECT DISTINCT ON (s.project_id) s.project_id, s.date_created, COALESCE(b.bucket, 'other') AS bucket
+			FROM project_statuses s
+			JOIN scope ON scope.id = s.project_id
+			LEFT JOIN buckets b ON b.title = s.title
+			ORDER BY s.project_id, s.id DESC
+		),
+		hours AS (
+			SELECT te.project_id,
+				COALESCE(SUM(te.hours) FILTER (WHERE te.type = {:debit_type}), 0) AS debit,
+				COALESCE(SUM(te.hours) FILTER (WHERE te.type = {:credit_type}), 0) AS credit
+			FROM project_time_entries te
+			JOIN scope ON scope.id = te.project_id
+			WHERE ({:consultant_id} = 0 OR te.consultant_id = {:consultant_id})
+				AND (NOT {:has_from} OR te.entry_date >= {:from})
+				AND (NOT {:has_to} OR te.entry_date <= {:to})
+			GROUP BY te.project_id
+		)`
+
+// configParams binds a dashboard configuration to the named params of bucketsSQL and the entry types
+func configParams(cfg entity.DashboardConfig) dbx.Params {
+	titles, buckets := bucketArrays(cfg)
+	return dbx.Params{
+		"bucket_titles": titles,
+		"bucket_names":  buckets,
+		"debit_type":    cfg.DebitType,
+		"credit_type":   cfg.CreditType,
+	}
+}
+
+// filterParams binds a dashboard filter and configuration to the named params of scopeSQL and projectCTEs
+func filterParams(filter entity.DashboardFilter, cfg entity.DashboardConfig) dbx.Params {
+	params := dbx.Params{
+		"manager_id":    filter.ManagerID,
+		"tag":           filter.Tag,
+		"consultant_id": filter.ConsultantID,
+		"from":          filter.From,
+		"to":            filter.To,
+		"has_from":      !filter.From.IsZero(),
+		"has_to":        !filter.To.IsZero(),
+	}
+	for k, v := range configParams(cfg) {
+		params[k] = v
+	}
+	return params
+}
+
+// orderSQL renders the ORDER BY of a leaderboard, column must come from a fixed set of names and
+// ties are broken by the id column
+func orderSQL(column, id string, board entity.Leaderboard) string {
+	direction := "ASC"
+	if board.Descending {
+		direction = "DESC"
+	}
+	return " ORDER BY " + column + " " + direction + " NULLS LAST, " + id + " ASC LIMIT {:limit}"
+}
+
+// GetMetricsByFilter will aggregate the dashboard metrics of the projects in scope of the filter in
+// a single query: latest status, hours and milestones are reduced per project, then over the scope.
+// As in the status history, the latest status is the last one inserted, whatever its date_created. Its
+// title is mapped to a bucket, unmapped titles count as other. A project is idle when its bucket is
+// idle or its latest status is older than the idle threshold, completed projects included.
+func (r *repository) GetMetricsByFilter(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*entity.MetricsDashboard, error) {
+	ctx, span := tracing.Start(ctx, "dashboard.repository.GetMetricsByFilter")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+	params := filterParams(filter, cfg)
+	params["now"] = now
+	params["ending_before"] = now.Add(cfg.EndingSoonWindow)
+	params["idle_before"] = now.Add(-cfg.IdleThreshold)
+
+	var m entity.MetricsDashboard
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH scope AS (` + scopeSQL + `),
+		` + projectCTEs + `,
+		milestones AS (
+			SELECT
+				COUNT(*) FILTER (WHERE m.due_date < {:now}) AS overdue,
+				COUNT(*) FILTER (WHERE m.due_date >= {:now} AND m.due_date < {:ending_before}) AS upcoming
+			FROM project_milestones m
+			JOIN scope ON scope.id = m.project_id
+			WHERE NOT m.completed
+		)
+		SELECT
+			(SELECT COUNT(*) FROM scope) AS projects,
+			(SELECT COUNT(*) FROM latest WHERE bucket = 'active') AS active,
+			(SELECT COUNT(*) FROM latest WHERE bucket = 'completed') AS completed,
+			(SELECT COUNT(*) FROM latest
+				WHERE bucket = 'idle' OR date_created < {:idle_before}) AS idle,
+			(SELECT COUNT(*) FROM latest WHERE bucket = 'other') AS other,
+			(SELECT COUNT(*) FROM hours WHERE credit > debit) AS out_of_budget,
+			(SELECT COALESCE(SUM(debit), 0) FROM hours) AS total_debit,
+			(SELECT COALESCE(SUM(credit), 0) FROM hours) AS total_credit,
+			(SELECT COALESCE(AVG(credit / debit), 0) FROM hours WHERE debit > 0) AS average_credit_over_debit,
+			(SELECT COUNT(*) FROM scope WHERE end_date > {:now} AND end_date < {:ending_before}) AS ending_soon,
+			milestones.overdue AS overdue_milestones,
+			milestones.upcoming AS upcoming_milestones
+		FROM milestones`).
+		Bind(params).
+		One(&m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// managerSortColumns maps leaderboard sort keys to GetManagerMetrics columns
+var managerSortColumns = map[string]string{
+	"projects":    "projects",
+	"active":      "active",
+	"completed":   "completed",
+	"outOfBudget": "out_of_budget",
+	"totalDebit":  "total_debit",
+	"totalCredit": "total_credit",
+}
+
+// GetManagerMetrics will aggregate, per manager of the projects in scope, the project count, the
+// active and completed counts by latest status bucket, the budget overruns and the hours
+func (r *repository) GetManagerMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ManagerMetrics, error) {
+	ctx, span := tracing.Start(ctx, "dashboard.repository.GetManagerMetrics")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	params := filterParams(filter, cfg)
+	params["limit"] = board.Limit
+
+	var list []entity.ManagerMetrics
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH scope AS (` + scopeSQL + `),
+		` + projectCTEs + `
+		SELECT scope.manager_id, c.first_name, c.last_name,
+			COUNT(*) AS projects,
+			COUNT(*) FILTER (WHERE l.bucket = 'active') AS active,
+			COUNT(*) FILTER (WHERE l.bucket = 'completed') AS completed,
+			COUNT(*) FILTER (WHERE h.credit > h.debit) AS out_of_budget,
+			COALESCE(SUM(h.debit), 0) AS total_debit,
+			COALESCE(SUM(h.credit), 0) AS total_credit
+		FROM scope
+		JOIN consultants c ON c.id = scope.manager_id
+		LEFT JOIN latest l ON l.project_id = scope.id
+		LEFT JOIN hours h ON h.project_id = scope.id
+		GROUP BY scope.manager_id, c.first_name, c.last_name` +
+		orderSQL(managerSortColumns[board.Sort], "scope.manager_id", board)).
+		Bind(params).
+		All(&list)
+	return list, err
+}
+
+// consultantSortColumns maps leaderboard sort keys to GetConsultantMetrics columns
+var consultantSortColumns = map[string]string{
+	"projects":    "projects",
+	"totalHours":  "total_hours",
+	"totalDebit":  "total_debit",
+	"totalCredit": "total_credit",
+	"creditShare": "credit_share",
+}
+
+// GetConsultantMetrics will aggregate, per consultant with time entries in scope, the hours logged, the
+// number of projects logged on and the share of credit in the debit and credit hours
+func (r *repository) GetConsultantMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ConsultantMetrics, error) {
+	ctx, span := tracing.Start(ctx, "dashboard.repository.GetConsultantMetrics")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	params := filterParams(filter, cfg)
+	params["limit"] = board.Limit
+
+	var list []entity.ConsultantMetrics
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH scope AS (` + scopeSQL + `),
+		entries AS (
+			SELECT te.consultant_id, te.project_id, te.type, te.hours
+			FROM project_time_entries te
+			JOIN scope ON scope.id = te.project_id
+			WHERE ({:consultant_id} = 0 OR te.consultant_id = {:consultant_id})
+				AND (NOT {:has_from} OR te.entry_date >= {:from})
+				AND (NOT {:has_to} OR te.entry_date <= {:to})
+		),
+		totals AS (
+			SELECT e.consultant_id,
+				COUNT(DISTINCT e.project_id) AS projects,
+				SUM(e.hours) AS total_hours,
+				COALESCE(SUM(e.hours) FILTER (WHERE e.type = {:debit_type}), 0) AS total_debit,
+				COALESCE(SUM(e.hours) FILTER (WHERE e.type = {:credit_type}), 0) AS total_credit
+			FROM entries e
+			GROUP BY e.consultant_id
+		)
+		SELECT t.consultant_id, c.first_name, c.last_name, t.projects, t.total_hours, t.total_debit, t.total_credit,
+			CASE WHEN t.total_debit + t.total_credit > 0
+				THEN t.total_credit / (t.total_debit + t.total_credit)
+				ELSE 0 END AS credit_share
+		FROM totals t
+		JOIN consultants c ON c.id = t.consultant_id` +
+		orderSQL(consultantSortColumns[board.Sort], "t.consultant_id", board)).
+		Bind(params).
+		All(&list)
+	return list, err
+}
+
+// GetSeriesPoints will aggregate, per week or month of [from, to], the debit and credit hours, the
+// projects whose latest status at period end is in the active bucket and the projects moved into the
+// completed bucket in the period. Every period is returned, empty ones included.
+func (r *repository) GetSeriesPoints(ctx context.Context, from, to time.Time, granularity string, cfg entity.DashboardConfig) ([]entity.SeriesPoint, error) {
+	ctx, span := tracing.Start(ctx, "dashboard.repository.GetSeriesPoints")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	params := configParams(cfg)
+	params["from"] = from
+	params["to"] = to
+	params["granularity"] = granularity
+
+	var list []entity.SeriesPoint
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`WITH periods AS (
+			SELECT period_start, period_start + ('1 ' || {:granularity}::text)::interval AS period_end
+			FROM generate_series(
+				date_trunc({:granularity}::text, {:from}::timestamp),
+				date_trunc({:granularity}::text, {:to}::timestamp),
+				('1 ' || {:granularity}::text)::interval
+			) AS period_start
+		),
+		hours AS (
+			SELECT date_trunc({:granularity}::text, te.entry_date) AS period_start,
+				SUM(te.hours) FILTER (WHERE te.type = {:debit_type}) AS total_debit,
+				SUM(te.hours) FILTER (WHERE te.type = {:credit_type}) AS total_credit
+			FROM project_time_entries te
+			WHERE te.entry_date >= date_trunc({:granularity}::text, {:from}::timestamp)
+				AND te.entry_date < date_trunc({:granularity}::text, {:to}::timestamp) + ('1 ' || {:granularity}::text)::interval
+			GROUP BY 1
+		),
+		buckets AS (` + bucketsSQL + `),
+		changes AS (
+			SELECT s.project_id, s.date_created, COALESCE(b.bucket, 'other') AS bucket,
+				LAG(COALESCE(b.bucket, 'other')) OVER (PARTITION BY s.project_id ORDER BY s.date_created, s.id) AS previous_bucket
+			FROM project_statuses s
+			LEFT JOIN buckets b ON b.title = s.title
+		),
+		completed AS (
+			SELECT date_trunc({:granularity}::text, c.date_created) AS period_start,
+				COUNT(DISTINCT c.project_id) AS completed_projects
+			FROM changes c
+			WHERE c.bucket = 'completed' AND c.previous_bucket IS DISTINCT FROM 'completed'
+			GROUP BY 1
+		)
+		SELECT pr.period_start,
+			COALESCE(h.total_debit, 0) AS total_debit,
+			COALESCE(h.total_credit, 0) AS total_credit,
+			CASE WHEN COALESCE(h.total_debit, 0) > 0 THEN COALESCE(h.total_credit, 0) / h.total_debit END AS credit_over_debit,
+			(SELECT COUNT(*) FROM projects p
+				WHERE (SELECT b.bucket FROM project_statuses s
+					LEFT JOIN buckets b ON b.title = s.title
+					WHERE s.project_id = p.id AND s.date_created < pr.period_end
+					ORDER BY s.date_created DESC, s.id DESC LIMIT 1) = 'active'
+			) AS active_projects,
+			COALESCE(c.completed_projects, 0) AS completed_projects
+		FROM periods pr
+		LEFT JOIN hours h ON h.period_start = pr.period_start
+		LEFT JOIN completed c ON c.period_start = pr.period_start
+		ORDER BY pr.period_start`).
+		Bind(params).
+		All(&list)
+	return list, err
+}
@@ -0,0 +1,314 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/consultant"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/meta"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
+	"github.com/renniemaharaj/project-list-go/internal/project"
+	"github.com/renniemaharaj/project-list-go/internal/schema"
+	"github.com/renniemaharaj/project-list-go/internal/status"
+	internalTime "github.com/renniemaharaj/project-list-go/internal/time"
+	"github.com/renniemaharaj/project-list-go/internal/utils"
+)
+
+// Size of the benchmark portfolio
+const (
+	benchConsultants = 50
+	benchProjects    = 1000
+	benchTimeEntries = 100000
+)
+
+// seedBenchmark seeds benchConsultants consultants managing benchProjects projects with a status, two
+// milestones and their share of benchTimeEntries time entries each into the TEST_POSTGRES_DSN database.
+// The seeded rows are removed once b completes. It skips b when no DSN is set.
+func seedBenchmark(b *testing.B) *database.DBContext {
+	b.Helper()
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		b.Skip("TEST_POSTGRES_DSN not set")
+	}
+	dbContext := database.New(config.Database{TestingDSN: dsn, Pool: config.Default().Database.Pool}).Testing
+	db, err := dbContext.Resolve()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	l := logger.New().Prefix("Dashboard Benchmark")
+	if err := schema.NewRepository(dbContext, l).InitializeDatabaseTables(ctx); err != nil {
+		b.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	params := dbx.Params{
+		"prefix":      fmt.Sprintf("bench-%d-", time.Now().UnixNano()),
+		"consultants": benchConsultants,
+		"projects":    benchProjects,
+		"entries":     benchTimeEntries,
+		"debit_type":  cfg.DebitType,
+		"credit_type": cfg.CreditType,
+	}
+	b.Cleanup(func() {
+		for _, q := range []string{
+			`DELETE FROM projects WHERE number LIKE {:prefix}::text || '%'`,
+			`DELETE FROM consultants WHERE email LIKE {:prefix}::text || '%'`,
+		} {
+			if _, err := db.NewQuery(q).Bind(params).Execute(); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+
+	err = dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		for _, q := range []string{
+			`INSERT INTO consultants (first_name, last_name, email)
+				SELECT 'Bench', 'Consultant ' || g, {:prefix}::text || g || '@bench.test'
+				FROM generate_series(1, {:consultants}) g`,
+			`INSERT INTO projects (manager_id, number, name, start_date, end_date)
+				SELECT c.id, {:prefix}::text || g, 'Bench project ' || g, NOW() - interval '1 year', NOW() + (g % 60) * interval '1 day'
+				FROM generate_series(1, {:projects}) g
+				JOIN consultants c ON c.email = {:prefix}::text || (1 + g % {:consultants}) || '@bench.test'`,
+			`INSERT INTO project_statuses (project_id, title, date_created)
+				SELECT p.id, (ARRAY['active', 'completed', 'on hold'])[1 + p.id % 3], NOW() - (p.id % 30) * interval '1 day'
+				FROM projects p WHERE p.number LIKE {:prefix}::text || '%'`,
+			`INSERT INTO project_milestones (project_id, title, due_date, completed)
+				SELECT p.id, 'Bench milestone ' || m, NOW() + (p.id % 20 - 10 + m) * interval '1 day', p.id % 4 = 0
+				FROM projects p, generate_series(1, 2) m WHERE p.number LIKE {:prefix}::text || '%'`,
+			`WITH p AS (
+				SELECT id, manager_id, row_number() OVER (ORDER BY id) AS n
+				FROM projects WHERE number LIKE {:prefix}::text || '%'
+			)
+			INSERT INTO project_time_entries (project_id, consultant_id, type, hours, title, entry_date)
+				SELECT p.id, p.manager_id, CASE WHEN g % 3 = 0 THEN {:credit_type} ELSE {:debit_type} END,
+					1 + g % 8, 'Bench entry', NOW() - (g % 365) * interval '1 day'
+				FROM generate_series(1, {:entries}) g
+				JOIN p ON p.n = 1 + g % {:projects}`,
+			`ANALYZE`,
+		} {
+			if _, err := tx.NewQuery(q).Bind(params).Execute(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return dbContext
+}
+
+// partial holds the dashboard metrics of one project in loadEverything
+type partial struct {
+	Completed, Active, Idle, OutOfBudget  int
+	TotalDebit, TotalCredit               float64
+	CreditOverDebitRatio                  float64 // valid only if HasRatio
+	HasRatio                              bool
+	OverdueMilestones, UpcomingMilestones int
+}
+
+// loadEverything computes the unfiltered dashboard the way the handler did before the aggregation
+// moved to SQL: the ids of every project, then their meta with all of their time entries, are loaded
+// and a worker pool reduces every project before the partials are summed. The cache is left out as
+// redis is not part of the comparison, titles, types and windows are those of the default config.
+func loadEverything(ctx context.Context, projects project.Service, metas meta.Service, cfg entity.DashboardConfig) (*entity.MetricsDashboard, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 1) Fetch project IDs in scope
+	projectIDS, err := projects.GetAllProjectIDS(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectIDS) == 0 {
+		return &entity.MetricsDashboard{}, nil
+	}
+	// 2) Fetch project rows and meta in bulk
+	projectMetas, projectData, err := metas.GetProjectsMetaByProjectIDS(ctx, projectIDS, true)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// 3) Pre-compute ending soon from projects
+	var result entity.MetricsDashboard
+	result.Projects = len(projectData)
+	for _, p := range projectData {
+		if p.EndDate.After(now) && p.EndDate.Before(now.Add(cfg.EndingSoonWindow)) {
+			result.EndingSoon++
+		}
+	}
+
+	// 4) Fan-out: compute per-project partials in a worker pool
+	jobs := make(chan int)
+	results := make(chan partial)
+	worker := func() {
+		for id := range jobs {
+			pm := projectMetas[id]
+
+			var p partial
+			// Latest status at index 0 if present
+			if len(pm.StatusHistory) > 0 {
+				switch pm.StatusHistory[0].Title {
+				case "completed":
+					p.Completed = 1
+				case "active":
+					p.Active = 1
+				}
+				if now.Sub(pm.StatusHistory[0].DateCreated) > cfg.IdleThreshold {
+					p.Idle = 1
+				}
+			}
+
+			var debit, credit float64
+			for _, t := range pm.TimeEntries {
+				switch t.Type {
+				case cfg.DebitType:
+					debit += float64(t.Hours)
+				case cfg.CreditType:
+					credit += float64(t.Hours)
+				}
+			}
+			for _, m := range pm.Milestones {
+				if m.Completed {
+					continue
+				}
+				switch {
+				case m.DueDate.Before(now):
+					p.OverdueMilestones++
+				case m.DueDate.Before(now.Add(cfg.EndingSoonWindow)):
+					p.UpcomingMilestones++
+				}
+			}
+
+			p.TotalDebit = debit
+			p.TotalCredit = credit
+			if credit > debit {
+				p.OutOfBudget = 1
+			}
+			if debit > 0 {
+				p.HasRatio = true
+				p.CreditOverDebitRatio = credit / debit
+			}
+			select {
+			case results <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	for range utils.MinMax(200, runtime.NumCPU()*4, 0) {
+		go worker()
+	}
+	go func() {
+		defer close(jobs)
+		for _, p := range projectData {
+			select {
+			case jobs <- p.ID:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 5) Aggregate
+	var (
+		totalRatios float64
+		ratioCount  int
+	)
+	for range projectData {
+		p := <-results
+		result.Completed += p.Completed
+		result.Active += p.Active
+		result.Idle += p.Idle
+		result.OutOfBudget += p.OutOfBudget
+		result.TotalDebit += p.TotalDebit
+		result.TotalCredit += p.TotalCredit
+		result.OverdueMilestones += p.OverdueMilestones
+		result.UpcomingMilestones += p.UpcomingMilestones
+		if p.HasRatio {
+			totalRatios += p.CreditOverDebitRatio
+			ratioCount++
+		}
+	}
+	if ratioCount > 0 {
+		result.AverageCreditOverDebit = float32(totalRatios / float64(ratioCount))
+	}
+	return &result, nil
+}
+
+// agree reports whether the SQL aggregation matches every metric of loadEverything, Other did not
+// exist before and hours are summed in another order
+func agree(aggregated, loaded *entity.MetricsDashboard) bool {
+	a, l := *aggregated, *loaded
+	if math.Abs(a.TotalDebit-l.TotalDebit) > 0.5 || math.Abs(a.TotalCredit-l.TotalCredit) > 0.5 ||
+		math.Abs(float64(a.AverageCreditOverDebit-l.AverageCreditOverDebit)) > 1e-3 {
+		return false
+	}
+	a.TotalDebit, a.TotalCredit, a.AverageCreditOverDebit, a.Other = 0, 0, 0, 0
+	l.TotalDebit, l.TotalCredit, l.AverageCreditOverDebit, l.Other = 0, 0, 0, 0
+	return a == l
+}
+
+// BenchmarkGetMetricsDashboard compares the SQL aggregation of the unfiltered dashboard with loading
+// every time entry and reducing them in Go, run it against a disposable database:
+//
+//	TEST_POSTGRES_DSN=... go test -run '^$' -bench GetMetricsDashboard ./internal/dashboard
+func BenchmarkGetMetricsDashboard(b *testing.B) {
+	db := seedBenchmark(b)
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	l := logger.New().Prefix("Dashboard Benchmark")
+
+	repo := NewRepository(db, l)
+	projectRepository := project.NewRepository(db, l)
+	projects := project.NewService(projectRepository, l)
+	metas := meta.NewService(meta.NewRepository(
+		internalTime.NewRepository(db, l),
+		status.NewRepository(db, l),
+		projectRepository,
+		consultant.NewRepository(db, l),
+		milestone.NewRepository(db, l),
+		l,
+	), l)
+
+	// both paths must agree on every metric before their timings mean anything
+	aggregated, err := repo.GetMetricsByFilter(ctx, entity.DashboardFilter{}, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	loaded, err := loadEverything(ctx, projects, metas, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if !agree(aggregated, loaded) {
+		b.Fatalf("aggregated %+v, loaded %+v", aggregated, loaded)
+	}
+
+	b.Run("sql", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := repo.GetMetricsByFilter(ctx, entity.DashboardFilter{}, cfg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("load-everything", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := loadEverything(ctx, projects, metas, cfg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
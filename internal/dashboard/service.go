@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// Series granularities, passed to date_trunc as is
+const (
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// Leaderboard limits
+const (
+	DefaultLeaderboardLimit = 10
+	MaxLeaderboardLimit     = 100
+)
+
+var (
+	// ErrInvalidSeries is returned when a series window or granularity fails validation
+	ErrInvalidSeries = apperror.New(apperror.KindValidation, "invalid series")
+	// ErrInvalidLeaderboard is returned when a leaderboard sort or limit fails validation
+	ErrInvalidLeaderboard = apperror.New(apperror.KindValidation, "invalid leaderboard")
+)
+
+type Service interface {
+	GetMetricsDashboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*MetricsDashboard, error)
+	GetMetricsSeries(ctx context.Context, from, to time.Time, granularity string, cfg entity.DashboardConfig) (*MetricsSeries, error)
+	GetManagerLeaderboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ManagerMetrics, error)
+	GetConsultantLeaderboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ConsultantMetrics, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type MetricsDashboard struct {
+	entity.MetricsDashboard
+}
+
+type MetricsSeries struct {
+	entity.MetricsSeries
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// GetMetricsDashboard aggregates the dashboard metrics of the projects in scope of the filter
+func (s *service) GetMetricsDashboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig) (*MetricsDashboard, error) {
+	m, err := s.repo.GetMetricsByFilter(ctx, filter, cfg)
+	if err != nil {
+		return &MetricsDashboard{}, err
+	}
+	return &MetricsDashboard{*m}, nil
+}
+
+// GetMetricsSeries returns one point per week or month of [from, to]
+func (s *service) GetMetricsSeries(ctx context.Context, from, to time.Time, granularity string, cfg entity.DashboardConfig) (*MetricsSeries, error) {
+	if granularity != GranularityWeek && granularity != GranularityMonth {
+		return &MetricsSeries{}, ErrInvalidSeries.WithField("granularity", fmt.Sprintf("granularity must be %s or %s", GranularityWeek, GranularityMonth))
+	}
+	if to.Before(from) {
+		return &MetricsSeries{}, ErrInvalidSeries.WithField("to", "to must not be before from")
+	}
+
+	points, err := s.repo.GetSeriesPoints(ctx, from, to, granularity, cfg)
+	if err != nil {
+		return &MetricsSeries{}, err
+	}
+	if points == nil {
+		points = []entity.SeriesPoint{}
+	}
+	return &MetricsSeries{entity.MetricsSeries{From: from, To: to, Granularity: granularity, Points: points}}, nil
+}
+
+// validateLeaderboard defaults the sort and limit of a leaderboard and checks them against columns
+func validateLeaderboard(board *entity.Leaderboard, columns map[string]string, defaultSort string) error {
+	if board.Sort == "" {
+		board.Sort = defaultSort
+	}
+	if _, ok := columns[board.Sort]; !ok {
+		return ErrInvalidLeaderboard.WithField("sort", fmt.Sprintf("cannot sort by %q", board.Sort))
+	}
+	if board.Limit == 0 {
+		board.Limit = DefaultLeaderboardLimit
+	}
+	if board.Limit < 0 || board.Limit > MaxLeaderboardLimit {
+		return ErrInvalidLeaderboard.WithField("limit", fmt.Sprintf("limit must be between 1 and %d", MaxLeaderboardLimit))
+	}
+	return nil
+}
+
+// GetManagerLeaderboard returns the top managers of the projects in scope, by project count by default
+func (s *service) GetManagerLeaderboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ManagerMetrics, error) {
+	if err := validateLeaderboard(&board, managerSortColumns, "projects"); err != nil {
+		return []entity.ManagerMetrics{}, err
+	}
+	list, err := s.repo.GetManagerMetrics(ctx, filter, cfg, board)
+	if err != nil || list == nil {
+		return []entity.ManagerMetrics{}, err
+	}
+	return list, nil
+}
+
+// GetConsultantLeaderboard returns the top consultants by hours logged in scope, by total hours by default
+func (s *service) GetConsultantLeaderboard(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ConsultantMetrics, error) {
+	if err := validateLeaderboard(&board, consultantSortColumns, "totalHours"); err != nil {
+		return []entity.ConsultantMetrics{}, err
+	}
+	list, err := s.repo.GetConsultantMetrics(ctx, filter, cfg, board)
+	if err != nil || list == nil {
+		return []entity.ConsultantMetrics{}, err
+	}
+	return list, nil
+}
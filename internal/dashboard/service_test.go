@@ -0,0 +1,111 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// fakeRepository has no series points or leaderboards and records what it was asked for
+type fakeRepository struct {
+	Repository
+	granularity string
+	board       *entity.Leaderboard
+}
+
+func (f *fakeRepository) GetSeriesPoints(ctx context.Context, from, to time.Time, granularity string, cfg entity.DashboardConfig) ([]entity.SeriesPoint, error) {
+	f.granularity = granularity
+	return nil, nil
+}
+
+func (f *fakeRepository) GetManagerMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ManagerMetrics, error) {
+	f.board = &board
+	return nil, nil
+}
+
+func (f *fakeRepository) GetConsultantMetrics(ctx context.Context, filter entity.DashboardFilter, cfg entity.DashboardConfig, board entity.Leaderboard) ([]entity.ConsultantMetrics, error) {
+	f.board = &board
+	return nil, nil
+}
+
+func TestGetMetricsSeries(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 6, 0)
+
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		granularity string
+		wantErr     bool
+	}{
+		{"weekly", from, to, GranularityWeek, false},
+		{"monthly", from, to, GranularityMonth, false},
+		{"single day", from, from, GranularityWeek, false},
+		{"daily", from, to, "day", true},
+		{"sql in granularity", from, to, "month'); DROP TABLE projects; --", true},
+		{"reversed window", to, from, GranularityMonth, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			series, err := NewService(repo, logger.New().Prefix("Dashboard Test")).GetMetricsSeries(context.Background(), tt.from, tt.to, tt.granularity, DefaultConfig())
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSeries) || repo.granularity != "" {
+					t.Fatalf("err = %v, queried %q, want ErrInvalidSeries before querying", err, repo.granularity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// no points is an empty series, not null
+			if series.Points == nil || series.Granularity != tt.granularity || repo.granularity != tt.granularity {
+				t.Fatalf("series = %+v", series)
+			}
+		})
+	}
+}
+
+func TestGetManagerLeaderboard(t *testing.T) {
+	tests := []struct {
+		board   entity.Leaderboard
+		want    entity.Leaderboard
+		wantErr bool
+	}{
+		{board: entity.Leaderboard{}, want: entity.Leaderboard{Sort: "projects", Limit: DefaultLeaderboardLimit}},
+		{board: entity.Leaderboard{Sort: "outOfBudget", Limit: 3}, want: entity.Leaderboard{Sort: "outOfBudget", Limit: 3}},
+		{board: entity.Leaderboard{Limit: MaxLeaderboardLimit}, want: entity.Leaderboard{Sort: "projects", Limit: MaxLeaderboardLimit}},
+		{board: entity.Leaderboard{Sort: "creditShare"}, wantErr: true}, // a consultant column
+		{board: entity.Leaderboard{Sort: "projects; DROP TABLE projects"}, wantErr: true},
+		{board: entity.Leaderboard{Limit: -1}, wantErr: true},
+		{board: entity.Leaderboard{Limit: MaxLeaderboardLimit + 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		repo := &fakeRepository{}
+		list, err := NewService(repo, logger.New().Prefix("Dashboard Test")).GetManagerLeaderboard(context.Background(), entity.DashboardFilter{}, DefaultConfig(), tt.board)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidLeaderboard) || repo.board != nil {
+				t.Errorf("%+v: err = %v, want ErrInvalidLeaderboard before querying", tt.board, err)
+			}
+			continue
+		}
+		if err != nil || list == nil || repo.board == nil || *repo.board != tt.want {
+			t.Errorf("%+v: list = %v, err = %v, queried %+v, want %+v", tt.board, list, err, repo.board, tt.want)
+		}
+	}
+}
+
+func TestGetConsultantLeaderboard(t *testing.T) {
+	repo := &fakeRepository{}
+	s := NewService(repo, logger.New().Prefix("Dashboard Test"))
+	if _, err := s.GetConsultantLeaderboard(context.Background(), entity.DashboardFilter{}, DefaultConfig(), entity.Leaderboard{}); err != nil || repo.board.Sort != "totalHours" {
+		t.Fatalf("err = %v, sort = %q, want totalHours", err, repo.board.Sort)
+	}
+	if _, err := s.GetConsultantLeaderboard(context.Background(), entity.DashboardFilter{}, DefaultConfig(), entity.Leaderboard{Sort: "active"}); !errors.Is(err, ErrInvalidLeaderboard) {
+		t.Fatalf("err = %v, want ErrInvalidLeaderboard for a manager column", err)
+	}
+}
@@ -1,61 +1,127 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
-	"github.com/joho/godotenv"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
 )
 
 // DBContext encapsulates a singleton DB connection
 type DBContext struct {
-	envVar string
-	dbx    *dbx.DB
-	err    error
+	envVar           string
+	dsn              string
+	pool             config.Pool
+	statementTimeout time.Duration
+	dbx              *dbx.DB
+	err              error
+
+	// candidates are resolved in order by the automatic profile
+	candidates []*DBContext
+	// fallback serves Get while a replica is not usable, usable is maintained by MonitorReplica
+	fallback *DBContext
+	usable   atomic.Bool
 }
 
-var (
-	databaseLogger = logger.NewLogger().Prefix("Database Logger")
+var databaseLogger = logger.NewLogger().Prefix("Database Logger")
 
+// Databases are the database profiles of a configuration, none is connected before it is resolved
+type Databases struct {
 	// Automatically resolves to the best available DB trying Production -> Docker -> Developer
-	Automatic = newInstance("AUTOMATIC")
+	Automatic *DBContext
 
 	// Explicit DB instances in order of priority
-	Production = newInstance("PROD_POSTGRES_DSN")
-	Docker     = newInstance("DOCKER_POSTGRES_DSN")
-	Developer  = newInstance("DEV_POSTGRES_DSN")
-	Testing    = newInstance("TEST_POSTGRES_DSN")
-)
+	Production *DBContext
+	Docker     *DBContext
+	Developer  *DBContext
+	Testing    *DBContext
+
+	// Replica serves heavy reads that tolerate replication lag, it falls back to Automatic while no
+	// replica is configured, reachable or within the allowed lag
+	Replica *DBContext
+}
 
-// newInstance creates a new dbInstance tied to an env var
-func newInstance(envVar string) *DBContext {
-	return &DBContext{envVar: envVar}
+// New returns the database profiles of cfg with their DSN, pool and statement timeout
+func New(cfg config.Database) *Databases {
+	profile := func(envVar, dsn string) *DBContext {
+		return &DBContext{envVar: envVar, dsn: dsn, pool: cfg.Pool, statementTimeout: cfg.StatementTimeout}
+	}
+	d := &Databases{
+		Production: profile("PROD_POSTGRES_DSN", cfg.ProductionDSN),
+		Docker:     profile("DOCKER_POSTGRES_DSN", cfg.DockerDSN),
+		Developer:  profile("DEV_POSTGRES_DSN", cfg.DeveloperDSN),
+		Testing:    profile("TEST_POSTGRES_DSN", cfg.TestingDSN),
+	}
+	d.Automatic = &DBContext{envVar: "AUTOMATIC", statementTimeout: cfg.StatementTimeout,
+		candidates: []*DBContext{d.Production, d.Docker, d.Developer}}
+	d.Replica = &DBContext{envVar: "REPLICA_POSTGRES_DSN", dsn: cfg.Replica.DSN, pool: cfg.Pool,
+		statementTimeout: cfg.Replica.StatementTimeout, fallback: d.Automatic}
+	return d
 }
 
-// Get returns the dbx db of the db context
+// Get returns the dbx db of the db context, or of its fallback while a replica is not usable
 func (dbContext *DBContext) Get() *dbx.DB {
+	if dbContext.fallback != nil && !dbContext.usable.Load() {
+		return dbContext.fallback.Get()
+	}
 	return dbContext.dbx
 }
 
-// EnvVar returns the environment variable name used by this db context
+// WithTimeout returns ctx bounded by the statement timeout of this db context, so a repository call is
+// canceled on the server once it runs longer. The timeout is also a run-time parameter of every
+// connection, which covers callers without a deadline but not poolers dropping startup parameters.
+func (dbContext *DBContext) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dbContext.statementTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, dbContext.statementTimeout)
+}
+
+// Ping checks the connection of this db context within ctx
+func (dbContext *DBContext) Ping(ctx context.Context) error {
+	if dbContext.dbx == nil {
+		return fmt.Errorf("%s not connected", dbContext.envVar)
+	}
+	return dbContext.dbx.DB().PingContext(ctx)
+}
+
+// Close closes the connection pool of this db context
+func (dbContext *DBContext) Close() error {
+	dbContext.usable.Store(false)
+	if dbContext.dbx == nil {
+		return nil
+	}
+	err := dbContext.dbx.Close()
+	dbContext.dbx = nil
+	return err
+}
+
+// EnvVar returns the environment variable name of the profile used by this db context
 func (dbContext *DBContext) EnvVar() string {
 	return dbContext.envVar
 }
 
+// DSN returns the connection string of this db context, for connections outside the pool such as listeners
+func (dbContext *DBContext) DSN() string {
+	return dbContext.dsn
+}
+
 // Resolve automatically chooses database by trying Production -> Docker -> Developer
 func (dbContext *DBContext) Resolve() (*dbx.DB, error) {
-	if dbContext.envVar == "AUTOMATIC" {
+	if dbContext.candidates != nil {
 		// Try in order, but assign result back into Automatic (self)
-		candidates := []*DBContext{Production, Docker, Developer}
-
-		for _, candidate := range candidates {
+		for _, candidate := range dbContext.candidates {
 			if db, err := candidate.GetManual(); err == nil {
 				// Mutate Automatic to hold this DB
 				dbContext.dbx = candidate.dbx
+				dbContext.dsn = candidate.dsn
 				dbContext.err = candidate.err
 				dbContext.envVar = candidate.envVar
 				databaseLogger.Warning(fmt.Sprintf("Automatic bound to %s", candidate.envVar))
@@ -63,7 +129,7 @@ func (dbContext *DBContext) Resolve() (*dbx.DB, error) {
 			}
 		}
 
-		panic("Adaptive DBContext: no valid DSN found (PROD_POSTGRES_DSN, DOCKER_POSTGRES_DSN, DEV_POSTGRES_DSN)")
+		return nil, fmt.Errorf("adaptive DBContext: no reachable DSN found (PROD_POSTGRES_DSN, DOCKER_POSTGRES_DSN, DEV_POSTGRES_DSN)")
 	}
 
 	return dbContext.GetManual()
@@ -72,26 +138,36 @@ func (dbContext *DBContext) Resolve() (*dbx.DB, error) {
 // GetManual tries to open and test a DB connection for this context
 func (dbContext *DBContext) GetManual() (*dbx.DB, error) {
 	if dbContext.dbx == nil {
-		_ = godotenv.Load()
-
-		dsn := os.Getenv(dbContext.envVar)
+		dsn := dbContext.dsn
 		if dsn == "" {
 			dbContext.err = fmt.Errorf("%s not set", dbContext.envVar)
 			databaseLogger.Warning(dbContext.err.Error())
 			return nil, dbContext.err
 		}
 
+		dsn, err := withStatementTimeout(dsn, dbContext.statementTimeout)
+		if err != nil {
+			dbContext.err = fmt.Errorf("invalid DSN (%s): %w", dbContext.envVar, err)
+			databaseLogger.Error(dbContext.err.Error())
+			return nil, dbContext.err
+		}
+
 		db, err := dbx.Open("postgres", dsn)
 		if err != nil {
 			dbContext.err = fmt.Errorf("failed to open DB (%s): %w", dbContext.envVar, err)
-			databaseLogger.Fatal(dbContext.err)
+			databaseLogger.Error(dbContext.err.Error())
 			return nil, dbContext.err
 		}
+		db.DB().SetMaxOpenConns(dbContext.pool.MaxOpenConns)
+		db.DB().SetMaxIdleConns(dbContext.pool.MaxIdleConns)
+		db.DB().SetConnMaxLifetime(dbContext.pool.ConnMaxLifetime)
+		db.DB().SetConnMaxIdleTime(dbContext.pool.ConnMaxIdleTime)
 
 		// Test connection
 		if pingErr := db.DB().Ping(); pingErr != nil {
 			dbContext.err = fmt.Errorf("failed to ping DB (%s): %w", dbContext.envVar, pingErr)
 			databaseLogger.Warning(dbContext.err.Error())
+			db.Close()
 			return nil, dbContext.err
 		}
 
@@ -101,3 +177,19 @@ func (dbContext *DBContext) GetManual() (*dbx.DB, error) {
 
 	return dbContext.dbx, dbContext.err
 }
+
+// withStatementTimeout adds the statement_timeout run-time parameter to dsn, so the server cancels
+// statements of every connection running longer than timeout
+func withStatementTimeout(dsn string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return dsn, nil
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		converted, err := pq.ParseURL(dsn)
+		if err != nil {
+			return "", err
+		}
+		dsn = converted
+	}
+	return fmt.Sprintf("%s statement_timeout=%d", dsn, timeout.Milliseconds()), nil
+}
@@ -6,11 +6,15 @@ import (
 	"time"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/project-list-go/internal/metrics"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
-// QueryDBLogFunc returns a logging function that can be used to log SQL queries.
+// QueryDBLogFunc returns a logging function that can be used to log SQL queries and record their metrics and spans.
 func QueryDBLogFunc() dbx.QueryLogFunc {
 	return func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+		metrics.ObserveDB("query", t, err)
+		tracing.RecordSQL(ctx, "query", t, sql, err)
 		if err == nil {
 			databaseLogger.SuccessF("DB query %s completed successfully in %d ms", sql, t.Milliseconds())
 		} else {
@@ -19,9 +23,11 @@ func QueryDBLogFunc() dbx.QueryLogFunc {
 	}
 }
 
-// ExecDBLogFunc returns a logging function that can be used to log SQL executions.
+// ExecDBLogFunc returns a logging function that can be used to log SQL executions and record their metrics and spans.
 func ExecDBLogFunc() dbx.ExecLogFunc {
 	return func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+		metrics.ObserveDB("exec", t, err)
+		tracing.RecordSQL(ctx, "exec", t, sql, err)
 		if err == nil {
 			databaseLogger.SuccessF("DB exec %s completed successfully in %d ms", sql, t.Milliseconds())
 		} else {
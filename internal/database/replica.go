@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/metrics"
+)
+
+// replicaLagSQL returns the replication lag in seconds, 0 while the replica replayed everything it
+// received or is not a replica at all, and NULL while it never replayed a transaction
+const replicaLagSQL = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+END`
+
+// MonitorReplica connects the replica db context and checks its replication lag every interval until
+// ctx is done. The replica serves reads only while it is connected and lags at most maxLag behind.
+func (dbContext *DBContext) MonitorReplica(ctx context.Context, maxLag, interval time.Duration) {
+	defer dbContext.usable.Store(false)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		usable := dbContext.checkReplica(ctx, maxLag)
+		if was := dbContext.usable.Swap(usable); was != usable {
+			if usable {
+				databaseLogger.Success("Routing reads to the replica")
+			} else {
+				databaseLogger.Warning("Routing reads to the primary, the replica is unreachable or lagging")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReplica connects the replica when needed and reports whether its lag is within maxLag
+func (dbContext *DBContext) checkReplica(ctx context.Context, maxLag time.Duration) bool {
+	if dbContext.dbx == nil {
+		db, err := dbContext.GetManual()
+		if err != nil {
+			metrics.SetReplicaLag(-1)
+			return false
+		}
+		db.QueryLogFunc = QueryDBLogFunc()
+		db.ExecLogFunc = ExecDBLogFunc()
+		if err := metrics.RegisterDBStats(db.DB(), "postgres_replica"); err != nil {
+			databaseLogger.Warning("Replica pool stats not exported: " + err.Error())
+		}
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, maxLag)
+	defer cancel()
+	var lag sql.NullFloat64
+	if err := dbContext.dbx.WithContext(checkCtx).NewQuery(replicaLagSQL).Row(&lag); err != nil || !lag.Valid {
+		metrics.SetReplicaLag(-1)
+		return false
+	}
+	metrics.SetReplicaLag(lag.Float64)
+	return lag.Float64 <= maxLag.Seconds()
+}
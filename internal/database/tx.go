@@ -7,8 +7,10 @@ import (
 )
 
 // UseTransaction provides an interface for transactions with defer rollback, error handling
-// and transaction commit
+// and transaction commit, the transaction is bounded by the statement timeout of the db context
 func (dbContext *DBContext) UseTransaction(ctx context.Context, consume func(tx *dbx.Tx) error) error {
+	ctx, cancel := dbContext.WithTimeout(ctx)
+	defer cancel()
 	tx, err := dbContext.Get().WithContext(ctx).Begin()
 	if err != nil {
 		return err
@@ -11,6 +11,7 @@ import (
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
 	internalConsultant "github.com/renniemaharaj/project-list-go/internal/consultant"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
@@ -19,7 +20,9 @@ import (
 	internalStatus "github.com/renniemaharaj/project-list-go/internal/status"
 	internalTag "github.com/renniemaharaj/project-list-go/internal/tag"
 	"github.com/renniemaharaj/project-list-go/internal/time"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 	"github.com/renniemaharaj/project-list-go/internal/utils"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 )
 
 type Repository interface {
@@ -28,11 +31,13 @@ type Repository interface {
 
 type repository struct {
 	dbContext *database.DBContext
+	types     config.TimeEntryTypes
 	l         *logger.Logger
 }
 
-func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository {
-	return &repository{dbContext, _l}
+// NewRepository returns a demo repository generating time entries of types
+func NewRepository(dbContext *database.DBContext, types config.TimeEntryTypes, _l *logger.Logger) Repository {
+	return &repository{dbContext, types, _l}
 }
 
 var (
@@ -43,6 +48,11 @@ var (
 // GenerateInsertDemoData inserts multiple demo consultants, projects, statuses, time entries,
 // consultant roles, consultant projects, and project tags.
 func (r *repository) GenerateInsertDemoData(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.GenerateInsertDemoData")
+	defer span.End()
+	// the demo data is no change of interest to webhook subscribers
+	ctx = webhook.WithoutEvents(ctx)
+
 	// Check if projects already exist
 	var count int
 	if err := r.dbContext.Get().NewQuery("SELECT COUNT(*) FROM projects").Row(&count); err != nil {
@@ -59,14 +69,12 @@ func (r *repository) GenerateInsertDemoData(ctx context.Context) error {
 
 	contents, err := os.ReadFile("cfx_c.json")
 	if err != nil {
-		r.l.Fatal(err)
-		panic("cfx_c.json containing consultants not found")
+		return fmt.Errorf("reading demo consultants from cfx_c.json: %w", err)
 	}
 
 	err = json.Unmarshal(contents, &consultants)
 	if err != nil {
-		r.l.Fatal(err)
-		panic("Consultants hydrate error")
+		return fmt.Errorf("decoding demo consultants: %w", err)
 	}
 
 	roles := []string{"administrator", "manager", "consultant"}
@@ -83,13 +91,16 @@ func (r *repository) GenerateInsertDemoData(ctx context.Context) error {
 
 // Internal generateProject method coordinates project generations
 func (r *repository) generateProject(ctx context.Context, c *entity.Consultant, index int, roles []string) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.generateProject")
+	defer span.End()
+
 	tags := []string{"support", "implementation", "custom", "reports", "software"}
 
 	project := &entity.Project{
 		Name:        fmt.Sprintf("Demo Project %d (by %s)", index, c.FirstName),
 		Number:      fmt.Sprintf("PRJ-%03d-%d", index, c.ID),
 		Description: fmt.Sprintf("Auto-generated demo project %d for consultant %s", index, c.FirstName),
-		ManagerID:   c.ID,
+		ManagerID:   &c.ID,
 	}
 	if err := internalProject.NewRepository(r.dbContext, r.l).InsertProjectByStruct(ctx, project); err != nil {
 		return err
@@ -118,6 +129,9 @@ func (r *repository) generateProject(ctx context.Context, c *entity.Consultant,
 
 // Internal helper function insertsConsultants into the consultants domain table
 func (r *repository) insertConsultants(ctx context.Context, consultants []entity.Consultant, roles []string) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.insertConsultants")
+	defer span.End()
+
 	for ci := range consultants {
 		c := &consultants[ci]
 		c.ProfilePicture = fmt.Sprintf("https://api.dicebear.com/7.x/lorelei/svg?seed=%s", url.QueryEscape(c.Email))
@@ -141,6 +155,9 @@ func (r *repository) insertConsultants(ctx context.Context, consultants []entity
 
 // Internal generateProjectForConsultants method orchestrates generating x projects for each inserted consultant
 func (r *repository) generateProjectsForConsultants(ctx context.Context, consultants []entity.Consultant, roles []string) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.generateProjectsForConsultants")
+	defer span.End()
+
 	for _, c := range consultants {
 		projectCount := rand.Intn(maxProjectPerConsultant) + 1
 		for pi := 0; pi < projectCount; pi++ {
@@ -155,6 +172,9 @@ func (r *repository) generateProjectsForConsultants(ctx context.Context, consult
 
 // Internal Generate random statuses, generates random statuses for a given project
 func (r *repository) generateRandomStatuses(ctx context.Context, c *entity.Consultant, project *entity.Project) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.generateRandomStatuses")
+	defer span.End()
+
 	allStatuses := []string{"planned", "active", "on-hold", "completed"}
 	statusCount := rand.Intn(len(allStatuses)-1) + 1
 	for i := 0; i < statusCount; i++ {
@@ -174,6 +194,9 @@ func (r *repository) generateRandomStatuses(ctx context.Context, c *entity.Consu
 
 // Internal assignConsultantsToProject method assigns consultants to project
 func (r *repository) assignConsultantsToProject(ctx context.Context, project *entity.Project, roles []string) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.assignConsultantsToProject")
+	defer span.End()
+
 	allConsultants, err := internalConsultant.NewRepository(r.dbContext, r.l).GetAllConsultants(ctx)
 	if err != nil {
 		return err
@@ -195,6 +218,9 @@ func (r *repository) assignConsultantsToProject(ctx context.Context, project *en
 
 // Internal insertProjectTags inserts tags for project
 func (r *repository) insertProjectTags(ctx context.Context, project *entity.Project, tags []string) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.insertProjectTags")
+	defer span.End()
+
 	tagCount := rand.Intn(len(tags)) + 1
 	for i := 0; i < tagCount; i++ {
 		tag := &entity.ProjectTag{ProjectID: project.ID, Tag: tags[rand.Intn(len(tags))]}
@@ -207,6 +233,9 @@ func (r *repository) insertProjectTags(ctx context.Context, project *entity.Proj
 
 // Internal insertTimeEntries method inserts varying time entries into project
 func (r *repository) insertTimeEntries(ctx context.Context, project *entity.Project, allConsultants []entity.Consultant) error {
+	ctx, span := tracing.Start(ctx, "demo.repository.insertTimeEntries")
+	defer span.End()
+
 	// choose random number of consultants (1 up to allConsultants length, capped by Max)
 	numConsultants := rand.Intn(utils.MinMax(1, len(allConsultants), 5)) + 1
 	rand.Shuffle(len(allConsultants), func(i, j int) { allConsultants[i], allConsultants[j] = allConsultants[j], allConsultants[i] })
@@ -242,10 +271,10 @@ func (r *repository) insertTimeEntries(ctx context.Context, project *entity.Proj
 		// debitCount := rand.Intn(utils.MinMax(1, 10, 5)) + 1  // up to 10 debits, default 5
 		// creditCount := rand.Intn(utils.MinMax(1, 10, 5)) + 1 // up to 10 credits, default 5
 
-		if err := createEntries("debit", consultant, 5); err != nil {
+		if err := createEntries(r.types.Debit, consultant, 5); err != nil {
 			return err
 		}
-		if err := createEntries("credit", consultant, 5); err != nil {
+		if err := createEntries(r.types.Credit, consultant, 5); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// Allocation table, the planned share of a consultant's week on a project assignment.
+// Exactly one of Percent (of the consultant's capacity) and HoursPerWeek is set.
+type Allocation struct {
+	ID           int       `json:"ID"`
+	ProjectID    int       `json:"projectID"`    // FK → projects
+	ConsultantID int       `json:"consultantID"` // FK → consultants
+	Percent      *float32  `json:"percent"`
+	HoursPerWeek *float32  `json:"hoursPerWeek"`
+	StartDate    time.Time `json:"startDate"`
+	EndDate      time.Time `json:"endDate"` // inclusive
+}
+
+// AllocationConflict is a week in which a consultant is allocated above 100% of capacity on some day
+type AllocationConflict struct {
+	ConsultantID int       `json:"consultantID"`
+	WeekStart    time.Time `json:"weekStart"`
+	PeakPercent  float64   `json:"peakPercent"`
+}
+
+// AllocationForecast compares planned allocation with actually logged credit hours for one
+// consultant, project and week
+type AllocationForecast struct {
+	ConsultantID  int       `json:"consultantID"`
+	ProjectID     int       `json:"projectID"`
+	WeekStart     time.Time `json:"weekStart"`
+	PlannedHours  float64   `json:"plannedHours"`
+	ActualHours   float64   `json:"actualHours"`
+	VarianceHours float64   `json:"varianceHours"` // actual - planned
+}
+
+// AllocationFilter narrows conflict and forecast reports, zero IDs match everything
+type AllocationFilter struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	ProjectID    int       `json:"projectID"`
+	ConsultantID int       `json:"consultantID"`
+}
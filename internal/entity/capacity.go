@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+// ConsultantCapacity table, the contracted working time of a consultant
+type ConsultantCapacity struct {
+	ConsultantID    int     `json:"consultantID"`    // FK → consultants
+	WeeklyHours     float32 `json:"weeklyHours"`     // full-time hours per week
+	PartTimePercent float32 `json:"partTimePercent"` // share of WeeklyHours actually worked
+}
+
+// Holiday table, a day on which nobody is expected to work
+type Holiday struct {
+	ID          int       `json:"ID"`
+	HolidayDate time.Time `json:"holidayDate"`
+	Name        string    `json:"name"`
+}
+
+// UtilizationPeriod is the logged credit hours over available hours of a consultant for one week or month
+type UtilizationPeriod struct {
+	ConsultantID   int       `json:"consultantID"`
+	PeriodStart    time.Time `json:"periodStart"`
+	AvailableHours float64   `json:"availableHours"`
+	LoggedHours    float64   `json:"loggedHours"`
+	Utilization    float64   `json:"utilization"` // LoggedHours ÷ AvailableHours, 0 when nothing is available
+}
+
+// ConsultantUtilization is the utilization of one consultant over a window
+type ConsultantUtilization struct {
+	Consultant     Consultant          `json:"consultant"`
+	AvailableHours float64             `json:"availableHours"`
+	LoggedHours    float64             `json:"loggedHours"`
+	Utilization    float64             `json:"utilization"`
+	Periods        []UtilizationPeriod `json:"periods"`
+}
+
+// UtilizationReport is the utilization of the team over a window
+type UtilizationReport struct {
+	From        time.Time               `json:"from"`
+	To          time.Time               `json:"to"`
+	Period      string                  `json:"period"` // week or month
+	Consultants []ConsultantUtilization `json:"consultants"`
+}
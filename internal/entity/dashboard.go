@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // The defined dashboard metrics type
 type MetricsDashboard struct {
 	Projects               int     `json:"projects"`
@@ -11,4 +13,80 @@ type MetricsDashboard struct {
 	TotalCredit            float64 `json:"totalCredit"`
 	AverageCreditOverDebit float32 `json:"avgCreditOverDebit"`
 	EndingSoon             int     `json:"endingSoonCount"`
+	Other                  int     `json:"other"` // projects whose latest status maps to no bucket
+	OverdueMilestones      int     `json:"overdueMilestones"`
+	UpcomingMilestones     int     `json:"upcomingMilestones"`
+}
+
+// DashboardFilter scopes the dashboard metrics, zero values mean unfiltered
+type DashboardFilter struct {
+	ManagerID    int       `json:"managerID"`    // projects managed by this consultant
+	Tag          string    `json:"tag"`          // projects carrying this tag
+	ConsultantID int       `json:"consultantID"` // projects the consultant is assigned to or logged time on, and only their entries
+	From         time.Time `json:"from"`         // time entries on or after
+	To           time.Time `json:"to"`           // time entries on or before
+}
+
+// IsZero reports whether the filter scopes nothing
+func (f DashboardFilter) IsZero() bool {
+	return f == DashboardFilter{}
+}
+
+// SeriesPoint holds the dashboard metrics of one week or month
+type SeriesPoint struct {
+	PeriodStart       time.Time `json:"periodStart"`
+	TotalDebit        float64   `json:"totalDebit"`
+	TotalCredit       float64   `json:"totalCredit"`
+	CreditOverDebit   *float64  `json:"creditOverDebit"`   // nil when nothing was debited in the period
+	ActiveProjects    int       `json:"activeProjects"`    // projects whose latest status at period end is active
+	CompletedProjects int       `json:"completedProjects"` // projects that changed status to completed in the period
+}
+
+// MetricsSeries is the dashboard trend over a window at a weekly or monthly granularity
+type MetricsSeries struct {
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Granularity string        `json:"granularity"` // week or month
+	Points      []SeriesPoint `json:"points"`
+}
+
+// DashboardConfig holds the thresholds and vocabulary the dashboard metrics are computed with
+type DashboardConfig struct {
+	IdleThreshold    time.Duration     `json:"idleThreshold"`    // age of the latest status after which a project is idle
+	EndingSoonWindow time.Duration     `json:"endingSoonWindow"` // horizon of ending soon projects and upcoming milestones
+	StatusBuckets    map[string]string `json:"statusBuckets"`    // status title -> active, completed, idle or other
+	DebitType        string            `json:"debitType"`        // time entry type counted as debit
+	CreditType       string            `json:"creditType"`       // time entry type counted as credit
+}
+
+// Leaderboard sorts and limits a manager or consultant breakdown
+type Leaderboard struct {
+	Sort       string `json:"sort"`
+	Descending bool   `json:"descending"`
+	Limit      int    `json:"limit"`
+}
+
+// ManagerMetrics is the breakdown of the projects managed by one consultant
+type ManagerMetrics struct {
+	ManagerID   int     `json:"managerID"`
+	FirstName   string  `json:"firstName"`
+	LastName    string  `json:"lastName"`
+	Projects    int     `json:"projects"`
+	Active      int     `json:"active"`
+	Completed   int     `json:"completed"`
+	OutOfBudget int     `json:"outOfBudget"`
+	TotalDebit  float64 `json:"totalDebit"`
+	TotalCredit float64 `json:"totalCredit"`
+}
+
+// ConsultantMetrics is the breakdown of the hours logged by one consultant
+type ConsultantMetrics struct {
+	ConsultantID int     `json:"consultantID"`
+	FirstName    string  `json:"firstName"`
+	LastName     string  `json:"lastName"`
+	Projects     int     `json:"projects"`
+	TotalHours   float64 `json:"totalHours"`
+	TotalDebit   float64 `json:"totalDebit"`
+	TotalCredit  float64 `json:"totalCredit"`
+	CreditShare  float64 `json:"creditShare"` // credit / (debit + credit), 0 without either
 }
@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProjectEvent is a change to a project pushed to subscribed clients
+type ProjectEvent struct {
+	Type       string          `json:"type"` // e.g. time_entry.created, status.added
+	ProjectID  int             `json:"projectID"`
+	Data       json.RawMessage `json:"data"` // the created or changed record
+	OccurredAt time.Time       `json:"occurredAt"`
+}
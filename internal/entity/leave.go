@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Leave table, an absence request of a consultant over an inclusive date range
+type Leave struct {
+	ID           int        `json:"ID"`
+	ConsultantID int        `json:"consultantID"` // FK → consultants
+	Type         string     `json:"type"`         // vacation, sick or public-holiday
+	StartDate    time.Time  `json:"startDate"`
+	EndDate      time.Time  `json:"endDate"`    // inclusive
+	Status       string     `json:"status"`     // pending, approved or rejected
+	ApproverID   *int       `json:"approverID"` // FK → consultants, set once decided
+	Note         string     `json:"note"`
+	DateCreated  time.Time  `json:"dateCreated"`
+	DateDecided  *time.Time `json:"dateDecided"`
+}
+
+// AvailabilityDay is the number of hours a consultant can work on one day
+type AvailabilityDay struct {
+	ConsultantID   int       `json:"consultantID"`
+	Day            time.Time `json:"day"`
+	AvailableHours float64   `json:"availableHours"`
+	Reason         string    `json:"reason"` // weekend, holiday or leave when unavailable, empty otherwise
+}
@@ -2,8 +2,9 @@ package entity
 
 // ProjectMeta struct encapsulates the necessary meta data of a poject
 type ProjectMeta struct {
-	Manager       Consultant      `json:"manager"`
+	Manager       *Consultant     `json:"manager,omitempty"` // nil for projects without a manager
 	TimeEntries   []TimeEntry     `json:"timeEntries"`
 	StatusHistory []ProjectStatus `json:"statusHistory"`
 	Consultants   []Consultant    `json:"consultants"`
+	Milestones    []Milestone     `json:"milestones"`
 }
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// Milestone table, a named deliverable of a project
+type Milestone struct {
+	ID            int        `json:"ID"`
+	ProjectID     int        `json:"projectID"` // FK → projects
+	OwnerID       *int       `json:"ownerID"`   // FK → consultants, optional
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	DueDate       time.Time  `json:"dueDate"`
+	Completed     bool       `json:"completed"`
+	DateCompleted *time.Time `json:"dateCompleted"` // set when completed
+}
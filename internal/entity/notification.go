@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NotificationPreference is how a consultant receives one notification event type
+type NotificationPreference struct {
+	ConsultantID int    `json:"consultantID"`
+	EventType    string `json:"eventType"` // e.g. project.over_budget
+	Mode         string `json:"mode"`      // immediate, digest or off
+}
+
+// Notification is one occurrence of an event sent, or to be sent, to one consultant
+type Notification struct {
+	ID           int             `json:"id"`
+	ConsultantID int             `json:"consultantID"`
+	EventType    string          `json:"eventType"`
+	ProjectID    *int            `json:"projectID"`
+	Data         json.RawMessage `json:"data"` // the template data of the event
+	Mode         string          `json:"mode"`
+	Status       string          `json:"status"` // pending, sent, failed or skipped
+	Attempts     int             `json:"attempts"`
+	LastError    string          `json:"lastError"`
+	DateCreated  time.Time       `json:"dateCreated"`
+	DateSent     *time.Time      `json:"dateSent"`
+}
@@ -11,7 +11,7 @@ type Project struct {
 	EndDate            time.Time `json:"endDate"`
 	Number             string    `json:"number"`
 	Name               string    `json:"name"`
-	ManagerID          int       `json:"managerID"` // FK → consultants
+	ManagerID          *int      `json:"managerID"` // FK → consultants, optional
 	Description        string    `json:"description"`
 }
 
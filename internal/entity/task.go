@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+// Task table, a unit of work breakdown under a project
+type Task struct {
+	ID            int     `json:"ID"`
+	ProjectID     int     `json:"projectID"`  // FK → projects
+	AssigneeID    *int    `json:"assigneeID"` // FK → consultants, optional
+	Title         string  `json:"title"`
+	Description   string  `json:"description"`
+	EstimateHours float32 `json:"estimateHours"`
+	Completed     bool    `json:"completed"`
+}
+
+// TaskDependency is a finish-to-start link, TaskID cannot start before DependsOnID finishes
+type TaskDependency struct {
+	ID          int `json:"ID"`
+	TaskID      int `json:"taskID"`      // FK → project_tasks
+	DependsOnID int `json:"dependsOnID"` // FK → project_tasks
+}
+
+// TaskProgress is a task with actual hours rolled up from its time entries
+type TaskProgress struct {
+	Task
+	ActualHours float32 `json:"actualHours"`
+	DependsOn   []int   `json:"dependsOn" db:"-"`
+}
+
+// TaskSchedule is the earliest and latest placement of a task on the critical path computation,
+// offsets are in working hours from the forecast start
+type TaskSchedule struct {
+	TaskID         int     `json:"taskID"`
+	RemainingHours float32 `json:"remainingHours"`
+	EarliestStart  float32 `json:"earliestStart"`
+	EarliestFinish float32 `json:"earliestFinish"`
+	LatestStart    float32 `json:"latestStart"`
+	LatestFinish   float32 `json:"latestFinish"`
+	Slack          float32 `json:"slack"`
+	Critical       bool    `json:"critical"`
+}
+
+// ProjectForecast compares the critical path forecast end date to the projected end date
+type ProjectForecast struct {
+	ProjectID        int            `json:"projectID"`
+	ForecastStart    time.Time      `json:"forecastStart"`
+	ForecastEndDate  time.Time      `json:"forecastEndDate"`
+	ProjectedEndDate time.Time      `json:"projectedEndDate"`
+	VarianceDays     int            `json:"varianceDays"` // positive when forecast is later than projected
+	RemainingHours   float32        `json:"remainingHours"`
+	CriticalPath     []int          `json:"criticalPath"`
+	Schedule         []TaskSchedule `json:"schedule"`
+}
@@ -9,7 +9,9 @@ type TimeEntry struct {
 	Title        string    `json:"title"`
 	ConsultantID int       `json:"consultantID"` // FK → consultants
 	Description  string    `json:"description"`
-	ProjectID    int       `json:"projectID"` // FK → projects
-	Type         string    `json:"type"`      // Debit or Credit
-	EntryDate    time.Time `json:"entryDate"` // when it was logged
+	ProjectID    int       `json:"projectID"`   // FK → projects
+	Type         string    `json:"type"`        // configured debit, credit or leave (non-billable) type
+	EntryDate    time.Time `json:"entryDate"`   // when it was logged
+	MilestoneID  *int      `json:"milestoneID"` // FK → project_milestones, optional
+	TaskID       *int      `json:"taskID"`      // FK → project_tasks, optional
 }
@@ -0,0 +1,42 @@
+package entity
+
+import "time"
+
+// TimelineFilter narrows the portfolio timeline to a date window, a manager and/or a tag
+type TimelineFilter struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	ManagerID int       `json:"managerID"` // 0 means any manager
+	Tag       string    `json:"tag"`       // empty means any tag
+}
+
+// StatusChange is a point in time where the status title of a project changed
+type StatusChange struct {
+	ID            int       `json:"id"`
+	ProjectID     int       `json:"projectID"`
+	Title         string    `json:"title"`
+	PreviousTitle *string   `json:"previousTitle"` // nil for the first status of a project
+	DateCreated   time.Time `json:"dateCreated"`
+}
+
+// TimelineProject is one row of the portfolio timeline, projected vs actual dates of a project
+// with its status change points and milestones inside the window
+type TimelineProject struct {
+	ProjectID          int            `json:"projectID"`
+	Number             string         `json:"number"`
+	Name               string         `json:"name"`
+	ManagerID          *int           `json:"managerID"`
+	ProjectedStartDate time.Time      `json:"projectedStartDate"`
+	ProjectedEndDate   time.Time      `json:"projectedEndDate"`
+	StartDate          time.Time      `json:"startDate"`
+	EndDate            time.Time      `json:"endDate"`
+	StatusChanges      []StatusChange `json:"statusChanges"`
+	Milestones         []Milestone    `json:"milestones"`
+}
+
+// Timeline is the portfolio timeline for a date window
+type Timeline struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Projects []TimelineProject `json:"projects"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookSubscription is an outgoing webhook target
+type WebhookSubscription struct {
+	ID          int            `json:"id"`
+	URL         string         `json:"url"`
+	EventTypes  pq.StringArray `json:"eventTypes"`       // empty means every event type
+	Secret      string         `json:"secret,omitempty"` // HMAC key, only returned on create
+	Active      bool           `json:"active"`
+	DateCreated time.Time      `json:"dateCreated"`
+}
+
+// WebhookDelivery is the delivery of one outbox event to one subscription
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	SubscriptionID int        `json:"subscriptionID"`
+	OutboxID       int        `json:"outboxID"`
+	EventType      string     `json:"eventType"`
+	Status         string     `json:"status"` // pending, delivered or dead
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastStatusCode *int       `json:"lastStatusCode"`
+	LastError      string     `json:"lastError"`
+	DateCreated    time.Time  `json:"dateCreated"`
+	DateDelivered  *time.Time `json:"dateDelivered"`
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/auth"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+var (
+	eventsLogger = logger.New().Prefix("Events Router")
+)
+
+const (
+	// writeTimeout disconnects clients that stop reading
+	writeTimeout = 10 * time.Second
+	// pingInterval keeps idle connections open through proxies
+	pingInterval = 30 * time.Second
+)
+
+// Handler serves the project events WebSocket of this instance, events published by any instance
+// reach its clients through Run
+type Handler struct {
+	hub *hub
+	// accept authorizes the origins allowed by the CORS policy of the route group
+	accept *websocket.AcceptOptions
+	tokens auth.Tokens
+	cache  *cache.Cache
+}
+
+// NewHandler returns a handler without clients, accepting WebSockets of the tokens from the allowed
+// origins of cors, events of every instance are received through the pub/sub of c
+func NewHandler(cors config.CORSPolicy, tokens auth.Tokens, c *cache.Cache) *Handler {
+	return &Handler{hub: newHub(), accept: acceptOptions(cors), tokens: tokens, cache: c}
+}
+
+// Builds the WebSocket accept options of a CORS policy. Origins such as https://app.example.com and
+// https://*.example.com are matched against the scheme and host of the request origin, "*" allows any
+// origin. The origin of the request host is always allowed.
+func acceptOptions(cors config.CORSPolicy) *websocket.AcceptOptions {
+	options := &websocket.AcceptOptions{}
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			options.InsecureSkipVerify = true
+			continue
+		}
+		options.OriginPatterns = append(options.OriginPatterns, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+	return options
+}
+
+// Routes registers the events routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.ProjectEvents)
+}
+
+// CloseConnections disconnects every WebSocket client of this instance so it reconnects to another
+// one, WebSocket connections are hijacked and not drained by the http server shutdown
+func (h *Handler) CloseConnections() {
+	h.hub.close()
+}
+
+// command is a message from the client changing its subscriptions
+type command struct {
+	Action     string `json:"action"` // subscribe or unsubscribe
+	ProjectIDS []int  `json:"projectIDs"`
+}
+
+// Gets the project IDs of the comma separated projectIDs query param
+func getProjectIDSFromRequest(r *http.Request) ([]int, bool) {
+	ids := []int{}
+	v := r.URL.Query().Get("projectIDs")
+	if v == "" {
+		return ids, true
+	}
+	for _, s := range strings.Split(v, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// ProjectEvents upgrades to a WebSocket streaming the events of the subscribed projects. Clients
+// authenticate with a bearer token or the token query param, subscribe with the projectIDs query
+// param and change subscriptions by sending {"action": "subscribe"|"unsubscribe", "projectIDs": [...]}.
+func (h *Handler) ProjectEvents(w http.ResponseWriter, r *http.Request) {
+	if !h.tokens.Valid(auth.TokenFromRequest(r)) {
+		problem.Write(w, r, apperror.Unauthorized("a valid bearer token or token query param is required"))
+		return
+	}
+	projectIDS, ok := getProjectIDSFromRequest(r)
+	if !ok {
+		problem.Write(w, r, apperror.Invalid("projectIDs", "invalid projectIDs"))
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, h.accept)
+	if err != nil {
+		eventsLogger.Error(err.Error())
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := newClient()
+	defer h.hub.remove(c)
+	if !h.hub.subscribe(c, projectIDS) {
+		conn.Close(websocket.StatusPolicyViolation, "too many subscriptions")
+		return
+	}
+
+	// read subscription changes until the client goes away
+	go func() {
+		defer cancel()
+		for {
+			var cmd command
+			if err := wsjson.Read(ctx, conn, &cmd); err != nil {
+				return
+			}
+			switch cmd.Action {
+			case "subscribe":
+				if !h.hub.subscribe(c, cmd.ProjectIDS) {
+					conn.Close(websocket.StatusPolicyViolation, "too many subscriptions")
+					return
+				}
+			case "unsubscribe":
+				h.hub.unsubscribe(c, cmd.ProjectIDS)
+			default:
+				conn.Close(websocket.StatusUnsupportedData, "unknown action")
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.slow:
+			conn.Close(websocket.StatusTryAgainLater, "client too slow")
+			return
+		case <-h.hub.closing:
+			conn.Close(websocket.StatusGoingAway, "server shutting down")
+			return
+		case event := <-c.send:
+			writeCtx, done := context.WithTimeout(ctx, writeTimeout)
+			err := wsjson.Write(writeCtx, conn, event)
+			done()
+			if err != nil {
+				return
+			}
+		case <-ping.C:
+			pingCtx, done := context.WithTimeout(ctx, writeTimeout)
+			err := conn.Ping(pingCtx)
+			done()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
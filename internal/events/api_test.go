@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/renniemaharaj/project-list-go/internal/auth"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+func TestGetProjectIDSFromRequest(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+		ok    bool
+	}{
+		{"", []int{}, true},
+		{"projectIDs=1", []int{1}, true},
+		{"projectIDs=1,%202,3", []int{1, 2, 3}, true},
+		{"projectIDs=1,x", nil, false},
+		{"projectIDs=1,", nil, false},
+	}
+	for _, tt := range tests {
+		ids, ok := getProjectIDSFromRequest(httptest.NewRequest(http.MethodGet, "/events?"+tt.query, nil))
+		if ok != tt.ok || !slices.Equal(ids, tt.want) {
+			t.Errorf("%q: ids = %v, ok = %v, want %v, %v", tt.query, ids, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Waits until a client follows projectID
+func waitSubscribed(t *testing.T, h *hub, projectID int) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		h.mu.Lock()
+		n := len(h.subs[projectID])
+		h.mu.Unlock()
+		if n > 0 {
+			return
+		}
+	}
+	t.Fatalf("no client subscribed to project %d", projectID)
+}
+
+// tokens accepts the token secret
+var tokens = auth.Tokens{"secret"}
+
+func TestProjectEvents(t *testing.T) {
+	h := NewHandler(config.CORSPolicy{}, tokens, nil)
+	s := httptest.NewServer(http.HandlerFunc(h.ProjectEvents))
+	defer s.Close()
+
+	// the token is checked before upgrading
+	res, err := http.Get(s.URL + "?token=other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(s.URL, "http")+"?projectIDs=1&token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	// subscribing over the connection adds projects, events of other projects are not sent
+	if err := wsjson.Write(ctx, conn, command{Action: "subscribe", ProjectIDS: []int{2}}); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribed(t, h.hub, 2)
+	h.hub.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 3})
+	h.hub.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 2})
+
+	var event entity.ProjectEvent
+	if err := wsjson.Read(ctx, conn, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.ProjectID != 2 {
+		t.Fatalf("event = %+v, want an event of project 2", event)
+	}
+
+	// unknown actions close the connection
+	if err := wsjson.Write(ctx, conn, command{Action: "follow"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusUnsupportedData {
+		t.Fatalf("close error = %v, want %v", err, websocket.StatusUnsupportedData)
+	}
+}
+
+func TestCloseConnections(t *testing.T) {
+	h := NewHandler(config.CORSPolicy{}, tokens, nil)
+	s := httptest.NewServer(http.HandlerFunc(h.ProjectEvents))
+	defer s.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(s.URL, "http")+"?projectIDs=1&token=secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+	waitSubscribed(t, h.hub, 1)
+
+	// closing one handler does not disconnect the clients of another
+	NewHandler(config.CORSPolicy{}, tokens, nil).CloseConnections()
+	h.CloseConnections()
+	h.CloseConnections()
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Fatalf("close error = %v, want %v", err, websocket.StatusGoingAway)
+	}
+}
+
+func TestProjectEventsAllowedOrigins(t *testing.T) {
+	h := NewHandler(config.CORSPolicy{AllowedOrigins: []string{"https://*.example.com", "http://localhost:3000/"}}, tokens, nil)
+	s := httptest.NewServer(http.HandlerFunc(h.ProjectEvents))
+	defer s.Close()
+
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{"", true},
+		{"https://app.example.com", true},
+		{"http://localhost:3000", true},
+		{"http://app.example.com", false},
+		{"https://example.com", false},
+		{"https://evil.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, res, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(s.URL, "http")+"?token=secret", &websocket.DialOptions{HTTPHeader: header})
+			if tt.allow {
+				if err != nil {
+					t.Fatalf("origin rejected: %v", err)
+				}
+				conn.CloseNow()
+				return
+			}
+			if err == nil {
+				conn.CloseNow()
+				t.Fatal("origin accepted")
+			}
+			if res == nil || res.StatusCode != http.StatusForbidden {
+				t.Fatalf("dial error = %v, want %d", err, http.StatusForbidden)
+			}
+		})
+	}
+
+	// "*" accepts any origin
+	h = NewHandler(config.CORSPolicy{AllowedOrigins: []string{"*"}}, tokens, nil)
+	if !h.accept.InsecureSkipVerify {
+		t.Fatal(`"*" does not accept any origin`)
+	}
+}
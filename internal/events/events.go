@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// Project event types
+const (
+	TypeTimeEntryCreated   = "time_entry.created"
+	TypeTimeEntryUpdated   = "time_entry.updated"
+	TypeTimeEntryDeleted   = "time_entry.deleted"
+	TypeStatusAdded        = "status.added"
+	TypeConsultantAssigned = "consultant.assigned"
+	TypeTagChanged         = "tag.changed"
+)
+
+// channel is the redis pub/sub channel project events are fanned out on between instances
+const channel = "events:projects"
+
+// TagChange is the data of a tag.changed event
+type TagChange struct {
+	Tag     string `json:"tag"`
+	Removed bool   `json:"removed"`
+}
+
+// Types lists every project event type, for validating event filters
+var Types = []string{
+	TypeTimeEntryCreated, TypeTimeEntryUpdated, TypeTimeEntryDeleted,
+	TypeStatusAdded, TypeConsultantAssigned, TypeTagChanged,
+}
+
+// New builds a project event carrying data as JSON
+func New(eventType string, projectID int, data any) (entity.ProjectEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return entity.ProjectEvent{}, err
+	}
+	return entity.ProjectEvent{
+		Type:       eventType,
+		ProjectID:  projectID,
+		Data:       raw,
+		OccurredAt: time.Now(),
+	}, nil
+}
+
+// Publish sends a project event through the pub/sub of c to the subscribers of the project on every
+// instance. It is called after the mutation committed, a failure only loses the live update and is
+// left to the caller to log.
+func Publish(ctx context.Context, c *cache.Cache, eventType string, projectID int, data any) error {
+	event, err := New(eventType, projectID, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.Publish(ctx, channel, payload)
+}
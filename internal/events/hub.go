@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+const (
+	// clientBuffer is the number of events queued per client before it is disconnected as too slow
+	clientBuffer = 32
+	// maxSubscriptions caps the projects a single client can follow
+	maxSubscriptions = 100
+)
+
+// client is one WebSocket connection and the projects it follows
+type client struct {
+	send     chan entity.ProjectEvent
+	projects map[int]struct{}
+	// slow is closed when the client fell behind and is disconnected
+	slow     chan struct{}
+	slowOnce sync.Once
+}
+
+func newClient() *client {
+	return &client{
+		send:     make(chan entity.ProjectEvent, clientBuffer),
+		projects: map[int]struct{}{},
+		slow:     make(chan struct{}),
+	}
+}
+
+// hub routes the events received from redis to the local clients subscribed to their project
+type hub struct {
+	mu   sync.Mutex
+	subs map[int]map[*client]struct{}
+	// closing is closed when the server shuts down
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+func newHub() *hub {
+	return &hub{subs: map[int]map[*client]struct{}{}, closing: make(chan struct{})}
+}
+
+// close disconnects every client of the hub
+func (h *hub) close() {
+	h.closeOnce.Do(func() { close(h.closing) })
+}
+
+// subscribe adds projects to a client, returning false when the client would exceed maxSubscriptions
+func (h *hub) subscribe(c *client, projectIDS []int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range projectIDS {
+		if _, ok := c.projects[id]; ok {
+			continue
+		}
+		if len(c.projects) >= maxSubscriptions {
+			return false
+		}
+		c.projects[id] = struct{}{}
+		if h.subs[id] == nil {
+			h.subs[id] = map[*client]struct{}{}
+		}
+		h.subs[id][c] = struct{}{}
+	}
+	return true
+}
+
+// unsubscribe removes projects from a client
+func (h *hub) unsubscribe(c *client, projectIDS []int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range projectIDS {
+		delete(c.projects, id)
+		delete(h.subs[id], c)
+		if len(h.subs[id]) == 0 {
+			delete(h.subs, id)
+		}
+	}
+}
+
+// remove drops a client from every project
+func (h *hub) remove(c *client) {
+	ids := []int{}
+	h.mu.Lock()
+	for id := range c.projects {
+		ids = append(ids, id)
+	}
+	h.mu.Unlock()
+	h.unsubscribe(c, ids)
+}
+
+// dispatch queues an event for the clients of its project, clients with a full queue are marked slow
+func (h *hub) dispatch(event entity.ProjectEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := range h.subs[event.ProjectID] {
+		select {
+		case c.send <- event:
+		default:
+			c.slowOnce.Do(func() { close(c.slow) })
+		}
+	}
+}
+
+// Run receives the project events of every instance from redis and dispatches them to the local
+// WebSocket clients until ctx is done. The returned channel is closed once dispatching stopped.
+func (h *Handler) Run(ctx context.Context) (<-chan struct{}, error) {
+	messages, err := h.cache.Subscribe(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for payload := range messages {
+			var event entity.ProjectEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				eventsLogger.WarningF("Ignoring malformed project event: %s", err.Error())
+				continue
+			}
+			h.hub.dispatch(event)
+		}
+	}()
+	return done, nil
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+func TestHubRoutesEventsToSubscribers(t *testing.T) {
+	h := &hub{subs: map[int]map[*client]struct{}{}}
+	a, b := newClient(), newClient()
+	h.subscribe(a, []int{1, 2})
+	h.subscribe(b, []int{2})
+
+	h.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 1})
+	h.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 2})
+	h.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 3})
+	if len(a.send) != 2 || len(b.send) != 1 {
+		t.Fatalf("queued %d and %d events, want 2 and 1", len(a.send), len(b.send))
+	}
+	if event := <-b.send; event.ProjectID != 2 {
+		t.Fatalf("event = %+v, want project 2", event)
+	}
+
+	h.unsubscribe(b, []int{2})
+	h.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 2})
+	if len(b.send) != 0 {
+		t.Fatal("unsubscribed client received an event")
+	}
+
+	// removing the last client of a project forgets the project
+	h.remove(a)
+	if len(h.subs) != 0 || len(a.projects) != 0 {
+		t.Fatalf("subscriptions left after remove: %v", h.subs)
+	}
+}
+
+func TestHubCapsSubscriptions(t *testing.T) {
+	h := &hub{subs: map[int]map[*client]struct{}{}}
+	c := newClient()
+
+	ids := make([]int, maxSubscriptions)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	if !h.subscribe(c, ids) {
+		t.Fatalf("%d subscriptions rejected", maxSubscriptions)
+	}
+	// following a project again is free
+	if !h.subscribe(c, []int{1}) {
+		t.Fatal("repeated subscription rejected")
+	}
+	if h.subscribe(c, []int{maxSubscriptions + 1}) {
+		t.Fatalf("subscription %d accepted", maxSubscriptions+1)
+	}
+}
+
+func TestHubMarksSlowClients(t *testing.T) {
+	h := &hub{subs: map[int]map[*client]struct{}{}}
+	c := newClient()
+	h.subscribe(c, []int{1})
+
+	for range clientBuffer + 2 {
+		h.dispatch(entity.ProjectEvent{Type: TypeStatusAdded, ProjectID: 1})
+	}
+	select {
+	case <-c.slow:
+	default:
+		t.Fatal("client with a full queue not marked slow")
+	}
+	if len(c.send) != clientBuffer {
+		t.Fatalf("queued %d events, want %d", len(c.send), clientBuffer)
+	}
+}
@@ -1,7 +1,161 @@
 package routes
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
-func HealthCheck(w http.ResponseWriter, r *http.Request) {
-	w.Write([]byte("Hello from public route!"))
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+	"github.com/renniemaharaj/project-list-go/internal/schema"
+)
+
+// Server states, only a ready server receives traffic
+const (
+	StateStarting = "starting"
+	StateReady    = "ready"
+	StateStopping = "stopping"
+)
+
+// Dependency statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// checkTimeout bounds every dependency check
+const checkTimeout = 2 * time.Second
+
+var (
+	healthLogger = logger.New().Prefix("Health")
+
+	// state of the server, starting until dependencies are initialized
+	state atomic.Value
+)
+
+func init() {
+	state.Store(StateStarting)
+}
+
+// SetState records the server state reported by /readyz
+func SetState(s string) {
+	state.Store(s)
+}
+
+// DependencyCheck is the outcome of checking one dependency
+type DependencyCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessReport is the body of /readyz
+type ReadinessReport struct {
+	Status string                     `json:"status"` // ready or not_ready
+	State  string                     `json:"state"`  // starting, ready or stopping
+	Checks map[string]DependencyCheck `json:"checks,omitempty"`
+}
+
+// Liveness reports that the process is alive and serving, it checks no dependency
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// RequireStarted answers 503 until the server finished starting, so early requests do not reach
+// uninitialized dependencies
+func RequireStarted(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state.Load().(string) == StateStarting {
+			problem.Write(w, r, apperror.Unavailable("service is starting"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check checks one dependency, failing with the reason it is unusable
+type Check func(ctx context.Context) error
+
+// Handler serves the readiness report of its dependency checks
+type Handler struct {
+	checks map[string]Check
+}
+
+// NewHandler returns a readiness handler running checks, keyed by the dependency they check
+func NewHandler(checks map[string]Check) *Handler {
+	return &Handler{checks}
+}
+
+// Readiness runs the dependency checks, 503 unless the server is ready and every dependency is up.
+// While starting or stopping dependencies are not checked.
+func (h *Handler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := ReadinessReport{Status: "not_ready", State: state.Load().(string)}
+
+	if report.State == StateReady {
+		report.Checks = h.checkDependencies(r.Context())
+		report.Status = "ready"
+		for name, check := range report.Checks {
+			if check.Status != StatusUp {
+				report.Status = "not_ready"
+				healthLogger.WarningF("Readiness check %s failed: %s", name, check.Error)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// checkDependencies runs every dependency check concurrently
+func (h *Handler) checkDependencies(ctx context.Context) map[string]DependencyCheck {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]DependencyCheck, len(h.checks))
+	)
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			result := DependencyCheck{Status: StatusUp, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// SchemaCheck returns a check failing when the database of repo is behind the schema version of this build
+func SchemaCheck(repo schema.Repository) Check {
+	return func(ctx context.Context) error {
+		version, err := repo.GetVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version < schema.Version {
+			return fmt.Errorf("schema version %d, expected at least %d", version, schema.Version)
+		}
+		return nil
+	}
 }
@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/renniemaharaj/project-list-go/internal/schema"
+)
+
+func TestLiveness(t *testing.T) {
+	w := httptest.NewRecorder()
+	Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequireStarted(t *testing.T) {
+	t.Cleanup(func() { SetState(StateStarting) })
+	handler := RequireStarted(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for state, want := range map[string]int{
+		StateStarting: http.StatusServiceUnavailable,
+		StateReady:    http.StatusOK,
+		StateStopping: http.StatusOK, // in-flight and late requests are still served while draining
+	} {
+		SetState(state)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/project", nil))
+		if w.Code != want {
+			t.Errorf("%s: status = %d, want %d", state, w.Code, want)
+		}
+	}
+}
+
+func TestReadinessWhileNotReady(t *testing.T) {
+	t.Cleanup(func() { SetState(StateStarting) })
+
+	// dependencies are not checked while starting or stopping
+	for _, state := range []string{StateStarting, StateStopping} {
+		SetState(state)
+		w := httptest.NewRecorder()
+		NewHandler(map[string]Check{"postgres": func(ctx context.Context) error {
+			t.Fatal("dependency checked while not ready")
+			return nil
+		}}).Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var report ReadinessReport
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusServiceUnavailable || report.Status != "not_ready" || report.State != state || report.Checks != nil {
+			t.Errorf("%s: status = %d, report = %+v", state, w.Code, report)
+		}
+	}
+}
+
+func TestReadinessChecksDependencies(t *testing.T) {
+	SetState(StateReady)
+	t.Cleanup(func() { SetState(StateStarting) })
+
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	// checks are bounded by checkTimeout
+	hanging := func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			return errors.New("no deadline")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		checks     map[string]Check
+		wantStatus string
+		wantCode   int
+	}{
+		{map[string]Check{"postgres": up, "redis": hanging}, "ready", http.StatusOK},
+		{map[string]Check{"postgres": up, "redis": down}, "not_ready", http.StatusServiceUnavailable},
+		{map[string]Check{}, "ready", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		NewHandler(tt.checks).Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var report ReadinessReport
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tt.wantCode || report.Status != tt.wantStatus || report.State != StateReady || len(report.Checks) != len(tt.checks) {
+			t.Fatalf("status = %d, report = %+v, want %s", w.Code, report, tt.wantStatus)
+		}
+		for name, check := range report.Checks {
+			if (check.Status == StatusUp) != (check.Error == "") {
+				t.Errorf("%s: check = %+v", name, check)
+			}
+		}
+	}
+}
+
+// schemaRepository reports a fixed schema version
+type schemaRepository struct {
+	schema.Repository
+	version int
+	err     error
+}
+
+func (s schemaRepository) GetVersion(ctx context.Context) (int, error) {
+	return s.version, s.err
+}
+
+func TestSchemaCheck(t *testing.T) {
+	for _, tt := range []struct {
+		repo    schemaRepository
+		wantErr bool
+	}{
+		{schemaRepository{version: schema.Version}, false},
+		{schemaRepository{version: schema.Version + 1}, false}, // a newer schema during a rolling deploy
+		{schemaRepository{version: schema.Version - 1}, true},
+		{schemaRepository{err: errors.New("relation does not exist")}, true},
+	} {
+		if err := SchemaCheck(tt.repo)(context.Background()); tt.wantErr != (err != nil) {
+			t.Errorf("version %d: err = %v", tt.repo.version, err)
+		}
+	}
+}
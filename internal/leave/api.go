@@ -0,0 +1,231 @@
+package leave
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+const (
+	// dateLayout is the layout of the from and to query parameters
+	dateLayout = "2006-01-02"
+	// maxWindow caps leave and availability windows
+	maxWindow = 2 * 366 * 24 * time.Hour
+	// maxCalendarSize caps the size of an imported iCalendar file
+	maxCalendarSize = 1 << 20
+)
+
+// Handler serves the leave routes through its service
+type Handler struct {
+	service Service
+}
+
+// NewHandler returns a handler serving the leave routes through service
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Routes registers the leave routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Post("/", h.RequestLeave)
+	r.Get("/pending", h.GetPendingLeaves)
+	r.Post("/holidays/import", h.ImportHolidayCalendar)
+	r.Get("/consultant/{consultantID}", h.GetLeavesByConsultantID)
+	r.Get("/availability/{consultantID}", h.GetAvailabilityByConsultantID)
+	r.Get("/{leaveID}", h.GetLeaveByID)
+	r.Put("/{leaveID}/approve", h.ApproveLeave)
+	r.Put("/{leaveID}/reject", h.RejectLeave)
+	r.Delete("/{leaveID}", h.DeleteLeave)
+}
+
+// Gets the from and to query parameters, the window defaults to the next 12 weeks
+func getWindowFromRequest(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	from := time.Now().Truncate(24 * time.Hour)
+	to := from.AddDate(0, 0, 12*7)
+
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("from", "invalid from, expected "+dateLayout))
+			return from, to, err
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			problem.Write(w, r, apperror.Invalid("to", "invalid to, expected "+dateLayout))
+			return from, to, err
+		}
+	}
+	if to.Before(from) || to.Sub(from) > maxWindow {
+		problem.Write(w, r, apperror.Invalid("to", "to must not be before from and the window must not exceed 2 years"))
+		return from, to, fmt.Errorf("invalid window")
+	}
+	return from, to, nil
+}
+
+// Loads the leave in the url
+func (h *Handler) getLeave(w http.ResponseWriter, r *http.Request) (*Leave, bool) {
+	leaveID, err := problem.IntParam(w, r, "leaveID")
+	if err != nil {
+		return nil, false
+	}
+
+	l, err := h.service.GetLeaveByID(r.Context(), leaveID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return l, true
+}
+
+// RequestLeave inserts a pending leave request from the request body
+func (h *Handler) RequestLeave(w http.ResponseWriter, r *http.Request) {
+	var l Leave
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid leave body"))
+		return
+	}
+	l.ID = 0
+
+	if err := h.service.RequestLeave(r.Context(), &l); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// GetLeaveByID returns a single leave
+func (h *Handler) GetLeaveByID(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.getLeave(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// GetPendingLeaves returns every leave request awaiting approval
+func (h *Handler) GetPendingLeaves(w http.ResponseWriter, r *http.Request) {
+	leaves, err := h.service.GetPendingLeaves(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(leaves)
+}
+
+// GetLeavesByConsultantID returns the leave of a consultant overlapping the from and to window
+func (h *Handler) GetLeavesByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+	from, to, err := getWindowFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	leaves, err := h.service.GetLeavesByConsultantID(r.Context(), consultantID, from, to)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(leaves)
+}
+
+// GetAvailabilityByConsultantID returns the available hours of a consultant per day of the window
+func (h *Handler) GetAvailabilityByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+	from, to, err := getWindowFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	days, err := h.service.GetAvailabilityByConsultantID(r.Context(), consultantID, from, to)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(days)
+}
+
+// decideLeave approves or rejects the leave in the url on behalf of the approver in the body
+func (h *Handler) decideLeave(w http.ResponseWriter, r *http.Request, approve bool) {
+	leaveID, err := problem.IntParam(w, r, "leaveID")
+	if err != nil {
+		return
+	}
+
+	var body struct {
+		ApproverID int `json:"approverID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ApproverID == 0 {
+		problem.Write(w, r, apperror.Invalid("approverID", "approverID is required"))
+		return
+	}
+
+	l, err := h.service.DecideLeave(r.Context(), leaveID, body.ApproverID, approve)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// ApproveLeave approves a pending leave request, only managers and administrators may approve
+func (h *Handler) ApproveLeave(w http.ResponseWriter, r *http.Request) {
+	h.decideLeave(w, r, true)
+}
+
+// RejectLeave rejects a pending leave request, only managers and administrators may reject
+func (h *Handler) RejectLeave(w http.ResponseWriter, r *http.Request) {
+	h.decideLeave(w, r, false)
+}
+
+// DeleteLeave removes a leave
+func (h *Handler) DeleteLeave(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.getLeave(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteLeaveByID(r.Context(), l.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ImportHolidayCalendar upserts the holidays of an iCalendar (.ics) request body
+func (h *Handler) ImportHolidayCalendar(w http.ResponseWriter, r *http.Request) {
+	imported, err := h.service.ImportHolidayCalendar(r.Context(), http.MaxBytesReader(w, r.Body, maxCalendarSize))
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]int{"imported": imported})
+}
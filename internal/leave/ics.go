@@ -0,0 +1,113 @@
+package leave
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// parseICS reads VEVENT entries of an iCalendar (.ics) stream into one holiday per day.
+// All-day events (DTSTART;VALUE=DATE) and date-time events are supported; DTEND is exclusive
+// as defined by RFC 5545, a date-time DTEND past midnight still covers its day, and a missing
+// DTEND means a single day.
+func parseICS(r io.Reader) ([]entity.Holiday, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := []entity.Holiday{}
+	var (
+		inEvent    bool
+		summary    string
+		start, end time.Time
+	)
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, summary, start, end = true, "", time.Time{}, time.Time{}
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q has no DTSTART", summary)
+			}
+			if end.IsZero() || !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+				holidays = append(holidays, entity.Holiday{HolidayDate: day, Name: summary})
+			}
+		case !inEvent:
+			continue
+		case name == "SUMMARY":
+			summary = unescapeICSText(value)
+		case name == "DTSTART":
+			if start, err = parseICSDate(params, value); err != nil {
+				return nil, err
+			}
+			start = startOfDay(start)
+		case name == "DTEND":
+			if end, err = parseICSDate(params, value); err != nil {
+				return nil, err
+			}
+			// an end past midnight, e.g. 17:00 on the last day, ends after that day
+			if day := startOfDay(end); !day.Equal(end) {
+				end = day.AddDate(0, 0, 1)
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// unfoldICS joins folded content lines, continuation lines start with a space or a tab
+func unfoldICS(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits `NAME;PARAM=VALUE:value` into its upper-cased name, params and value
+func splitICSLine(line string) (string, string, string) {
+	head, value, found := strings.Cut(line, ":")
+	if !found {
+		return "", "", ""
+	}
+	name, params, _ := strings.Cut(head, ";")
+	return strings.ToUpper(name), strings.ToUpper(params), value
+}
+
+// parseICSDate parses DATE (20261225) and DATE-TIME (20261225T000000[Z]) values, the time of day
+// is kept in the zone of the calendar
+func parseICSDate(params, value string) (time.Time, error) {
+	if strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME") || len(value) == 8 {
+		return time.Parse("20060102", value)
+	}
+	t, err := time.Parse("20060102T150405", strings.TrimSuffix(value, "Z"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ics date %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// startOfDay returns the calendar day of t
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// unescapeICSText reverses TEXT escaping of RFC 5545
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,123 @@
+package leave
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps events into a VCALENDAR with CRLF line endings
+func calendar(events ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0",
+		// timezone definitions carry DTSTARTs of their own which are not holidays
+		"BEGIN:VTIMEZONE", "TZID:Europe/Berlin", "BEGIN:STANDARD", "DTSTART:19701025T030000", "END:STANDARD", "END:VTIMEZONE",
+	}
+	for _, e := range events {
+		lines = append(lines, "BEGIN:VEVENT", e, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestParseICS(t *testing.T) {
+	tests := []struct {
+		name  string
+		ics   string
+		want  []string // date and name of every holiday
+		error bool
+	}{
+		{
+			name: "all-day event with exclusive DTEND",
+			ics:  calendar("SUMMARY:Christmas Day\r\nDTSTART;VALUE=DATE:20261225\r\nDTEND;VALUE=DATE:20261226"),
+			want: []string{"2026-12-25 Christmas Day"},
+		},
+		{
+			name: "multi-day event",
+			ics:  calendar("SUMMARY:Office closed\r\nDTSTART;VALUE=DATE:20261224\r\nDTEND;VALUE=DATE:20261227"),
+			want: []string{"2026-12-24 Office closed", "2026-12-25 Office closed", "2026-12-26 Office closed"},
+		},
+		{
+			name: "missing DTEND is a single day",
+			ics:  calendar("SUMMARY:New Year\r\nDTSTART;VALUE=DATE:20270101"),
+			want: []string{"2027-01-01 New Year"},
+		},
+		{
+			name: "DATE value without VALUE param",
+			ics:  calendar("SUMMARY:New Year\r\nDTSTART:20270101"),
+			want: []string{"2027-01-01 New Year"},
+		},
+		{
+			name: "DATE-TIME in UTC",
+			ics:  calendar("SUMMARY:Half day\r\nDTSTART:20261231T090000Z\r\nDTEND:20261231T130000Z"),
+			want: []string{"2026-12-31 Half day"},
+		},
+		{
+			name: "DATE-TIME ending past midnight of a later day",
+			ics:  calendar("SUMMARY:Conference\r\nDTSTART:20261005T090000Z\r\nDTEND:20261007T170000Z"),
+			want: []string{"2026-10-05 Conference", "2026-10-06 Conference", "2026-10-07 Conference"},
+		},
+		{
+			name: "DATE-TIME with TZID",
+			ics:  calendar("SUMMARY:Reunification Day\r\nDTSTART;TZID=Europe/Berlin:20261003T000000\r\nDTEND;TZID=Europe/Berlin:20261004T000000"),
+			want: []string{"2026-10-03 Reunification Day"},
+		},
+		{
+			name: "folded and escaped SUMMARY",
+			ics:  calendar("SUMMARY:Christmas Eve\\, afternoon\r\n  only\r\nDTSTART;VALUE=DATE:20261224"),
+			want: []string{"2026-12-24 Christmas Eve, afternoon only"},
+		},
+		{
+			name: "several events",
+			ics: calendar(
+				"SUMMARY:Boxing Day\r\nDTSTART;VALUE=DATE:20261226",
+				"DTSTART;VALUE=DATE:20261225\r\nSUMMARY:Christmas Day",
+			),
+			want: []string{"2026-12-26 Boxing Day", "2026-12-25 Christmas Day"},
+		},
+		{
+			name: "LF line endings",
+			ics:  "BEGIN:VEVENT\nSUMMARY:Labour Day\nDTSTART;VALUE=DATE:20270501\nEND:VEVENT\n",
+			want: []string{"2027-05-01 Labour Day"},
+		},
+		{
+			name:  "missing DTSTART",
+			ics:   calendar("SUMMARY:Someday"),
+			error: true,
+		},
+		{
+			name:  "invalid date",
+			ics:   calendar("SUMMARY:Someday\r\nDTSTART:2026-12-25T00:00"),
+			error: true,
+		},
+		{
+			name: "no events",
+			ics:  calendar(),
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holidays, err := parseICS(strings.NewReader(tt.ics))
+			if tt.error {
+				if err == nil {
+					t.Fatalf("holidays = %+v, want an error", holidays)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, h := range holidays {
+				if h.HolidayDate.Location() != time.UTC || h.HolidayDate.Hour() != 0 {
+					t.Errorf("holiday date %s is not a UTC calendar day", h.HolidayDate)
+				}
+				got = append(got, h.HolidayDate.Format(time.DateOnly)+" "+h.Name)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("holidays = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package leave
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/capacity"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+// ErrLeaveAlreadyDecided is returned when approving or rejecting leave that is no longer pending
+var ErrLeaveAlreadyDecided = apperror.New(apperror.KindConflict, "leave is no longer pending")
+
+type Repository interface {
+	InsertLeaveByStruct(ctx context.Context, l *entity.Leave) error
+	GetLeaveByID(ctx context.Context, leaveID int) (*entity.Leave, error)
+	GetLeavesByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.Leave, error)
+	GetPendingLeaves(ctx context.Context) ([]entity.Leave, error)
+	DecideLeaveByID(ctx context.Context, leaveID, approverID int, status string) error
+	DeleteLeaveByID(ctx context.Context, leaveID int) error
+	IsApprover(ctx context.Context, consultantID int) (bool, error)
+	GetAvailabilityByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.AvailabilityDay, error)
+	InsertHolidays(ctx context.Context, holidays []entity.Holiday) error
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	logger    *logger.Logger
+}
+
+func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository {
+	return &repository{dbContext, _l}
+}
+
+// InsertLeaveByStruct will insert a leave request and set its ID, status and creation date
+func (r *repository) InsertLeaveByStruct(ctx context.Context, l *entity.Leave) error {
+	ctx, span := tracing.Start(ctx, "leave.repository.InsertLeaveByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`INSERT INTO consultant_leaves (consultant_id, type, start_date, end_date, note)
+			VALUES ({:consultant_id}, {:type}, {:start_date}, {:end_date}, {:note})
+			RETURNING id, status, date_created`).
+			Bind(dbx.Params{
+				"consultant_id": l.ConsultantID,
+				"type":          l.Type,
+				"start_date":    l.StartDate,
+				"end_date":      l.EndDate,
+				"note":          l.Note,
+			}).One(l)
+	})
+}
+
+// GetLeaveByID will get and return a leave by ID
+func (r *repository) GetLeaveByID(ctx context.Context, leaveID int) (*entity.Leave, error) {
+	ctx, span := tracing.Start(ctx, "leave.repository.GetLeaveByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var l entity.Leave
+	err := r.dbContext.Get().WithContext(ctx).Select().From("consultant_leaves").Where(dbx.HashExp{"id": leaveID}).One(&l)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("leave %d not found", leaveID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// GetLeavesByConsultantID will return the leave of a consultant overlapping [from, to]
+func (r *repository) GetLeavesByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.Leave, error) {
+	ctx, span := tracing.Start(ctx, "leave.repository.GetLeavesByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Leave
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("consultant_leaves").
+		Where(dbx.HashExp{"consultant_id": consultantID}).
+		AndWhere(dbx.NewExp("start_date <= {:to} AND end_date >= {:from}", dbx.Params{"from": from, "to": to})).
+		OrderBy("start_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetPendingLeaves will return every leave request awaiting a decision, oldest first
+func (r *repository) GetPendingLeaves(ctx context.Context) ([]entity.Leave, error) {
+	ctx, span := tracing.Start(ctx, "leave.repository.GetPendingLeaves")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Leave
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("consultant_leaves").
+		Where(dbx.HashExp{"status": StatusPending}).
+		OrderBy("date_created ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// DecideLeaveByID will approve or reject a pending leave request
+func (r *repository) DecideLeaveByID(ctx context.Context, leaveID, approverID int, status string) error {
+	ctx, span := tracing.Start(ctx, "leave.repository.DecideLeaveByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		result, err := tx.Update("consultant_leaves", dbx.Params{
+			"status":       status,
+			"approver_id":  approverID,
+			"date_decided": time.Now(),
+		}, dbx.HashExp{"id": leaveID, "status": StatusPending}).Execute()
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return ErrLeaveAlreadyDecided
+		}
+		return nil
+	})
+}
+
+// DeleteLeaveByID will delete a leave by ID
+func (r *repository) DeleteLeaveByID(ctx context.Context, leaveID int) error {
+	ctx, span := tracing.Start(ctx, "leave.repository.DeleteLeaveByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("consultant_leaves", dbx.HashExp{"id": leaveID}).Execute()
+		return err
+	})
+}
+
+// IsApprover will report whether the consultant holds a role allowed to decide on leave
+func (r *repository) IsApprover(ctx context.Context, consultantID int) (bool, error) {
+	ctx, span := tracing.Start(ctx, "leave.repository.IsApprover")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := r.dbContext.Get().WithContext(ctx).Select("COUNT(*)").
+		From("consultant_roles").
+		Where(dbx.HashExp{"consultant_id": consultantID}).
+		AndWhere(dbx.NewExp("role = ANY({:roles})", dbx.Params{"roles": pq.StringArray(ApproverRoles)})).
+		Row(&count)
+	return count > 0, err
+}
+
+// GetAvailabilityByConsultantID will return the available hours of a consultant per day of [from, to]
+func (r *repository) GetAvailabilityByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.AvailabilityDay, error) {
+	ctx, span := tracing.Start(ctx, "leave.repository.GetAvailabilityByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.AvailabilityDay
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`SELECT consultant_id, day, available_hours, reason
+		FROM consultant_availability({:from}::date, {:to}::date, {:weekly_hours}, {:part_time_percent})
+		WHERE consultant_id = {:consultant_id}
+		ORDER BY day`).
+		Bind(dbx.Params{
+			"consultant_id":     consultantID,
+			"from":              from,
+			"to":                to,
+			"weekly_hours":      capacity.DefaultWeeklyHours,
+			"part_time_percent": capacity.DefaultPartTimePercent,
+		}).All(&list)
+	return list, err
+}
+
+// InsertHolidays will upsert holidays by date in a single transaction
+func (r *repository) InsertHolidays(ctx context.Context, holidays []entity.Holiday) error {
+	ctx, span := tracing.Start(ctx, "leave.repository.InsertHolidays")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		for _, h := range holidays {
+			_, err := tx.NewQuery(`INSERT INTO holidays (holiday_date, name)
+				VALUES ({:holiday_date}, {:name})
+				ON CONFLICT (holiday_date) DO UPDATE SET name = EXCLUDED.name`).
+				Bind(dbx.Params{"holiday_date": h.HolidayDate, "name": h.Name}).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
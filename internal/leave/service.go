@@ -0,0 +1,180 @@
+package leave
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// Leave types
+const (
+	TypeVacation      = "vacation"
+	TypeSick          = "sick"
+	TypePublicHoliday = "public-holiday"
+)
+
+// Leave statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+var (
+	// ErrInvalidLeave is returned when a leave request or holiday calendar fails validation
+	ErrInvalidLeave = apperror.New(apperror.KindValidation, "invalid leave")
+	// ErrNotApprover is returned when the deciding consultant is not allowed to approve leave
+	ErrNotApprover = apperror.New(apperror.KindForbidden, "consultant may not decide on leave")
+
+	// ApproverRoles are the consultant_roles allowed to approve or reject leave
+	ApproverRoles = []string{"manager", "administrator"}
+)
+
+type Service interface {
+	RequestLeave(ctx context.Context, l *Leave) error
+	GetLeaveByID(ctx context.Context, leaveID int) (*Leave, error)
+	GetLeavesByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]Leave, error)
+	GetPendingLeaves(ctx context.Context) ([]Leave, error)
+	DecideLeave(ctx context.Context, leaveID, approverID int, approve bool) (*Leave, error)
+	DeleteLeaveByID(ctx context.Context, leaveID int) error
+	GetAvailabilityByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.AvailabilityDay, error)
+	ImportHolidayCalendar(ctx context.Context, ics io.Reader) (int, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Leave struct {
+	entity.Leave
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// validate checks the leave type and date range of a request
+func (l *Leave) validate() error {
+	switch l.Type {
+	case TypeVacation, TypeSick, TypePublicHoliday:
+	default:
+		return ErrInvalidLeave.WithField("type", fmt.Sprintf("type must be %s, %s or %s", TypeVacation, TypeSick, TypePublicHoliday))
+	}
+	if l.ConsultantID == 0 {
+		return ErrInvalidLeave.WithField("consultantID", "consultantID is required")
+	}
+	if l.StartDate.IsZero() || l.EndDate.IsZero() {
+		return ErrInvalidLeave.WithField("startDate", "startDate and endDate are required")
+	}
+	if l.EndDate.Before(l.StartDate) {
+		return ErrInvalidLeave.WithField("endDate", "endDate must not be before startDate")
+	}
+	return nil
+}
+
+func (s *service) RequestLeave(ctx context.Context, l *Leave) error {
+	if err := l.validate(); err != nil {
+		return err
+	}
+	return s.repo.InsertLeaveByStruct(ctx, &l.Leave)
+}
+
+func (s *service) GetLeaveByID(ctx context.Context, leaveID int) (*Leave, error) {
+	l, err := s.repo.GetLeaveByID(ctx, leaveID)
+	if err != nil {
+		return &Leave{}, err
+	}
+	return &Leave{*l}, nil
+}
+
+func (s *service) GetLeavesByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]Leave, error) {
+	leaves, err := s.repo.GetLeavesByConsultantID(ctx, consultantID, from, to)
+	if err != nil {
+		return []Leave{}, err
+	}
+	results := []Leave{}
+	for _, l := range leaves {
+		results = append(results, Leave{l})
+	}
+	return results, nil
+}
+
+func (s *service) GetPendingLeaves(ctx context.Context) ([]Leave, error) {
+	leaves, err := s.repo.GetPendingLeaves(ctx)
+	if err != nil {
+		return []Leave{}, err
+	}
+	results := []Leave{}
+	for _, l := range leaves {
+		results = append(results, Leave{l})
+	}
+	return results, nil
+}
+
+// DecideLeave approves or rejects pending leave, only managers and administrators may decide and
+// nobody may decide on their own leave
+func (s *service) DecideLeave(ctx context.Context, leaveID, approverID int, approve bool) (*Leave, error) {
+	l, err := s.repo.GetLeaveByID(ctx, leaveID)
+	if err != nil {
+		return &Leave{}, err
+	}
+	if l.ConsultantID == approverID {
+		return &Leave{}, fmt.Errorf("%w: own leave", ErrNotApprover)
+	}
+	allowed, err := s.repo.IsApprover(ctx, approverID)
+	if err != nil {
+		return &Leave{}, err
+	}
+	if !allowed {
+		return &Leave{}, ErrNotApprover
+	}
+
+	status := StatusRejected
+	if approve {
+		status = StatusApproved
+	}
+	if err := s.repo.DecideLeaveByID(ctx, leaveID, approverID, status); err != nil {
+		return &Leave{}, err
+	}
+	return s.GetLeaveByID(ctx, leaveID)
+}
+
+func (s *service) DeleteLeaveByID(ctx context.Context, leaveID int) error {
+	return s.repo.DeleteLeaveByID(ctx, leaveID)
+}
+
+func (s *service) GetAvailabilityByConsultantID(ctx context.Context, consultantID int, from, to time.Time) ([]entity.AvailabilityDay, error) {
+	days, err := s.repo.GetAvailabilityByConsultantID(ctx, consultantID, from, to)
+	if err != nil {
+		return []entity.AvailabilityDay{}, err
+	}
+	if days == nil {
+		days = []entity.AvailabilityDay{}
+	}
+	return days, nil
+}
+
+// ImportHolidayCalendar upserts every day of every event of an iCalendar file into holidays and
+// returns the number of days imported
+func (s *service) ImportHolidayCalendar(ctx context.Context, ics io.Reader) (int, error) {
+	holidays, err := parseICS(ics)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidLeave, err)
+	}
+	for _, h := range holidays {
+		if h.Name == "" {
+			return 0, fmt.Errorf("%w: event on %s has no SUMMARY", ErrInvalidLeave, h.HolidayDate.Format("2006-01-02"))
+		}
+	}
+	if err := s.repo.InsertHolidays(ctx, holidays); err != nil {
+		return 0, err
+	}
+	return len(holidays), nil
+}
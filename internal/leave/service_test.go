@@ -0,0 +1,95 @@
+package leave
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// fakeRepository holds leave 1 of consultant 5, consultant 7 is a manager
+type fakeRepository struct {
+	Repository
+	leave    entity.Leave
+	holidays []entity.Holiday
+}
+
+func (f *fakeRepository) GetLeaveByID(ctx context.Context, leaveID int) (*entity.Leave, error) {
+	l := f.leave
+	return &l, nil
+}
+
+func (f *fakeRepository) IsApprover(ctx context.Context, consultantID int) (bool, error) {
+	return consultantID == 7, nil
+}
+
+func (f *fakeRepository) DecideLeaveByID(ctx context.Context, leaveID, approverID int, status string) error {
+	f.leave.Status = status
+	f.leave.ApproverID = &approverID
+	return nil
+}
+
+func (f *fakeRepository) InsertHolidays(ctx context.Context, holidays []entity.Holiday) error {
+	f.holidays = append(f.holidays, holidays...)
+	return nil
+}
+
+func newTestService() (Service, *fakeRepository) {
+	repo := &fakeRepository{leave: entity.Leave{ID: 1, ConsultantID: 5, Status: StatusPending}}
+	return NewService(repo, logger.New().Prefix("Leave Test")), repo
+}
+
+func TestRequestLeaveValidates(t *testing.T) {
+	day := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	for _, l := range []entity.Leave{
+		{ConsultantID: 5, Type: "holiday", StartDate: day, EndDate: day},
+		{Type: TypeVacation, StartDate: day, EndDate: day},
+		{ConsultantID: 5, Type: TypeSick, StartDate: day},
+		{ConsultantID: 5, Type: TypeVacation, StartDate: day, EndDate: day.AddDate(0, 0, -1)},
+	} {
+		s, _ := newTestService()
+		if err := s.RequestLeave(context.Background(), &Leave{l}); !errors.Is(err, ErrInvalidLeave) {
+			t.Errorf("%+v: err = %v, want ErrInvalidLeave", l, err)
+		}
+	}
+}
+
+func TestDecideLeave(t *testing.T) {
+	s, _ := newTestService()
+	if _, err := s.DecideLeave(context.Background(), 1, 5, true); !errors.Is(err, ErrNotApprover) {
+		t.Fatalf("own leave: err = %v, want ErrNotApprover", err)
+	}
+	if _, err := s.DecideLeave(context.Background(), 1, 6, true); !errors.Is(err, ErrNotApprover) {
+		t.Fatalf("no approver role: err = %v, want ErrNotApprover", err)
+	}
+
+	l, err := s.DecideLeave(context.Background(), 1, 7, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Status != StatusRejected || l.ApproverID == nil || *l.ApproverID != 7 {
+		t.Fatalf("leave = %+v, want rejected by 7", l)
+	}
+}
+
+func TestImportHolidayCalendar(t *testing.T) {
+	s, repo := newTestService()
+	n, err := s.ImportHolidayCalendar(context.Background(), strings.NewReader(calendar(
+		"SUMMARY:Office closed\r\nDTSTART;VALUE=DATE:20261224\r\nDTEND;VALUE=DATE:20261227",
+	)))
+	if err != nil || n != 3 || len(repo.holidays) != 3 {
+		t.Fatalf("imported %d, stored %d, err = %v, want 3 days", n, len(repo.holidays), err)
+	}
+
+	// nothing is stored when an event is unnamed or the calendar does not parse
+	for _, ics := range []string{calendar("DTSTART;VALUE=DATE:20261224"), calendar("SUMMARY:Someday")} {
+		s, repo := newTestService()
+		if _, err := s.ImportHolidayCalendar(context.Background(), strings.NewReader(ics)); !errors.Is(err, ErrInvalidLeave) || len(repo.holidays) != 0 {
+			t.Errorf("err = %v, stored %d, want ErrInvalidLeave and nothing stored", err, len(repo.holidays))
+		}
+	}
+}
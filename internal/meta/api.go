@@ -1,52 +1,53 @@
 package meta
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/cache"
-	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
 )
 
-var (
-	metaLogger = logger.New().Prefix("Meta Logger")
-)
+// Handler serves the project meta routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the project meta routes through service, caching reads in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
 
-func Meta(r chi.Router) {
-	r.Get("/{projectID}", GetProjectMetaByProjectID)
+// Routes registers the project meta routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/{projectID}", h.GetProjectMetaByProjectID)
 }
 
 // GetProjectMetaByProjectID returns the meta data for a project by ID
-func GetProjectMetaByProjectID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProjectMetaByProjectID(w http.ResponseWriter, r *http.Request) {
 	projectIDStr := chi.URLParam(r, "projectID")
 	if projectIDStr == "" {
-		http.Error(w, "projectID is required", http.StatusBadRequest)
-		metaLogger.Error("projectID was missing from request")
+		problem.Write(w, r, apperror.Invalid("projectID", "projectID is required"))
 		return
 	}
 
 	projectID, err := strconv.Atoi(projectIDStr)
 	if err != nil {
-		http.Error(w, "invalid projectID", http.StatusBadRequest)
-		metaLogger.Fatal(err)
+		problem.Write(w, r, apperror.Invalid("projectID", "projectID must be an integer"))
 		return
 	}
 
-	projectMeta, err := cache.Use("projects:meta:"+projectIDStr, func() (*ProjectMeta, error) {
-		md, err := NewService(NewRepository(database.Automatic, metaLogger), metaLogger).GetProjectMetaByProjectID(r.Context(), projectID)
-		if err != nil {
-			metaLogger.Fatal(err)
-			return &ProjectMeta{}, err
-		}
-		return md, err
+	projectMeta, err := cache.Use(r.Context(), h.cache, "projects:meta:"+projectIDStr, func(ctx context.Context) (*ProjectMeta, error) {
+		return h.service.GetProjectMetaByProjectID(ctx, projectID)
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to fetch project meta", http.StatusInternalServerError)
-		metaLogger.Fatal(err)
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -7,11 +7,13 @@ import (
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 	"github.com/renniemaharaj/project-list-go/internal/consultant"
-	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
 	"github.com/renniemaharaj/project-list-go/internal/project"
 	"github.com/renniemaharaj/project-list-go/internal/status"
 	internalTime "github.com/renniemaharaj/project-list-go/internal/time"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"golang.org/x/sync/errgroup"
 )
 
 type Repository interface {
@@ -19,99 +21,157 @@ type Repository interface {
 	GetProjectsMetaByProjectIDS(ctx context.Context, projectIDs []int, light bool) (map[int]entity.ProjectMeta, []entity.Project, error)
 }
 
+// repository composes project meta from the repositories owning each part of it
 type repository struct {
-	dbContext *database.DBContext
-	l         *logger.Logger
+	timeEntries internalTime.Repository
+	statuses    status.Repository
+	projects    project.Repository
+	consultants consultant.Repository
+	milestones  milestone.Repository
+	l           *logger.Logger
 }
 
-func NewRepository(_db *database.DBContext, _l *logger.Logger) Repository {
-	return &repository{_db, _l}
+func NewRepository(timeEntries internalTime.Repository, statuses status.Repository, projects project.Repository, consultants consultant.Repository, milestones milestone.Repository, _l *logger.Logger) Repository {
+	return &repository{
+		timeEntries: timeEntries,
+		statuses:    statuses,
+		projects:    projects,
+		consultants: consultants,
+		milestones:  milestones,
+		l:           _l,
+	}
 }
 
-// GetProjectByID will get and return a project meta data by ID and (error or nil)
+// GetProjectMetaByProjectID will get and return a project meta data by ID and (error or nil). The
+// lookups run concurrently, the first failing one cancels the others and its error is returned, a
+// missing project is not found.
 func (r *repository) GetProjectMetaByProjectID(ctx context.Context, projectID int) (*entity.ProjectMeta, error) {
+	ctx, span := tracing.Start(ctx, "meta.repository.GetProjectMetaByProjectID")
+	defer span.End()
+
 	var projectMeta entity.ProjectMeta
-	// first get time entries
-	timeEntries, err := internalTime.NewRepository(r.dbContext, r.l).GetTimeEntryHistoryByProjectID(ctx, projectID)
-	if err != nil {
-		r.l.Fatal(err)
-	}
-	projectMeta.TimeEntries = timeEntries
-	// second get status history
-	statusHistory, err := status.NewRepository(r.dbContext, r.l).GetStatusHistoryByProjectID(ctx, projectID)
-	if err != nil {
-		r.l.Fatal(err)
-	}
-	projectMeta.StatusHistory = statusHistory
-	// third get project
-	project, err := project.NewRepository(r.dbContext, r.l).GetProjectDataByID(ctx, projectID)
-	if err != nil {
-		r.l.Fatal(err)
-	}
-	// fourth get manager from project
-	manager, err := consultant.NewRepository(r.dbContext, r.l).GetConsultantDataByID(ctx, project.ManagerID)
-	if err != nil {
-		r.l.Fatal(err)
-	}
-	projectMeta.Manager = *manager
-	// fifth get project consultans
-	projectConsultants, err := consultant.NewRepository(r.dbContext, r.l).GetRelatedConsultantsByProjectID(ctx, project.ID)
-	if err != nil {
-		r.l.Fatal(err)
+	g, ctx := errgroup.WithContext(ctx)
+	// the project, then its manager if it has one
+	g.Go(func() error {
+		project, err := r.projects.GetProjectDataByID(ctx, projectID)
+		if err != nil {
+			return err
+		}
+		if project.ManagerID == nil {
+			return nil
+		}
+		projectMeta.Manager, err = r.consultants.GetConsultantDataByID(ctx, *project.ManagerID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectMeta.TimeEntries, err = r.timeEntries.GetTimeEntryHistoryByProjectID(ctx, projectID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectMeta.StatusHistory, err = r.statuses.GetStatusHistoryByProjectID(ctx, projectID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectMeta.Consultants, err = r.consultants.GetRelatedConsultantsByProjectID(ctx, projectID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectMeta.Milestones, err = r.milestones.GetMilestonesByProjectID(ctx, projectID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
 	}
-	projectMeta.Consultants = projectConsultants
 	return &projectMeta, nil
 }
 
 // GetProjectsMetaByProjectIDS will return meta data for multiple projects in batch.
-// It reduces thousands of queries (per project) into 5 total batched queries and logs timings.
+// It reduces thousands of queries (per project) into 6 total batched queries, run concurrently, and logs timings.
 func (r *repository) GetProjectsMetaByProjectIDS(ctx context.Context, projectIDs []int, light bool) (map[int]entity.ProjectMeta, []entity.Project, error) {
+	ctx, span := tracing.Start(ctx, "meta.repository.GetProjectsMetaByProjectIDS")
+	defer span.End()
+
 	start := time.Now()
 	r.l.Info(fmt.Sprintf("Starting GetProjectsMetaByProjectIDS for %d projects", len(projectIDs)))
 
+	var (
+		timeEntries        []entity.TimeEntry
+		statusHistory      []entity.ProjectStatus
+		projects           []entity.Project
+		projectConsultants []entity.ProjectConsultantLink
+		managers           []entity.Consultant
+		milestones         []entity.Milestone
+	)
+	g, ctx := errgroup.WithContext(ctx)
+
 	// --- 1. Batch fetch time entries ---
-	callStart := time.Now()
-	timeEntries, err := internalTime.NewRepository(r.dbContext, r.l).GetTimeEntryHistoryByProjectsIDS(ctx, projectIDs)
-	if err != nil {
-		return nil, nil, err
-	}
-	r.l.Info(fmt.Sprintf("Fetched %d time entries in %v", len(timeEntries), time.Since(callStart)))
+	g.Go(func() (err error) {
+		callStart := time.Now()
+		if timeEntries, err = r.timeEntries.GetTimeEntryHistoryByProjectsIDS(ctx, projectIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d time entries in %v", len(timeEntries), time.Since(callStart)))
+		return nil
+	})
 
 	// --- 2. Batch fetch status history ---
-	callStart = time.Now()
-	statusHistory, err := status.NewRepository(r.dbContext, r.l).GetStatusHistoryByProjectsIDS(ctx, projectIDs)
-	if err != nil {
-		return nil, nil, err
-	}
-	r.l.Info(fmt.Sprintf("Fetched %d status history entries in %v", len(statusHistory), time.Since(callStart)))
-
-	// --- 3. Batch fetch projects ---
-	callStart = time.Now()
-	projects, err := project.NewRepository(r.dbContext, r.l).GetProjectsDataByIDS(ctx, projectIDs)
-	if err != nil {
-		return nil, nil, err
-	}
-	r.l.Info(fmt.Sprintf("Fetched %d projects in %v", len(projects), time.Since(callStart)))
+	g.Go(func() (err error) {
+		callStart := time.Now()
+		if statusHistory, err = r.statuses.GetStatusHistoryByProjectsIDS(ctx, projectIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d status history entries in %v", len(statusHistory), time.Since(callStart)))
+		return nil
+	})
+
+	// --- 3. Batch fetch projects, then their managers ---
+	g.Go(func() (err error) {
+		callStart := time.Now()
+		if projects, err = r.projects.GetProjectsDataByIDS(ctx, projectIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d projects in %v", len(projects), time.Since(callStart)))
+
+		callStart = time.Now()
+		managerIDs := make([]int, 0, len(projects))
+		for _, p := range projects {
+			if p.ManagerID != nil {
+				managerIDs = append(managerIDs, *p.ManagerID)
+			}
+		}
+		if len(managerIDs) == 0 {
+			return nil
+		}
+		if managers, err = r.consultants.GetConsultantDataByIDS(ctx, managerIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d managers in %v", len(managers), time.Since(callStart)))
+		return nil
+	})
 
 	// --- 4. Batch fetch related consultants ---
-	callStart = time.Now()
-	projectConsultants, err := consultant.NewRepository(r.dbContext, r.l).GetRelatedConsultantsByProjectsIDS(ctx, projectIDs)
-	if err != nil {
-		return nil, nil, err
-	}
-	r.l.Info(fmt.Sprintf("Fetched %d project consultants in %v", len(projectConsultants), time.Since(callStart)))
+	g.Go(func() (err error) {
+		callStart := time.Now()
+		if projectConsultants, err = r.consultants.GetRelatedConsultantsByProjectsIDS(ctx, projectIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d project consultants in %v", len(projectConsultants), time.Since(callStart)))
+		return nil
+	})
+
+	// --- 5. Batch fetch milestones ---
+	g.Go(func() (err error) {
+		callStart := time.Now()
+		if milestones, err = r.milestones.GetMilestonesByProjectsIDS(ctx, projectIDs); err != nil {
+			return err
+		}
+		r.l.Info(fmt.Sprintf("Fetched %d milestones in %v", len(milestones), time.Since(callStart)))
+		return nil
+	})
 
-	// --- 5. Batch fetch managers ---
-	callStart = time.Now()
-	managerIDs := make([]int, 0, len(projects))
-	for _, p := range projects {
-		managerIDs = append(managerIDs, p.ManagerID)
-	}
-	managers, err := consultant.NewRepository(r.dbContext, r.l).GetConsultantDataByIDS(ctx, managerIDs)
-	if err != nil {
+	if err := g.Wait(); err != nil {
 		return nil, nil, err
 	}
-	r.l.Info(fmt.Sprintf("Fetched %d managers in %v", len(managers), time.Since(callStart)))
 
 	// --- 6. Group results into maps for quick lookup ---
 	timeMap := make(map[int][]entity.TimeEntry)
@@ -139,16 +199,27 @@ func (r *repository) GetProjectsMetaByProjectIDS(ctx context.Context, projectIDs
 		consultantsMap[c.ProjectID] = append(consultantsMap[c.ProjectID], c.Consultant)
 	}
 
+	milestoneMap := make(map[int][]entity.Milestone)
+	for _, m := range milestones {
+		milestoneMap[m.ProjectID] = append(milestoneMap[m.ProjectID], m)
+	}
+
 	// --- 7. Construct ProjectMeta map ---
 	projectMetas := make(map[int]entity.ProjectMeta, len(projectIDs))
 	for _, pid := range projectIDs {
-		p := projectMap[pid]
+		var manager *entity.Consultant
+		if p := projectMap[pid]; p.ManagerID != nil {
+			if m, ok := managerMap[*p.ManagerID]; ok {
+				manager = &m
+			}
+		}
 
 		projectMetas[pid] = entity.ProjectMeta{
 			TimeEntries:   timeMap[pid],
 			StatusHistory: statusMap[pid],
-			Manager:       managerMap[p.ManagerID],
+			Manager:       manager,
 			Consultants:   consultantsMap[pid],
+			Milestones:    milestoneMap[pid],
 		}
 	}
 
@@ -0,0 +1,200 @@
+package meta
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/consultant"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
+	"github.com/renniemaharaj/project-list-go/internal/project"
+	"github.com/renniemaharaj/project-list-go/internal/status"
+	internalTime "github.com/renniemaharaj/project-list-go/internal/time"
+)
+
+var errQuery = errors.New("pq: connection reset by peer")
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// fakeProjects knows project 1, managed by consultant 7, and project 2 without a manager
+type fakeProjects struct {
+	project.Repository
+}
+
+var projects = map[int]entity.Project{
+	1: {ID: 1, ManagerID: intPtr(7)},
+	2: {ID: 2},
+}
+
+func (fakeProjects) GetProjectDataByID(ctx context.Context, projectID int) (*entity.Project, error) {
+	p, ok := projects[projectID]
+	if !ok {
+		return nil, apperror.NotFound("project %d not found", projectID)
+	}
+	return &p, nil
+}
+
+func (fakeProjects) GetProjectsDataByIDS(ctx context.Context, ids []int) ([]entity.Project, error) {
+	list := []entity.Project{}
+	for _, id := range ids {
+		if p, ok := projects[id]; ok {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+// fakeConsultants knows manager 7 and records the managers looked up
+type fakeConsultants struct {
+	consultant.Repository
+	mu      sync.Mutex
+	lookups [][]int
+}
+
+func (f *fakeConsultants) GetConsultantDataByID(ctx context.Context, consultantID int) (*entity.Consultant, error) {
+	f.mu.Lock()
+	f.lookups = append(f.lookups, []int{consultantID})
+	f.mu.Unlock()
+	return &entity.Consultant{ID: consultantID}, nil
+}
+
+func (f *fakeConsultants) GetConsultantDataByIDS(ctx context.Context, consultantIDS []int) ([]entity.Consultant, error) {
+	f.mu.Lock()
+	f.lookups = append(f.lookups, consultantIDS)
+	f.mu.Unlock()
+	list := make([]entity.Consultant, len(consultantIDS))
+	for i, id := range consultantIDS {
+		list[i] = entity.Consultant{ID: id}
+	}
+	return list, nil
+}
+
+func (f *fakeConsultants) GetRelatedConsultantsByProjectID(ctx context.Context, projectID int) ([]entity.Consultant, error) {
+	return []entity.Consultant{{ID: 3}}, nil
+}
+
+func (f *fakeConsultants) GetRelatedConsultantsByProjectsIDS(ctx context.Context, projectIDs []int) ([]entity.ProjectConsultantLink, error) {
+	return []entity.ProjectConsultantLink{{Consultant: entity.Consultant{ID: 3}, ProjectID: 1}}, nil
+}
+
+// fakeTimeEntries fails with err when set
+type fakeTimeEntries struct {
+	internalTime.Repository
+	err error
+}
+
+func (f fakeTimeEntries) GetTimeEntryHistoryByProjectID(ctx context.Context, projectID int) ([]entity.TimeEntry, error) {
+	return nil, f.err
+}
+
+func (f fakeTimeEntries) GetTimeEntryHistoryByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.TimeEntry, error) {
+	return nil, f.err
+}
+
+// fakeStatuses waits for its context to be canceled when block is set
+type fakeStatuses struct {
+	status.Repository
+	block bool
+}
+
+func (f fakeStatuses) wait(ctx context.Context) error {
+	if !f.block {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return errors.New("not canceled")
+	}
+}
+
+func (f fakeStatuses) GetStatusHistoryByProjectID(ctx context.Context, projectID int) ([]entity.ProjectStatus, error) {
+	return nil, f.wait(ctx)
+}
+
+func (f fakeStatuses) GetStatusHistoryByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.ProjectStatus, error) {
+	return nil, f.wait(ctx)
+}
+
+type fakeMilestones struct {
+	milestone.Repository
+}
+
+func (fakeMilestones) GetMilestonesByProjectID(ctx context.Context, projectID int) ([]entity.Milestone, error) {
+	return nil, nil
+}
+
+func (fakeMilestones) GetMilestonesByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.Milestone, error) {
+	return nil, nil
+}
+
+func newTestRepository(timeEntries fakeTimeEntries, statuses fakeStatuses) (Repository, *fakeConsultants) {
+	consultants := &fakeConsultants{}
+	return NewRepository(timeEntries, statuses, fakeProjects{}, consultants, fakeMilestones{}, logger.New().Prefix("Meta Test")), consultants
+}
+
+func TestGetProjectMetaByProjectID(t *testing.T) {
+	r, consultants := newTestRepository(fakeTimeEntries{}, fakeStatuses{})
+
+	meta, err := r.GetProjectMetaByProjectID(context.Background(), 1)
+	if err != nil || meta.Manager == nil || meta.Manager.ID != 7 || len(meta.Consultants) != 1 {
+		t.Fatalf("meta = %+v, err = %v, want manager 7", meta, err)
+	}
+
+	// projects without a manager have none, instead of failing the lookup of manager 0
+	meta, err = r.GetProjectMetaByProjectID(context.Background(), 2)
+	if err != nil || meta.Manager != nil || len(consultants.lookups) != 1 {
+		t.Fatalf("meta = %+v, err = %v, lookups %v, want no manager", meta, err, consultants.lookups)
+	}
+
+	if _, err := r.GetProjectMetaByProjectID(context.Background(), 3); apperror.KindOf(err) != apperror.KindNotFound {
+		t.Fatalf("err = %v, want not found", err)
+	}
+}
+
+func TestGetProjectMetaPropagatesErrors(t *testing.T) {
+	// the failing lookup cancels the blocked one and its error is returned
+	r, _ := newTestRepository(fakeTimeEntries{err: errQuery}, fakeStatuses{block: true})
+
+	start := time.Now()
+	if _, err := r.GetProjectMetaByProjectID(context.Background(), 1); !errors.Is(err, errQuery) {
+		t.Fatalf("err = %v, want the failing lookup's error", err)
+	}
+	if _, _, err := r.GetProjectsMetaByProjectIDS(context.Background(), []int{1, 2}, false); !errors.Is(err, errQuery) {
+		t.Fatalf("batch err = %v, want the failing lookup's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("took %s, want the other lookups canceled", elapsed)
+	}
+}
+
+func TestGetProjectsMetaByProjectIDS(t *testing.T) {
+	r, consultants := newTestRepository(fakeTimeEntries{}, fakeStatuses{})
+
+	metas, list, err := r.GetProjectsMetaByProjectIDS(context.Background(), []int{1, 2}, false)
+	if err != nil || len(list) != 2 || len(metas) != 2 {
+		t.Fatalf("metas = %+v, projects = %+v, err = %v", metas, list, err)
+	}
+	if metas[1].Manager == nil || metas[1].Manager.ID != 7 || len(metas[1].Consultants) != 1 {
+		t.Fatalf("project 1 = %+v, want manager 7 and its consultant", metas[1])
+	}
+	if metas[2].Manager != nil || len(metas[2].Consultants) != 0 {
+		t.Fatalf("project 2 = %+v, want neither manager nor consultants", metas[2])
+	}
+
+	// only managers that exist are looked up, and not at all without any
+	if _, _, err := r.GetProjectsMetaByProjectIDS(context.Background(), []int{2}, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(consultants.lookups) != 1 || len(consultants.lookups[0]) != 1 || consultants.lookups[0][0] != 7 {
+		t.Fatalf("manager lookups = %v, want [[7]]", consultants.lookups)
+	}
+}
@@ -46,7 +46,7 @@ func (s *service) GetProjectsMetaByProjectIDS(ctx context.Context, projectIDs []
 	}
 	projectData := make([]project.Project, len(projects))
 	for i, p := range projects {
-		projectData[i] = project.Project{p}
+		projectData[i] = project.Project{Project: p}
 	}
 	return resultMetas, projectData, nil
 }
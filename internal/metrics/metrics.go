@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Cache results counted per key family
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+	CacheFill = "fill"
+)
+
+var (
+	// registry holds the collectors served on /metrics, runtime and process metrics included
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method and chi route pattern.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_statement_duration_seconds",
+		Help:    "Duration of SQL statements by operation (query or exec).",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 16),
+	}, []string{"operation"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_statement_errors_total",
+		Help: "Failed SQL statements by operation (query or exec).",
+	}, []string{"operation"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Cache lookups by key family and result (hit, miss or fill).",
+	}, []string{"family", "result"})
+
+	dashboardBuild = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "dashboard_build_duration_seconds",
+		Help:    "Duration of dashboard metric builds that missed the cache.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+
+	replicaLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "db_replica_lag_seconds",
+		Help: "Replication lag of the read replica, -1 while it is unreachable or the lag is unknown.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, dbDuration, dbErrors, cacheRequests, dashboardBuild, replicaLag,
+	)
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDBStats exports the connection pool stats of db, labeled with name
+func RegisterDBStats(db *sql.DB, name string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveDB records the duration and outcome of a SQL statement, operation is query or exec
+func ObserveDB(operation string, d time.Duration, err error) {
+	dbDuration.WithLabelValues(operation).Observe(d.Seconds())
+	if err != nil {
+		dbErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveCache counts a cache result for the family of key
+func ObserveCache(key, result string) {
+	cacheRequests.WithLabelValues(KeyFamily(key), result).Inc()
+}
+
+// SetReplicaLag records the replication lag of the read replica, negative when unknown
+func SetReplicaLag(seconds float64) {
+	replicaLag.Set(seconds)
+}
+
+// ObserveDashboardBuild records the duration of a dashboard build
+func ObserveDashboardBuild(d time.Duration) {
+	dashboardBuild.Observe(d.Seconds())
+}
+
+// KeyFamily returns the first two segments of a cache key, e.g. projects:one for projects:one:42. The
+// second segment is dropped when it is not a word, so ids and dates never become label values.
+func KeyFamily(key string) string {
+	segments := strings.SplitN(key, ":", 3)
+	if len(segments) == 1 || !isWord(segments[1]) {
+		return segments[0]
+	}
+	return segments[0] + ":" + segments[1]
+}
+
+// isWord reports whether s is made of letters and underscores only
+func isWord(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
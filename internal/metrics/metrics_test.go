@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestKeyFamily(t *testing.T) {
+	for key, want := range map[string]string{
+		"metrics_dashboard":                     "metrics_dashboard",
+		"metrics_dashboard:manager:1:tag:cloud": "metrics_dashboard:manager",
+		"projects:one:42":                       "projects:one",
+		"projects:milestones:7":                 "projects:milestones",
+		"projects:42":                           "projects",
+		"timeline:2026-01-01:2026-03-31":        "timeline",
+		"dashboard:series:2026-01-01":           "dashboard:series",
+		"projects::1":                           "projects",
+	} {
+		if got := KeyFamily(key); got != want {
+			t.Errorf("KeyFamily(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+// scrape returns the exposition of the registered metrics
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	b, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/project/{projectID}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/project/{projectID}/tags", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/project/1", nil),
+		httptest.NewRequest(http.MethodGet, "/project/2", nil),
+		httptest.NewRequest(http.MethodPost, "/project/2/tags", nil),
+		httptest.NewRequest(http.MethodGet, "/nowhere/3", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	out := scrape(t)
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/project/{projectID}",status="200"} 2`,
+		`http_requests_total{method="POST",route="/project/{projectID}/tags",status="201"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	// ids never become label values
+	if strings.Contains(out, `route="/project/1"`) {
+		t.Error("request path used as route label")
+	}
+}
+
+func TestObserve(t *testing.T) {
+	ObserveCache("projects:one:42", CacheHit)
+	ObserveCache("projects:one:43", CacheHit)
+	ObserveDB("exec", time.Millisecond, io.EOF)
+	ObserveDashboardBuild(time.Second)
+
+	out := scrape(t)
+	for _, want := range []string{
+		`cache_requests_total{family="projects:one",result="hit"} 2`,
+		`db_statement_errors_total{operation="exec"} 1`,
+		`db_statement_duration_seconds_count{operation="exec"} 1`,
+		`dashboard_build_duration_seconds_count 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
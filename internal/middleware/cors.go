@@ -2,21 +2,166 @@ package cors
 
 import (
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/renniemaharaj/project-list-go/internal/config"
 )
 
-// CORS middleware handle cors policy
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Adjust this based on your frontend origin
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
+// policy is a compiled config.CORSPolicy
+type policy struct {
+	anyOrigin  bool
+	origins    map[string]struct{}
+	wildcards  []wildcard
+	methods    string
+	anyHeader  bool
+	headers    string
+	exposed    string
+	credential bool
+	maxAge     string
+}
+
+// wildcard matches the subdomains of suffix, e.g. https://*.example.com matches https://app.example.com
+// but neither https://example.com nor http://app.example.com
+type wildcard struct {
+	scheme string
+	suffix string
+}
+
+// New returns the CORS middleware of a route group policy. Requests from disallowed origins are served
+// without CORS headers, so browsers block reading them. Responses depending on the origin vary on it.
+func New(cfg config.CORSPolicy) func(http.Handler) http.Handler {
+	p := compile(cfg)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// a shared "*" does not depend on the origin, everything else does
+			if !p.anyOrigin || p.credential {
+				w.Header().Add("Vary", "Origin")
+			}
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !p.allows(origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			if p.anyOrigin && !p.credential {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.credential {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", p.methods)
+				if p.anyHeader {
+					if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+						h.Set("Access-Control-Allow-Headers", requested)
+					}
+				} else if p.headers != "" {
+					h.Set("Access-Control-Allow-Headers", p.headers)
+				}
+				if p.maxAge != "" {
+					h.Set("Access-Control-Max-Age", p.maxAge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if p.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", p.exposed)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PathPolicy applies a policy to a path and everything under it
+type PathPolicy struct {
+	Prefix string
+	Policy config.CORSPolicy
+}
+
+// ByPath returns a middleware applying the policy of the first matching path, and fallback to every
+// other request. It runs before routing because chi only runs route group middlewares for matched
+// routes, which preflight requests of routes without an OPTIONS handler never are.
+func ByPath(fallback config.CORSPolicy, policies ...PathPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallbackHandler := New(fallback)(next)
+		handlers := make([]http.Handler, len(policies))
+		for i, p := range policies {
+			handlers[i] = New(p.Policy)(next)
 		}
 
-		next.ServeHTTP(w, r)
-	})
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i, p := range policies {
+				if r.URL.Path == p.Prefix || strings.HasPrefix(r.URL.Path, strings.TrimSuffix(p.Prefix, "/")+"/") {
+					handlers[i].ServeHTTP(w, r)
+					return
+				}
+			}
+			fallbackHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// compile normalizes the origins of cfg and renders its header values once
+func compile(cfg config.CORSPolicy) policy {
+	p := policy{
+		origins:    map[string]struct{}{},
+		methods:    strings.Join(cfg.AllowedMethods, ", "),
+		anyHeader:  slices.Contains(cfg.AllowedHeaders, "*"),
+		headers:    strings.Join(cfg.AllowedHeaders, ", "),
+		exposed:    strings.Join(cfg.ExposedHeaders, ", "),
+		credential: cfg.AllowCredentials,
+	}
+	if seconds := int(cfg.MaxAge.Seconds()); seconds > 0 {
+		p.maxAge = strconv.Itoa(seconds)
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			p.wildcards = append(p.wildcards, wildcard{scheme: scheme + "://", suffix: "." + host})
+			continue
+		}
+		p.origins[origin] = struct{}{}
+	}
+	return p
+}
+
+// allows reports whether the policy accepts the request origin
+func (p policy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if host, ok := strings.CutPrefix(origin, w.scheme); ok && strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,134 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/config"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+})
+
+func serve(h http.Handler, method, path, origin string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestAllows(t *testing.T) {
+	p := compile(config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com/", "https://*.Example.org"}})
+	for origin, want := range map[string]bool{
+		"https://app.example.com":     true,
+		"HTTPS://APP.EXAMPLE.COM":     true,
+		"http://app.example.com":      false,
+		"https://example.com":         false,
+		"https://api.example.org":     true,
+		"https://a.b.example.org":     true,
+		"https://example.org":         false,
+		"https://.example.org":        false,
+		"http://api.example.org":      false,
+		"https://api.example.org.com": false,
+		"https://evilexample.org":     false,
+	} {
+		if got := p.allows(origin); got != want {
+			t.Errorf("allows(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	policy := config.CORSPolicy{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+	h := New(policy)(ok)
+
+	w := serve(h, http.MethodGet, "/project", "https://app.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Expose-Headers") != "ETag" ||
+		w.Header().Get("Vary") != "Origin" || w.Body.String() != "ok" {
+		t.Fatalf("allowed origin: headers = %v, body %q", w.Header(), w.Body.String())
+	}
+
+	// disallowed origins are served without CORS headers for the browser to block
+	w = serve(h, http.MethodGet, "/project", "https://evil.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Body.String() != "ok" {
+		t.Fatalf("disallowed origin: headers = %v, body %q", w.Header(), w.Body.String())
+	}
+
+	w = serve(h, http.MethodOptions, "/project", "https://app.example.com", "Access-Control-Request-Method", "POST")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 ||
+		w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" ||
+		w.Header().Get("Access-Control-Max-Age") != "600" ||
+		len(w.Header().Values("Vary")) != 3 {
+		t.Fatalf("preflight: status = %d, headers = %v", w.Code, w.Header())
+	}
+
+	// a preflight of a disallowed origin ends without allowing anything
+	w = serve(h, http.MethodOptions, "/project", "https://evil.example.com", "Access-Control-Request-Method", "POST")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "" || w.Body.Len() != 0 {
+		t.Fatalf("disallowed preflight: status = %d, headers = %v", w.Code, w.Header())
+	}
+
+	// an OPTIONS request without Access-Control-Request-Method is not a preflight
+	if w = serve(h, http.MethodOptions, "/project", "https://app.example.com"); w.Body.String() != "ok" {
+		t.Fatalf("options: body = %q, want it served", w.Body.String())
+	}
+}
+
+func TestNewAnyOrigin(t *testing.T) {
+	h := New(config.CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*"}})(ok)
+
+	w := serve(h, http.MethodGet, "/healthz", "https://anywhere.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Fatalf("headers = %v, want a shared * without Vary", w.Header())
+	}
+
+	// any header echoes the requested headers
+	w = serve(h, http.MethodOptions, "/healthz", "https://anywhere.example.com",
+		"Access-Control-Request-Method", "GET", "Access-Control-Request-Headers", "X-Trace")
+	if w.Header().Get("Access-Control-Allow-Headers") != "X-Trace" || w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Fatalf("preflight headers = %v", w.Header())
+	}
+}
+
+func TestNewCredentials(t *testing.T) {
+	h := New(config.CORSPolicy{AllowedOrigins: []string{"https://*.example.com"}, AllowCredentials: true})(ok)
+	w := serve(h, http.MethodGet, "/project", "https://app.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("headers = %v, want the origin echoed with credentials", w.Header())
+	}
+}
+
+func TestByPath(t *testing.T) {
+	api := config.CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: []string{"GET"}}
+	public := config.CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}
+	h := ByPath(api, PathPolicy{Prefix: "/metrics", Policy: public})(ok)
+
+	for path, want := range map[string]string{
+		"/metrics":       "*",
+		"/metrics/":      "*",
+		"/metrics/child": "*",
+		"/metricsextra":  "",
+		"/project":       "",
+	} {
+		w := serve(h, http.MethodGet, path, "https://other.example.com")
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("%s: allowed origin = %q, want %q", path, got, want)
+		}
+	}
+}
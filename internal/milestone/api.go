@@ -0,0 +1,165 @@
+package milestone
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+// Handler serves the milestone routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the milestone routes through service, caching reads in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
+
+// Routes registers the milestone routes, mounted under /project/{projectID}/milestones
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.GetMilestonesByProjectID)
+	r.Post("/", h.CreateMilestone)
+	r.Get("/{milestoneID}", h.GetMilestoneByID)
+	r.Put("/{milestoneID}", h.UpdateMilestone)
+	r.Delete("/{milestoneID}", h.DeleteMilestone)
+}
+
+// Clears cached values which embed the milestones of a project
+func (h *Handler) invalidateProjectMilestones(projectID int) {
+	h.cache.Invalidate(
+		fmt.Sprintf("projects:milestones:%d", projectID),
+		fmt.Sprintf("projects:meta:%d", projectID),
+		"metrics_dashboard",
+	)
+}
+
+// Loads a milestone and makes sure it belongs to the project in the url
+func (h *Handler) getProjectMilestone(w http.ResponseWriter, r *http.Request) (*Milestone, bool) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return nil, false
+	}
+	milestoneID, err := problem.IntParam(w, r, "milestoneID")
+	if err != nil {
+		return nil, false
+	}
+
+	m, err := h.service.GetMilestoneByID(r.Context(), milestoneID)
+	if err == nil && m.ProjectID != projectID {
+		err = apperror.NotFound("milestone %d not found", milestoneID)
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return m, true
+}
+
+// GetMilestonesByProjectID returns all milestones of a project
+func (h *Handler) GetMilestonesByProjectID(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	milestones, err := cache.Use(r.Context(), h.cache, fmt.Sprintf("projects:milestones:%d", projectID), func(ctx context.Context) ([]Milestone, error) {
+		return h.service.GetMilestonesByProjectID(ctx, projectID)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(milestones)
+}
+
+// GetMilestoneByID returns a single milestone of a project
+func (h *Handler) GetMilestoneByID(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.getProjectMilestone(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+// CreateMilestone inserts a milestone for the project from the request body
+func (h *Handler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	var m Milestone
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid milestone body"))
+		return
+	}
+	m.ID = 0
+	m.ProjectID = projectID
+
+	if err := h.service.InsertMilestoneByStruct(r.Context(), &m); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectMilestones(projectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+// UpdateMilestone replaces a milestone of the project with the request body
+func (h *Handler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getProjectMilestone(w, r)
+	if !ok {
+		return
+	}
+
+	var m Milestone
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid milestone body"))
+		return
+	}
+	m.ID = existing.ID
+	m.ProjectID = existing.ProjectID
+	// Keep the original completion date when re-saving a completed milestone
+	if m.Completed && m.DateCompleted == nil {
+		m.DateCompleted = existing.DateCompleted
+	}
+
+	if err := h.service.UpdateMilestoneByStruct(r.Context(), &m); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectMilestones(m.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+// DeleteMilestone removes a milestone of the project
+func (h *Handler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.getProjectMilestone(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMilestoneByID(r.Context(), m.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectMilestones(m.ProjectID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
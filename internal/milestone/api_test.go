@@ -0,0 +1,92 @@
+package milestone
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// stubService serves milestone 1 of project 10 and records deletions
+type stubService struct {
+	Service
+	deleted []int
+}
+
+func (s *stubService) GetMilestoneByID(ctx context.Context, milestoneID int) (*Milestone, error) {
+	if milestoneID != 1 {
+		return &Milestone{}, apperror.NotFound("milestone %d not found", milestoneID)
+	}
+	return &Milestone{entity.Milestone{ID: 1, ProjectID: 10, Title: "Go live"}}, nil
+}
+
+func (s *stubService) InsertMilestoneByStruct(ctx context.Context, m *Milestone) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	m.ID = 2
+	return nil
+}
+
+func (s *stubService) DeleteMilestoneByID(ctx context.Context, milestoneID int) error {
+	s.deleted = append(s.deleted, milestoneID)
+	return nil
+}
+
+func newTestRouter(s Service) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/project/{projectID}/milestones", NewHandler(s, nil).Routes)
+	return r
+}
+
+func TestMilestoneRoutes(t *testing.T) {
+	due := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339)
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/project/10/milestones/1", "", http.StatusOK},
+		{http.MethodGet, "/project/11/milestones/1", "", http.StatusNotFound}, // of another project
+		{http.MethodGet, "/project/10/milestones/3", "", http.StatusNotFound},
+		{http.MethodGet, "/project/10/milestones/first", "", http.StatusBadRequest},
+		{http.MethodPost, "/project/10/milestones", `{"title":"Launch","dueDate":"` + due + `"}`, http.StatusCreated},
+		{http.MethodPost, "/project/10/milestones", `{"title":" "}`, http.StatusBadRequest},
+		{http.MethodPost, "/project/10/milestones", `{`, http.StatusBadRequest},
+		{http.MethodDelete, "/project/11/milestones/1", "", http.StatusNotFound},
+		{http.MethodDelete, "/project/10/milestones/1", "", http.StatusNoContent},
+	}
+	s := &stubService{}
+	router := newTestRouter(s)
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body.String())
+		}
+	}
+	// only the milestone of the project in the url was deleted
+	if len(s.deleted) != 1 || s.deleted[0] != 1 {
+		t.Fatalf("deleted = %v, want milestone 1 once", s.deleted)
+	}
+}
+
+func TestCreateMilestoneTakesProjectFromURL(t *testing.T) {
+	w := httptest.NewRecorder()
+	body := `{"id":7,"projectID":99,"title":"Launch","dueDate":"2026-12-01T00:00:00Z"}`
+	newTestRouter(&stubService{}).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/project/10/milestones", strings.NewReader(body)))
+
+	var m entity.Milestone
+	if err := json.NewDecoder(w.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 2 || m.ProjectID != 10 {
+		t.Fatalf("milestone = %+v, want the id of the service and the project of the url", m)
+	}
+}
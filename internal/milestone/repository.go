@@ -0,0 +1,167 @@
+package milestone
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+type Repository interface {
+	InsertMilestoneByStruct(ctx context.Context, m *entity.Milestone) error
+	GetMilestoneByID(ctx context.Context, milestoneID int) (*entity.Milestone, error)
+	GetMilestonesByProjectID(ctx context.Context, projectID int) ([]entity.Milestone, error)
+	GetMilestonesByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.Milestone, error)
+	GetMilestonesByProjectsIDSInRange(ctx context.Context, projectIDS []int, from, to time.Time) ([]entity.Milestone, error)
+	UpdateMilestoneByStruct(ctx context.Context, m *entity.Milestone) error
+	DeleteMilestoneByID(ctx context.Context, milestoneID int) error
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	logger    *logger.Logger
+}
+
+func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository {
+	return &repository{dbContext, _l}
+}
+
+// InsertMilestoneByStruct will insert a milestone into project_milestones and set its ID
+func (r *repository) InsertMilestoneByStruct(ctx context.Context, m *entity.Milestone) error {
+	ctx, span := tracing.Start(ctx, "milestone.repository.InsertMilestoneByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		// lib/pq does not support LastInsertId, so the ID is read back with RETURNING
+		return tx.NewQuery(`INSERT INTO project_milestones
+			(project_id, owner_id, title, description, due_date, completed, date_completed)
+			VALUES ({:project_id}, {:owner_id}, {:title}, {:description}, {:due_date}, {:completed}, {:date_completed})
+			RETURNING id`).
+			Bind(dbx.Params{
+				"project_id":     m.ProjectID,
+				"owner_id":       m.OwnerID,
+				"title":          m.Title,
+				"description":    m.Description,
+				"due_date":       m.DueDate,
+				"completed":      m.Completed,
+				"date_completed": m.DateCompleted,
+			}).Row(&m.ID)
+	})
+}
+
+// GetMilestoneByID will get and return a milestone by ID
+func (r *repository) GetMilestoneByID(ctx context.Context, milestoneID int) (*entity.Milestone, error) {
+	ctx, span := tracing.Start(ctx, "milestone.repository.GetMilestoneByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var m entity.Milestone
+	err := r.dbContext.Get().WithContext(ctx).Select().From("project_milestones").Where(dbx.HashExp{"id": milestoneID}).One(&m)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("milestone %d not found", milestoneID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// GetMilestonesByProjectID will return all milestones of a project ordered by due date
+func (r *repository) GetMilestonesByProjectID(ctx context.Context, projectID int) ([]entity.Milestone, error) {
+	ctx, span := tracing.Start(ctx, "milestone.repository.GetMilestonesByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Milestone
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("project_milestones").
+		Where(dbx.HashExp{"project_id": projectID}).
+		OrderBy("due_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetMilestonesByProjectsIDS will return all milestones for multiple projects
+func (r *repository) GetMilestonesByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.Milestone, error) {
+	ctx, span := tracing.Start(ctx, "milestone.repository.GetMilestonesByProjectsIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Milestone
+
+	// Convert []int -> []interface{} for dbx.In
+	args := make([]interface{}, len(projectIDS))
+	for i, id := range projectIDS {
+		args[i] = id
+	}
+
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("project_milestones").
+		Where(dbx.In("project_id", args...)).
+		OrderBy("due_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetMilestonesByProjectsIDSInRange will return milestones for multiple projects due within [from, to]
+func (r *repository) GetMilestonesByProjectsIDSInRange(ctx context.Context, projectIDS []int, from, to time.Time) ([]entity.Milestone, error) {
+	ctx, span := tracing.Start(ctx, "milestone.repository.GetMilestonesByProjectsIDSInRange")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Milestone
+
+	// Convert []int -> []interface{} for dbx.In
+	args := make([]interface{}, len(projectIDS))
+	for i, id := range projectIDS {
+		args[i] = id
+	}
+
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("project_milestones").
+		Where(dbx.In("project_id", args...)).
+		AndWhere(dbx.Between("due_date", from, to)).
+		OrderBy("due_date ASC", "id ASC").
+		All(&list)
+	return list, err
+}
+
+// UpdateMilestoneByStruct will update a milestone by ID
+func (r *repository) UpdateMilestoneByStruct(ctx context.Context, m *entity.Milestone) error {
+	ctx, span := tracing.Start(ctx, "milestone.repository.UpdateMilestoneByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Update("project_milestones", dbx.Params{
+			"owner_id":       m.OwnerID,
+			"title":          m.Title,
+			"description":    m.Description,
+			"due_date":       m.DueDate,
+			"completed":      m.Completed,
+			"date_completed": m.DateCompleted,
+		}, dbx.HashExp{"id": m.ID}).Execute()
+		return err
+	})
+}
+
+// DeleteMilestoneByID will delete a milestone by ID
+func (r *repository) DeleteMilestoneByID(ctx context.Context, milestoneID int) error {
+	ctx, span := tracing.Start(ctx, "milestone.repository.DeleteMilestoneByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("project_milestones", dbx.HashExp{"id": milestoneID}).Execute()
+		return err
+	})
+}
@@ -0,0 +1,93 @@
+package milestone
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// ErrInvalidMilestone is returned when a milestone fails validation
+var ErrInvalidMilestone = apperror.New(apperror.KindValidation, "invalid milestone")
+
+type Service interface {
+	InsertMilestoneByStruct(ctx context.Context, m *Milestone) error
+	GetMilestoneByID(ctx context.Context, milestoneID int) (*Milestone, error)
+	GetMilestonesByProjectID(ctx context.Context, projectID int) ([]Milestone, error)
+	UpdateMilestoneByStruct(ctx context.Context, m *Milestone) error
+	DeleteMilestoneByID(ctx context.Context, milestoneID int) error
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Milestone struct {
+	entity.Milestone
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// validate checks required fields and keeps the completion date in step with the completed flag
+func (m *Milestone) validate() error {
+	m.Title = strings.TrimSpace(m.Title)
+	if m.Title == "" {
+		return ErrInvalidMilestone.WithField("title", "title is required")
+	}
+	if m.DueDate.IsZero() {
+		return ErrInvalidMilestone.WithField("dueDate", "dueDate is required")
+	}
+	if m.Completed && m.DateCompleted == nil {
+		now := time.Now()
+		m.DateCompleted = &now
+	}
+	if !m.Completed {
+		m.DateCompleted = nil
+	}
+	return nil
+}
+
+func (s *service) InsertMilestoneByStruct(ctx context.Context, m *Milestone) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return s.repo.InsertMilestoneByStruct(ctx, &m.Milestone)
+}
+
+func (s *service) GetMilestoneByID(ctx context.Context, milestoneID int) (*Milestone, error) {
+	m, err := s.repo.GetMilestoneByID(ctx, milestoneID)
+	if err != nil {
+		return &Milestone{}, err
+	}
+	return &Milestone{*m}, nil
+}
+
+func (s *service) GetMilestonesByProjectID(ctx context.Context, projectID int) ([]Milestone, error) {
+	milestones, err := s.repo.GetMilestonesByProjectID(ctx, projectID)
+	if err != nil {
+		return []Milestone{}, err
+	}
+	results := []Milestone{}
+	for _, m := range milestones {
+		results = append(results, Milestone{m})
+	}
+	return results, nil
+}
+
+func (s *service) UpdateMilestoneByStruct(ctx context.Context, m *Milestone) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	return s.repo.UpdateMilestoneByStruct(ctx, &m.Milestone)
+}
+
+func (s *service) DeleteMilestoneByID(ctx context.Context, milestoneID int) error {
+	return s.repo.DeleteMilestoneByID(ctx, milestoneID)
+}
@@ -0,0 +1,94 @@
+package milestone
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// fakeRepository records the milestones it is asked to save
+type fakeRepository struct {
+	Repository
+	saved []entity.Milestone
+}
+
+func (f *fakeRepository) InsertMilestoneByStruct(ctx context.Context, m *entity.Milestone) error {
+	f.saved = append(f.saved, *m)
+	return nil
+}
+
+func (f *fakeRepository) UpdateMilestoneByStruct(ctx context.Context, m *entity.Milestone) error {
+	f.saved = append(f.saved, *m)
+	return nil
+}
+
+func TestInsertMilestoneValidates(t *testing.T) {
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		milestone entity.Milestone
+		wantErr   bool
+	}{
+		{"valid", entity.Milestone{Title: " Go live ", DueDate: due}, false},
+		{"blank title", entity.Milestone{Title: "  ", DueDate: due}, true},
+		{"missing due date", entity.Milestone{Title: "Go live"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			m := &Milestone{tt.milestone}
+			err := NewService(repo, logger.New().Prefix("Milestone Test")).InsertMilestoneByStruct(context.Background(), m)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMilestone) || len(repo.saved) != 0 {
+					t.Fatalf("err = %v, saved = %d, want ErrInvalidMilestone and nothing saved", err, len(repo.saved))
+				}
+				return
+			}
+			if err != nil || len(repo.saved) != 1 {
+				t.Fatalf("err = %v, saved = %d, want one saved milestone", err, len(repo.saved))
+			}
+			if repo.saved[0].Title != "Go live" {
+				t.Fatalf("title = %q, want it trimmed", repo.saved[0].Title)
+			}
+		})
+	}
+}
+
+func TestUpdateMilestoneTracksCompletionDate(t *testing.T) {
+	repo := &fakeRepository{}
+	s := NewService(repo, logger.New().Prefix("Milestone Test"))
+	due := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// completing a milestone stamps the completion date
+	m := &Milestone{entity.Milestone{ID: 1, Title: "Go live", DueDate: due, Completed: true}}
+	if err := s.UpdateMilestoneByStruct(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if repo.saved[0].DateCompleted == nil {
+		t.Fatal("completed milestone saved without a completion date")
+	}
+
+	// an existing completion date is kept
+	completed := time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC)
+	m = &Milestone{entity.Milestone{ID: 1, Title: "Go live", DueDate: due, Completed: true, DateCompleted: &completed}}
+	if err := s.UpdateMilestoneByStruct(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if !repo.saved[1].DateCompleted.Equal(completed) {
+		t.Fatalf("completion date = %v, want %v", repo.saved[1].DateCompleted, completed)
+	}
+
+	// reopening clears it
+	m = &Milestone{entity.Milestone{ID: 1, Title: "Go live", DueDate: due, DateCompleted: &completed}}
+	if err := s.UpdateMilestoneByStruct(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	if repo.saved[2].DateCompleted != nil {
+		t.Fatalf("reopened milestone kept completion date %v", repo.saved[2].DateCompleted)
+	}
+}
@@ -0,0 +1,94 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+// Handler serves the notification routes through its service
+type Handler struct {
+	service Service
+}
+
+// NewHandler returns a handler serving the notification routes through service
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Routes registers the notification routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/preferences/{consultantID}", h.GetPreferencesByConsultantID)
+	r.Put("/preferences/{consultantID}", h.SetPreferences)
+	r.Get("/consultant/{consultantID}", h.GetNotificationsByConsultantID)
+}
+
+// GetPreferencesByConsultantID returns how a consultant receives every notification event type
+func (h *Handler) GetPreferencesByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	preferences, err := h.service.GetPreferencesByConsultantID(r.Context(), consultantID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(preferences)
+}
+
+// SetPreferences stores the preferences in the request body, a list of eventType and mode
+// (immediate, digest or off), and returns the resulting preferences
+func (h *Handler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	var preferences []Preference
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid notification preferences body"))
+		return
+	}
+
+	service := h.service
+	err = service.SetPreferences(r.Context(), consultantID, preferences)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	h.GetPreferencesByConsultantID(w, r)
+}
+
+// GetNotificationsByConsultantID returns the notifications of a consultant, newest first, capped by limit
+func (h *Handler) GetNotificationsByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	limit := DefaultNotificationLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			problem.Write(w, r, apperror.Invalid("limit", "invalid limit"))
+			return
+		}
+	}
+
+	notifications, err := h.service.GetNotificationsByConsultantID(r.Context(), consultantID, limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(notifications)
+}
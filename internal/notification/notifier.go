@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+)
+
+const (
+	// MaxAttempts before a notification is marked failed
+	MaxAttempts = 5
+	// retryDelay between attempts of a notification that could not be sent
+	retryDelay = 5 * time.Minute
+
+	// pollInterval between detection and send passes
+	pollInterval = 30 * time.Second
+	// batchSize of notifications claimed per query
+	batchSize = 100
+	// lease of claimed notifications, longer than a pass takes to send them
+	lease = 5 * time.Minute
+)
+
+// NotifierConfig configures what is detected and when digests go out
+type NotifierConfig struct {
+	// DigestHour is the hour of the day, server time, digests of the past day are sent at
+	DigestHour int
+	// OnHoldStatus is the status title that puts a project on hold
+	OnHoldStatus string
+	// Types of the time entries counted as budget and as worked hours
+	Types config.TimeEntryTypes
+}
+
+// NewNotifierConfig returns the notifier configuration of the notification settings
+func NewNotifierConfig(cfg config.Notifications, types config.TimeEntryTypes) NotifierConfig {
+	return NotifierConfig{DigestHour: cfg.DigestHour, OnHoldStatus: cfg.OnHoldStatus, Types: types}
+}
+
+// Notifier detects new notifications, sends immediate ones as they come and the others in a daily digest
+type Notifier struct {
+	repo      Repository
+	transport Transport
+	cfg       NotifierConfig
+	logger    *logger.Logger
+}
+
+// NewNotifier returns a notifier sending through transport
+func NewNotifier(repo Repository, transport Transport, cfg NotifierConfig, logger *logger.Logger) *Notifier {
+	return &Notifier{repo, transport, cfg, logger}
+}
+
+// Run notifies until ctx is done
+func (n *Notifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			n.logger.Error("Notification pass failed: " + err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce detects new notifications, sends the immediate ones and, once the digest hour passed,
+// the digests of everything collected before it
+func (n *Notifier) RunOnce(ctx context.Context) error {
+	detected, err := n.repo.DetectNotifications(ctx, detection{
+		DebitType:    n.cfg.Types.Debit,
+		CreditType:   n.cfg.Types.Credit,
+		OnHoldStatus: n.cfg.OnHoldStatus,
+	})
+	if err != nil {
+		return err
+	}
+	if detected > 0 {
+		n.logger.InfoF("Detected %d new notifications", detected)
+	}
+
+	now := time.Now()
+	for {
+		list, err := n.repo.ClaimDueNotifications(ctx, ModeImmediate, now, batchSize, lease)
+		if err != nil {
+			return err
+		}
+		for _, claimed := range list {
+			m, err := render(claimed)
+			n.record(ctx, []claimedNotification{claimed}, m, err)
+		}
+		if len(list) < batchSize {
+			break
+		}
+	}
+
+	cutoff := n.digestCutoff(now)
+	for {
+		list, err := n.repo.ClaimDueNotifications(ctx, ModeDigest, cutoff, batchSize, lease)
+		if err != nil {
+			return err
+		}
+		// claims are ordered by consultant, one digest per consultant in the batch
+		for start := 0; start < len(list); {
+			end := start + 1
+			for end < len(list) && list[end].ConsultantID == list[start].ConsultantID {
+				end++
+			}
+			m, err := renderDigest(list[start:end])
+			n.record(ctx, list[start:end], m, err)
+			start = end
+		}
+		if len(list) < batchSize {
+			break
+		}
+	}
+	return nil
+}
+
+// digestCutoff returns the latest digest hour at or before now, digests cover what was collected before it
+func (n *Notifier) digestCutoff(now time.Time) time.Time {
+	cutoff := time.Date(now.Year(), now.Month(), now.Day(), n.cfg.DigestHour, 0, 0, 0, now.Location())
+	if cutoff.After(now) {
+		cutoff = cutoff.AddDate(0, 0, -1)
+	}
+	return cutoff
+}
+
+// record sends a rendered message and stores the outcome for the notifications it carries. Render
+// errors fail the notifications right away, send errors are retried up to MaxAttempts.
+func (n *Notifier) record(ctx context.Context, list []claimedNotification, m Message, renderErr error) {
+	ids := make([]int, len(list))
+	attempts := 0
+	for i, claimed := range list {
+		ids[i] = claimed.ID
+		attempts = max(attempts, claimed.Attempts+1)
+	}
+
+	var result notificationResult
+	switch err := n.send(ctx, m, renderErr); {
+	case err == nil:
+		result = notificationResult{Sent: true}
+	case renderErr != nil || attempts >= MaxAttempts:
+		result = notificationResult{Error: err.Error(), Failed: true}
+	default:
+		result = notificationResult{Error: err.Error(), RetryAt: time.Now().Add(retryDelay)}
+	}
+	if result.Error != "" {
+		n.logger.Warning(fmt.Sprintf("Failed to send notifications %v to %s: %s", ids, list[0].Email, result.Error))
+	}
+	if err := n.repo.RecordNotificationResult(ctx, ids, result); err != nil {
+		n.logger.Error(fmt.Sprintf("Failed to record notifications %v: %s", ids, err.Error()))
+	}
+}
+
+// send sends m unless rendering it failed
+func (n *Notifier) send(ctx context.Context, m Message, renderErr error) error {
+	if renderErr != nil {
+		return renderErr
+	}
+	return n.transport.Send(ctx, m)
+}
@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+)
+
+// fakeRepository hands out its claims per mode once and records the results
+type fakeRepository struct {
+	Repository
+	claims  map[string][]claimedNotification
+	cutoff  time.Time
+	results map[int]notificationResult
+}
+
+func (f *fakeRepository) DetectNotifications(ctx context.Context, d detection) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeRepository) ClaimDueNotifications(ctx context.Context, mode string, createdBefore time.Time, limit int, lease time.Duration) ([]claimedNotification, error) {
+	if mode == ModeDigest {
+		f.cutoff = createdBefore
+	}
+	list := f.claims[mode]
+	f.claims[mode] = nil
+	return list, nil
+}
+
+func (f *fakeRepository) RecordNotificationResult(ctx context.Context, notificationIDs []int, result notificationResult) error {
+	for _, id := range notificationIDs {
+		f.results[id] = result
+	}
+	return nil
+}
+
+// fakeTransport records the messages sent, failing every send while err is set
+type fakeTransport struct {
+	mu   sync.Mutex
+	sent []Message
+	err  error
+}
+
+func (f *fakeTransport) Send(ctx context.Context, m Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+// Runs one notifier pass over the claims
+func runOnce(t *testing.T, transport *fakeTransport, claims map[string][]claimedNotification) *fakeRepository {
+	t.Helper()
+	repo := &fakeRepository{claims: claims, results: map[int]notificationResult{}}
+	n := NewNotifier(repo, transport, NotifierConfig{DigestHour: 8, OnHoldStatus: "on-hold"}, logger.New().Prefix("Notification Test"))
+	if err := n.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+const overBudget = `{"number": "P-1", "name": "Apollo", "credit": 120.5, "debit": 100}`
+
+func TestNotifierSendsImmediateNotifications(t *testing.T) {
+	transport := &fakeTransport{}
+	repo := runOnce(t, transport, map[string][]claimedNotification{
+		ModeImmediate: {{ID: 1, ConsultantID: 1, Email: "ada@example.com", FirstName: "Ada", EventType: EventOverBudget, Data: overBudget}},
+	})
+
+	if len(transport.sent) != 1 || !repo.results[1].Sent {
+		t.Fatalf("sent %d, result %+v, want one sent notification", len(transport.sent), repo.results[1])
+	}
+	m := transport.sent[0]
+	if m.To != "ada@example.com" || m.Subject != "Project P-1 is over budget" || !strings.Contains(m.Text, "Credit hours logged: 120.50") {
+		t.Fatalf("message = %+v", m)
+	}
+}
+
+func TestNotifierSendsOneDigestPerConsultant(t *testing.T) {
+	transport := &fakeTransport{}
+	repo := runOnce(t, transport, map[string][]claimedNotification{
+		ModeDigest: {
+			{ID: 1, ConsultantID: 1, Email: "ada@example.com", FirstName: "Ada", EventType: EventOverBudget, Data: overBudget},
+			{ID: 2, ConsultantID: 1, Email: "ada@example.com", FirstName: "Ada", EventType: EventOnHold, Data: `{"number": "P-2", "name": "Gemini", "since": "2026-10-01T09:00:00Z"}`},
+			{ID: 3, ConsultantID: 2, Email: "alan@example.com", FirstName: "Alan", EventType: EventOverBudget, Data: overBudget},
+		},
+	})
+
+	if len(transport.sent) != 2 {
+		t.Fatalf("sent %d digests, want 2", len(transport.sent))
+	}
+	ada := transport.sent[0]
+	if ada.To != "ada@example.com" || ada.Subject != "Your daily digest: 2 notifications" || !strings.Contains(ada.Text, "P-2 Gemini was put on hold on 2026-10-01") {
+		t.Fatalf("digest = %+v", ada)
+	}
+	for id := 1; id <= 3; id++ {
+		if !repo.results[id].Sent {
+			t.Fatalf("notification %d: result = %+v, want sent", id, repo.results[id])
+		}
+	}
+	// digests cover what was collected before the last digest hour
+	if repo.cutoff.Hour() != 8 || repo.cutoff.After(time.Now()) {
+		t.Fatalf("digest cutoff = %s, want the last 08:00", repo.cutoff)
+	}
+}
+
+func TestNotifierRetriesAndFails(t *testing.T) {
+	transport := &fakeTransport{err: errors.New("connection refused")}
+	repo := runOnce(t, transport, map[string][]claimedNotification{
+		ModeImmediate: {
+			{ID: 1, EventType: EventOverBudget, Data: overBudget, Attempts: 0},
+			{ID: 2, EventType: EventOverBudget, Data: overBudget, Attempts: MaxAttempts - 1},
+			{ID: 3, EventType: EventOverBudget, Data: `{"number":`}, // undecodable data is never retried
+		},
+	})
+
+	if r := repo.results[1]; r.Sent || r.Failed || r.RetryAt.IsZero() || r.Error != "connection refused" {
+		t.Fatalf("first attempt: result = %+v, want a retry", r)
+	}
+	if r := repo.results[2]; !r.Failed || !r.RetryAt.IsZero() {
+		t.Fatalf("last attempt: result = %+v, want failed", r)
+	}
+	if r := repo.results[3]; !r.Failed {
+		t.Fatalf("undecodable: result = %+v, want failed", r)
+	}
+}
+
+func TestDigestCutoff(t *testing.T) {
+	n := &Notifier{cfg: NotifierConfig{DigestHour: 8}}
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	for now, want := range map[time.Time]time.Time{
+		day.Add(7 * time.Hour):  day.Add(-16 * time.Hour), // before the digest hour, yesterday's
+		day.Add(8 * time.Hour):  day.Add(8 * time.Hour),
+		day.Add(23 * time.Hour): day.Add(8 * time.Hour),
+	} {
+		if got := n.digestCutoff(now); !got.Equal(want) {
+			t.Errorf("digestCutoff(%s) = %s, want %s", now, got, want)
+		}
+	}
+}
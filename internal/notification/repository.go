@@ -0,0 +1,272 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/leave"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+// Notification statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// detection is what the detection queries match on
+type detection struct {
+	DebitType    string
+	CreditType   string
+	OnHoldStatus string
+}
+
+// claimedNotification is a due notification leased to the notifier with its recipient and data
+type claimedNotification struct {
+	ID           int
+	ConsultantID int
+	Email        string
+	FirstName    string
+	EventType    string
+	Data         string
+	Attempts     int
+}
+
+// notificationResult is the outcome of one send, a notification neither sent nor failed is retried
+// from RetryAt
+type notificationResult struct {
+	Error   string
+	Sent    bool
+	Failed  bool
+	RetryAt time.Time
+}
+
+type Repository interface {
+	GetPreferencesByConsultantID(ctx context.Context, consultantID int) ([]entity.NotificationPreference, error)
+	UpsertPreference(ctx context.Context, p *entity.NotificationPreference) error
+	GetNotificationsByConsultantID(ctx context.Context, consultantID int, limit int) ([]entity.Notification, error)
+	DetectNotifications(ctx context.Context, d detection) (int64, error)
+	ClaimDueNotifications(ctx context.Context, mode string, createdBefore time.Time, limit int, lease time.Duration) ([]claimedNotification, error)
+	RecordNotificationResult(ctx context.Context, notificationIDs []int, result notificationResult) error
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	logger    *logger.Logger
+}
+
+func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository {
+	return &repository{dbContext, _l}
+}
+
+// GetPreferencesByConsultantID will return the stored preferences of a consultant, event types
+// without a row are delivered immediately
+func (r *repository) GetPreferencesByConsultantID(ctx context.Context, consultantID int) ([]entity.NotificationPreference, error) {
+	ctx, span := tracing.Start(ctx, "notification.repository.GetPreferencesByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.NotificationPreference
+	err := r.dbContext.Get().WithContext(ctx).Select().
+		From("notification_preferences").
+		Where(dbx.HashExp{"consultant_id": consultantID}).
+		OrderBy("event_type ASC").
+		All(&list)
+	return list, err
+}
+
+// UpsertPreference will insert or replace the preference of a consultant for an event type
+func (r *repository) UpsertPreference(ctx context.Context, p *entity.NotificationPreference) error {
+	ctx, span := tracing.Start(ctx, "notification.repository.UpsertPreference")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.NewQuery(`INSERT INTO notification_preferences (consultant_id, event_type, mode)
+			VALUES ({:consultant_id}, {:event_type}, {:mode})
+			ON CONFLICT (consultant_id, event_type) DO UPDATE SET mode = EXCLUDED.mode`).
+			Bind(dbx.Params{"consultant_id": p.ConsultantID, "event_type": p.EventType, "mode": p.Mode}).
+			Execute()
+		return err
+	})
+}
+
+// GetNotificationsByConsultantID will return the latest notifications of a consultant
+func (r *repository) GetNotificationsByConsultantID(ctx context.Context, consultantID int, limit int) ([]entity.Notification, error) {
+	ctx, span := tracing.Start(ctx, "notification.repository.GetNotificationsByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.Notification
+	err := r.dbContext.Get().WithContext(ctx).
+		Select("id", "consultant_id", "event_type", "project_id", "data", "mode", "status", "attempts",
+			"last_error", "date_created", "date_sent").
+		From("notifications").
+		Where(dbx.HashExp{"consultant_id": consultantID}).
+		OrderBy("id DESC").
+		Limit(int64(limit)).
+		All(&list)
+	return list, err
+}
+
+// recipientSQL resolves the delivery mode of the recipient of a candidate, no preference means immediate
+const recipientSQL = `COALESCE(pref.mode, 'immediate')`
+
+// insertCandidatesSQL wraps a candidates query (consultant_id, event_type, project_id, dedupe_key, data)
+// into an insert of the occurrences not seen yet, skipped when the recipient turned the event type off
+const insertCandidatesSQL = `INSERT INTO notifications (consultant_id, event_type, project_id, dedupe_key, data, mode, status)
+	SELECT c.consultant_id, c.event_type, c.project_id, c.dedupe_key, c.data, ` + recipientSQL + `,
+		CASE WHEN ` + recipientSQL + ` = 'off' THEN 'skipped' ELSE 'pending' END
+	FROM candidates c
+	LEFT JOIN notification_preferences pref
+		ON pref.consultant_id = c.consultant_id AND pref.event_type = c.event_type
+	ON CONFLICT (consultant_id, dedupe_key) DO NOTHING`
+
+// DetectNotifications will create a notification for every new occurrence and return how many were
+// created. It is idempotent, an occurrence is identified by its dedupe key:
+//   - project.over_budget once per project whose credit hours exceed its debit hours, to its manager
+//   - project.on_hold once per latest on-hold status of a project, to its manager
+//   - approval.pending once per pending leave request, to every approver but the requester
+func (r *repository) DetectNotifications(ctx context.Context, d detection) (int64, error) {
+	ctx, span := tracing.Start(ctx, "notification.repository.DetectNotifications")
+	defer span.End()
+
+	queries := []string{
+		`WITH candidates AS (
+			SELECT p.manager_id AS consultant_id, 'project.over_budget' AS event_type, p.id AS project_id,
+				'project.over_budget:' || p.id AS dedupe_key,
+				jsonb_build_object('projectID', p.id, 'number', p.number, 'name', p.name,
+					'debit', h.debit, 'credit', h.credit) AS data
+			FROM projects p
+			JOIN (
+				SELECT project_id,
+					COALESCE(SUM(hours) FILTER (WHERE type = {:debit_type}), 0) AS debit,
+					COALESCE(SUM(hours) FILTER (WHERE type = {:credit_type}), 0) AS credit
+				FROM project_time_entries
+				GROUP BY project_id
+			) h ON h.project_id = p.id
+			WHERE p.manager_id IS NOT NULL AND h.credit > h.debit
+		) ` + insertCandidatesSQL,
+
+		`WITH latest AS (
+			SELECT DISTINCT ON (project_id) id, project_id, title, description, date_created
+			FROM project_statuses
+			ORDER BY project_id, date_created DESC, id DESC
+		),
+		candidates AS (
+			SELECT p.manager_id AS consultant_id, 'project.on_hold' AS event_type, p.id AS project_id,
+				'project.on_hold:' || l.id AS dedupe_key,
+				jsonb_build_object('projectID', p.id, 'number', p.number, 'name', p.name,
+					'description', COALESCE(l.description, ''), 'since', l.date_created) AS data
+			FROM latest l
+			JOIN projects p ON p.id = l.project_id
+			WHERE p.manager_id IS NOT NULL AND l.title = {:on_hold}
+		) ` + insertCandidatesSQL,
+
+		`WITH candidates AS (
+			SELECT DISTINCT approver.consultant_id, 'approval.pending' AS event_type, NULL::INTEGER AS project_id,
+				'approval.pending:leave:' || l.id AS dedupe_key,
+				jsonb_build_object('leaveID', l.id, 'consultant', c.first_name || ' ' || c.last_name,
+					'type', l.type, 'startDate', l.start_date, 'endDate', l.end_date, 'note', l.note) AS data
+			FROM consultant_leaves l
+			JOIN consultants c ON c.id = l.consultant_id
+			JOIN consultant_roles approver
+				ON approver.role = ANY({:approver_roles}) AND approver.consultant_id <> l.consultant_id
+			WHERE l.status = 'pending'
+		) ` + insertCandidatesSQL,
+	}
+
+	var created int64
+	err := r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		params := dbx.Params{
+			"debit_type":     d.DebitType,
+			"credit_type":    d.CreditType,
+			"on_hold":        d.OnHoldStatus,
+			"approver_roles": pq.StringArray(leave.ApproverRoles),
+		}
+		for _, q := range queries {
+			result, err := tx.NewQuery(q).Bind(params).Execute()
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			created += n
+		}
+		return nil
+	})
+	return created, err
+}
+
+// ClaimDueNotifications will lease pending notifications of a mode created before createdBefore and
+// return them with their recipient. A notification whose lease expires, e.g. after a crash, is claimed again.
+func (r *repository) ClaimDueNotifications(ctx context.Context, mode string, createdBefore time.Time, limit int, lease time.Duration) ([]claimedNotification, error) {
+	ctx, span := tracing.Start(ctx, "notification.repository.ClaimDueNotifications")
+	defer span.End()
+
+	var list []claimedNotification
+	err := r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`UPDATE notifications n
+			SET locked_until = NOW() + make_interval(secs => {:lease_seconds})
+			FROM consultants c
+			WHERE c.id = n.consultant_id
+				AND n.id IN (
+					SELECT id FROM notifications
+					WHERE status = 'pending' AND mode = {:mode} AND date_created < {:created_before}
+						AND (locked_until IS NULL OR locked_until < NOW())
+					ORDER BY consultant_id, id
+					LIMIT {:limit}
+					FOR UPDATE SKIP LOCKED
+				)
+			RETURNING n.id, n.consultant_id, c.email, c.first_name, n.event_type, n.data::text AS data, n.attempts`).
+			Bind(dbx.Params{
+				"mode":           mode,
+				"created_before": createdBefore,
+				"limit":          limit,
+				"lease_seconds":  lease.Seconds(),
+			}).
+			All(&list)
+	})
+	return list, err
+}
+
+// RecordNotificationResult will store the outcome of a send for the notifications in it, releasing
+// their lease or extending it until the retry
+func (r *repository) RecordNotificationResult(ctx context.Context, notificationIDs []int, result notificationResult) error {
+	ctx, span := tracing.Start(ctx, "notification.repository.RecordNotificationResult")
+	defer span.End()
+
+	params := dbx.Params{
+		"attempts":     dbx.NewExp("attempts + 1"),
+		"last_error":   result.Error,
+		"locked_until": nil,
+	}
+	switch {
+	case result.Sent:
+		params["status"] = StatusSent
+		params["date_sent"] = time.Now()
+	case result.Failed:
+		params["status"] = StatusFailed
+	default:
+		params["locked_until"] = result.RetryAt
+	}
+
+	ids := make(pq.Int64Array, len(notificationIDs))
+	for i, id := range notificationIDs {
+		ids[i] = int64(id)
+	}
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Update("notifications", params, dbx.NewExp("id = ANY({:ids})", dbx.Params{"ids": ids})).Execute()
+		return err
+	})
+}
@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// Notification event types
+const (
+	EventOverBudget      = "project.over_budget"
+	EventOnHold          = "project.on_hold"
+	EventApprovalPending = "approval.pending"
+)
+
+// Delivery modes of a preference, immediate is the default
+const (
+	ModeImmediate = "immediate"
+	ModeDigest    = "digest"
+	ModeOff       = "off"
+)
+
+const (
+	// DefaultNotificationLimit and MaxNotificationLimit bound the notification history
+	DefaultNotificationLimit = 50
+	MaxNotificationLimit     = 500
+)
+
+var (
+	// ErrInvalidPreference is returned when a preference fails validation
+	ErrInvalidPreference = apperror.New(apperror.KindValidation, "invalid notification preference")
+
+	// EventTypes lists every notification event type
+	EventTypes = []string{EventOverBudget, EventOnHold, EventApprovalPending}
+)
+
+type Service interface {
+	GetPreferencesByConsultantID(ctx context.Context, consultantID int) ([]Preference, error)
+	SetPreferences(ctx context.Context, consultantID int, preferences []Preference) error
+	GetNotificationsByConsultantID(ctx context.Context, consultantID int, limit int) ([]entity.Notification, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Preference struct {
+	entity.NotificationPreference
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// validate checks the event type and mode of a preference
+func (p *Preference) validate() error {
+	if !slices.Contains(EventTypes, p.EventType) {
+		return ErrInvalidPreference.WithField("eventType", fmt.Sprintf("unknown event type %q", p.EventType))
+	}
+	switch p.Mode {
+	case ModeImmediate, ModeDigest, ModeOff:
+		return nil
+	default:
+		return ErrInvalidPreference.WithField("mode", fmt.Sprintf("mode must be %s, %s or %s", ModeImmediate, ModeDigest, ModeOff))
+	}
+}
+
+// GetPreferencesByConsultantID returns the preference of a consultant for every event type,
+// immediate where nothing is stored
+func (s *service) GetPreferencesByConsultantID(ctx context.Context, consultantID int) ([]Preference, error) {
+	stored, err := s.repo.GetPreferencesByConsultantID(ctx, consultantID)
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]Preference, 0, len(EventTypes))
+	for _, eventType := range EventTypes {
+		p := Preference{entity.NotificationPreference{ConsultantID: consultantID, EventType: eventType, Mode: ModeImmediate}}
+		for _, sp := range stored {
+			if sp.EventType == eventType {
+				p.Mode = sp.Mode
+			}
+		}
+		preferences = append(preferences, p)
+	}
+	return preferences, nil
+}
+
+// SetPreferences validates and stores preferences of a consultant, event types left out keep theirs
+func (s *service) SetPreferences(ctx context.Context, consultantID int, preferences []Preference) error {
+	for i := range preferences {
+		preferences[i].ConsultantID = consultantID
+		if err := preferences[i].validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range preferences {
+		if err := s.repo.UpsertPreference(ctx, &p.NotificationPreference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotificationsByConsultantID returns the latest notifications of a consultant, limit defaults
+// to DefaultNotificationLimit and is capped at MaxNotificationLimit
+func (s *service) GetNotificationsByConsultantID(ctx context.Context, consultantID int, limit int) ([]entity.Notification, error) {
+	if limit <= 0 {
+		limit = DefaultNotificationLimit
+	}
+	return s.repo.GetNotificationsByConsultantID(ctx, consultantID, min(limit, MaxNotificationLimit))
+}
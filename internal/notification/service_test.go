@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// preferenceRepository stores the preferences of one consultant
+type preferenceRepository struct {
+	Repository
+	stored []entity.NotificationPreference
+	limit  int
+}
+
+func (p *preferenceRepository) GetPreferencesByConsultantID(ctx context.Context, consultantID int) ([]entity.NotificationPreference, error) {
+	return p.stored, nil
+}
+
+func (p *preferenceRepository) UpsertPreference(ctx context.Context, preference *entity.NotificationPreference) error {
+	p.stored = append(p.stored, *preference)
+	return nil
+}
+
+func (p *preferenceRepository) GetNotificationsByConsultantID(ctx context.Context, consultantID int, limit int) ([]entity.Notification, error) {
+	p.limit = limit
+	return []entity.Notification{}, nil
+}
+
+func TestPreferences(t *testing.T) {
+	repo := &preferenceRepository{}
+	s := NewService(repo, logger.New().Prefix("Notification Test"))
+
+	err := s.SetPreferences(context.Background(), 4, []Preference{{entity.NotificationPreference{EventType: EventOnHold, Mode: ModeDigest}}})
+	if err != nil || len(repo.stored) != 1 || repo.stored[0].ConsultantID != 4 {
+		t.Fatalf("err = %v, stored %+v", err, repo.stored)
+	}
+
+	// every event type is listed, immediate unless stored otherwise
+	preferences, err := s.GetPreferencesByConsultantID(context.Background(), 4)
+	if err != nil || len(preferences) != len(EventTypes) {
+		t.Fatalf("preferences = %+v, err = %v", preferences, err)
+	}
+	for _, p := range preferences {
+		want := ModeImmediate
+		if p.EventType == EventOnHold {
+			want = ModeDigest
+		}
+		if p.Mode != want {
+			t.Errorf("%s: mode = %s, want %s", p.EventType, p.Mode, want)
+		}
+	}
+
+	// nothing is stored when any preference is invalid
+	for _, invalid := range []entity.NotificationPreference{{EventType: "project.created", Mode: ModeOff}, {EventType: EventOnHold, Mode: "weekly"}} {
+		repo.stored = nil
+		valid := Preference{entity.NotificationPreference{EventType: EventOverBudget, Mode: ModeOff}}
+		if err := s.SetPreferences(context.Background(), 4, []Preference{valid, {invalid}}); !errors.Is(err, ErrInvalidPreference) || len(repo.stored) != 0 {
+			t.Errorf("%+v: err = %v, stored %d", invalid, err, len(repo.stored))
+		}
+	}
+}
+
+func TestGetNotificationsLimit(t *testing.T) {
+	for limit, want := range map[int]int{0: DefaultNotificationLimit, 20: 20, MaxNotificationLimit + 1: MaxNotificationLimit} {
+		repo := &preferenceRepository{}
+		if _, err := NewService(repo, logger.New().Prefix("Notification Test")).GetNotificationsByConsultantID(context.Background(), 1, limit); err != nil || repo.limit != want {
+			t.Errorf("limit %d: queried %d, err = %v, want %d", limit, repo.limit, err, want)
+		}
+	}
+}
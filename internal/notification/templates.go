@@ -0,0 +1,118 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templateFuncs format the raw JSON values of event data
+var templateFuncs = map[string]any{
+	// date keeps the day of a JSON timestamp
+	"date": func(v any) string {
+		s := fmt.Sprint(v)
+		if len(s) > 10 {
+			return s[:10]
+		}
+		return s
+	},
+	// hours formats a number of hours
+	"hours": func(v any) string {
+		if f, ok := v.(float64); ok {
+			return fmt.Sprintf("%.2f", f)
+		}
+		return fmt.Sprint(v)
+	},
+}
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.New("email.txt.tmpl").Funcs(templateFuncs).ParseFS(templateFiles, "templates/email.txt.tmpl"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("email.html.tmpl").Funcs(templateFuncs).ParseFS(templateFiles, "templates/email.html.tmpl"))
+)
+
+// templateData is what the event templates are executed with
+type templateData struct {
+	Recipient string
+	Event     map[string]any
+}
+
+// digestData is what the digest templates are executed with, Items are the event summaries
+type digestData struct {
+	Recipient string
+	Items     []string
+}
+
+// execute runs the text templates name.subject and name.text and the html template name.html
+func execute(name string, data any) (subject, text, html string, err error) {
+	var b strings.Builder
+	if err = textTemplates.ExecuteTemplate(&b, name+".subject", data); err != nil {
+		return
+	}
+	subject = strings.TrimSpace(b.String())
+
+	b.Reset()
+	if err = textTemplates.ExecuteTemplate(&b, name+".text", data); err != nil {
+		return
+	}
+	text = b.String()
+
+	var h bytes.Buffer
+	if err = htmlTemplates.ExecuteTemplate(&h, name+".html", data); err != nil {
+		return
+	}
+	return subject, text, h.String(), nil
+}
+
+// decodeEvent decodes the JSON data of a notification for its templates
+func decodeEvent(n claimedNotification) (templateData, error) {
+	data := templateData{Recipient: n.FirstName}
+	if err := json.Unmarshal([]byte(n.Data), &data.Event); err != nil {
+		return data, fmt.Errorf("decoding notification %d: %w", n.ID, err)
+	}
+	return data, nil
+}
+
+// render builds the message of one notification to its recipient
+func render(n claimedNotification) (Message, error) {
+	data, err := decodeEvent(n)
+	if err != nil {
+		return Message{}, err
+	}
+	subject, text, html, err := execute(n.EventType, data)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{To: n.Email, Subject: subject, Text: text, HTML: html}, nil
+}
+
+// renderDigest builds one message summarizing notifications of the same recipient
+func renderDigest(list []claimedNotification) (Message, error) {
+	if len(list) == 0 {
+		return Message{}, fmt.Errorf("empty digest")
+	}
+	digest := digestData{Recipient: list[0].FirstName}
+	for _, n := range list {
+		data, err := decodeEvent(n)
+		if err != nil {
+			return Message{}, err
+		}
+		var b strings.Builder
+		if err := textTemplates.ExecuteTemplate(&b, n.EventType+".summary", data); err != nil {
+			return Message{}, err
+		}
+		digest.Items = append(digest.Items, b.String())
+	}
+
+	subject, text, html, err := execute("digest", digest)
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{To: list[0].Email, Subject: subject, Text: text, HTML: html}, nil
+}
@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderEveryEventType(t *testing.T) {
+	data := map[string]string{
+		EventOverBudget:      overBudget,
+		EventOnHold:          `{"number": "P-2", "name": "Gemini", "since": "2026-10-01T09:00:00Z", "description": "Waiting on the client"}`,
+		EventApprovalPending: `{"consultant": "Alan Turing", "type": "vacation", "startDate": "2026-12-21T00:00:00Z", "endDate": "2026-12-24T00:00:00Z"}`,
+	}
+	for _, eventType := range EventTypes {
+		m, err := render(claimedNotification{ID: 1, Email: "ada@example.com", FirstName: "Ada", EventType: eventType, Data: data[eventType]})
+		if err != nil {
+			t.Fatalf("%s: %v", eventType, err)
+		}
+		if m.Subject == "" || !strings.HasPrefix(m.Text, "Hi Ada,") || !strings.Contains(m.HTML, "Ada") || strings.Contains(m.Text, "<no value>") {
+			t.Fatalf("%s: message = %+v", eventType, m)
+		}
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	m, err := render(claimedNotification{
+		EventType: EventOverBudget,
+		FirstName: "Ada",
+		Data:      `{"number": "P-1", "name": "<script>alert(1)</script>", "credit": 2, "debit": 1}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(m.HTML, "<script>") || !strings.Contains(m.HTML, "&lt;script&gt;") {
+		t.Fatalf("html = %s, want the project name escaped", m.HTML)
+	}
+}
+
+func TestRenderDigestRejectsEmptyDigest(t *testing.T) {
+	if _, err := renderDigest(nil); err == nil {
+		t.Fatal("empty digest rendered")
+	}
+}
@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+)
+
+// Message is a rendered email
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Transport sends rendered emails
+type Transport interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// NewTransport returns the transport selected by cfg:
+//   - smtp sends through the smtp host as its from address, authenticating when a username is set
+//   - file appends every message to the notifications file
+//   - log writes every message to the logger
+func NewTransport(cfg config.Notifications, l *logger.Logger) (Transport, error) {
+	switch cfg.Transport {
+	case "smtp":
+		if cfg.SMTP.Host == "" || cfg.SMTP.From == "" {
+			return nil, fmt.Errorf("smtp host and from are required by the smtp transport")
+		}
+		addr := net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(cfg.SMTP.Port))
+		return NewSMTPTransport(addr, cfg.SMTP.From, cfg.SMTP.Username, cfg.SMTP.Password), nil
+	case "file":
+		return NewFileTransport(cfg.File, cfg.SMTP.From), nil
+	case "log":
+		return NewLogTransport(l), nil
+	default:
+		return nil, fmt.Errorf("unknown notification transport %q, expected smtp, file or log", cfg.Transport)
+	}
+}
+
+// SMTPTransport sends through an SMTP server, upgrading to TLS when the server offers STARTTLS
+type SMTPTransport struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPTransport returns a transport sending through addr as from, with PLAIN authentication
+// when username is set
+func NewSMTPTransport(addr, from, username, password string) *SMTPTransport {
+	t := &SMTPTransport{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		t.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return t
+}
+
+// Send sends one message
+func (t *SMTPTransport) Send(ctx context.Context, m Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := buildMIME(t.from, m, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(t.addr, t.auth, t.from, []string{m.To}, body)
+}
+
+// FileTransport appends every message to a file, for development and tests
+type FileTransport struct {
+	path string
+	from string
+	mu   sync.Mutex
+}
+
+// NewFileTransport returns a transport appending to path
+func NewFileTransport(path, from string) *FileTransport {
+	if from == "" {
+		from = "notifications@localhost"
+	}
+	return &FileTransport{path: path, from: from}
+}
+
+// Send appends one message followed by a blank line
+func (t *FileTransport) Send(_ context.Context, m Message) error {
+	body, err := buildMIME(t.from, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(body, "\r\n"...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LogTransport writes every message to a logger instead of sending it
+type LogTransport struct {
+	logger *logger.Logger
+}
+
+// NewLogTransport returns a transport writing to l
+func NewLogTransport(l *logger.Logger) *LogTransport {
+	return &LogTransport{l}
+}
+
+// Send logs the recipient, subject and text body of one message
+func (t *LogTransport) Send(_ context.Context, m Message) error {
+	t.logger.InfoF("Email to %s: %s\n%s", m.To, m.Subject, m.Text)
+	return nil
+}
+
+// buildMIME encodes a message as a multipart/alternative email with a text and an html part
+func buildMIME(from string, m Message, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", m.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"context"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+)
+
+func TestBuildMIME(t *testing.T) {
+	body, err := buildMIME("noreply@example.com", Message{To: "ada@example.com", Subject: "Projekt über Budget", Text: "plain", HTML: "<p>html</p>"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Projekt über Budget" || msg.Header.Get("To") != "ada@example.com" {
+		t.Fatalf("headers = %v", msg.Header)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q", msg.Header.Get("Content-Type"))
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{"text/plain; charset=utf-8", "text/html; charset=utf-8"} {
+		part, err := parts.NextPart()
+		if err != nil || part.Header.Get("Content-Type") != want {
+			t.Fatalf("part = %v, err = %v, want %s", part, err, want)
+		}
+	}
+}
+
+func TestFileTransportAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.eml")
+	transport := NewFileTransport(path, "")
+	for _, to := range []string{"ada@example.com", "alan@example.com"} {
+		if err := transport.Send(context.Background(), Message{To: to, Subject: "Hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), "From: notifications@localhost") != 2 || !strings.Contains(string(b), "To: alan@example.com") {
+		t.Fatalf("file = %s, want both messages", b)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	l := logger.New().Prefix("Notification Test")
+	cfg := config.Default().Notifications
+
+	if transport, err := NewTransport(cfg, l); err != nil {
+		t.Fatal(err)
+	} else if _, ok := transport.(*LogTransport); !ok {
+		t.Fatalf("transport = %T, want the log transport by default", transport)
+	}
+
+	cfg.Transport = "smtp"
+	if _, err := NewTransport(cfg, l); err == nil {
+		t.Fatal("smtp transport without a host")
+	}
+	cfg.SMTP.Host, cfg.SMTP.From = "mail.example.com", "noreply@example.com"
+	if transport, err := NewTransport(cfg, l); err != nil || transport.(*SMTPTransport).addr != "mail.example.com:587" {
+		t.Fatalf("transport = %+v, err = %v, want port 587 by default", transport, err)
+	}
+
+	cfg.Transport = "pigeon"
+	if _, err := NewTransport(cfg, l); err == nil {
+		t.Fatal("unknown transport accepted")
+	}
+}
@@ -0,0 +1,42 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// CorrelationID identifies every request by its X-Request-ID header, or a new id without one, and
+// returns the id in the X-Request-ID response header so clients can report it
+func CorrelationID(next http.Handler) http.Handler {
+	return middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// Recoverer answers requests whose handler panicked with an internal error problem, the panic and
+// its stack are logged with the correlation id and the process keeps serving
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// aborting handlers panic on purpose, the server handles it
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			p := forRequest(New(fmt.Errorf("panic: %v", rec)), r)
+			problemLogger.Error(fmt.Sprintf("%s %s [%s]: panic: %v\n%s", r.Method, r.URL.Path, p.CorrelationID, rec, debug.Stack()))
+			// upgraded connections were hijacked and cannot be answered
+			if r.Header.Get("Connection") != "Upgrade" {
+				respond(w, p)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,28 @@
+package problem
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+)
+
+// IntParam returns the integer url param name of r, writing a bad request problem when it is missing
+// or not an integer
+func IntParam(w http.ResponseWriter, r *http.Request, name string) (int, error) {
+	str := chi.URLParam(r, name)
+	if str == "" {
+		err := apperror.Invalid(name, name+" is required")
+		Write(w, r, err)
+		return 0, err
+	}
+
+	v, err := strconv.Atoi(str)
+	if err != nil {
+		err = apperror.Invalid(name, name+" must be an integer")
+		Write(w, r, err)
+		return 0, err
+	}
+	return v, nil
+}
@@ -0,0 +1,84 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+)
+
+// ContentType of problem details responses
+const ContentType = "application/problem+json"
+
+var (
+	problemLogger = logger.New().Prefix("Request Errors")
+
+	// statuses answering each error kind
+	statuses = map[apperror.Kind]int{
+		apperror.KindNotFound:     http.StatusNotFound,
+		apperror.KindConflict:     http.StatusConflict,
+		apperror.KindValidation:   http.StatusBadRequest,
+		apperror.KindUnauthorized: http.StatusUnauthorized,
+		apperror.KindForbidden:    http.StatusForbidden,
+		apperror.KindUnavailable:  http.StatusServiceUnavailable,
+	}
+)
+
+// Problem is an RFC 7807 problem details body
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// CorrelationID is the X-Request-ID of the request, internal errors are logged with it
+	CorrelationID string                `json:"correlationId,omitempty"`
+	Errors        []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// New returns the problem answering err, the details of internal errors are not disclosed
+func New(err error) Problem {
+	kind := apperror.KindOf(err)
+	status, ok := statuses[kind]
+	if !ok {
+		return Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(http.StatusInternalServerError),
+			Status: http.StatusInternalServerError,
+			Detail: "an unexpected error occurred, report it with the correlation id",
+		}
+	}
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+		Errors: apperror.FieldsOf(err),
+	}
+}
+
+// Write answers the request with the problem of err. Internal errors are logged with the correlation
+// id of the request, the response only carries the id.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	p := forRequest(New(err), r)
+	if p.Status == http.StatusInternalServerError {
+		problemLogger.ErrorF("%s %s [%s]: %s", r.Method, r.URL.Path, p.CorrelationID, err.Error())
+	}
+	respond(w, p)
+}
+
+// forRequest sets the instance and correlation id of p to those of r
+func forRequest(p Problem, r *http.Request) Problem {
+	p.Instance = r.URL.Path
+	p.CorrelationID = middleware.GetReqID(r.Context())
+	return p
+}
+
+// respond writes p as the response
+func respond(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,156 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+)
+
+// serve runs h behind the correlation id middleware and decodes the problem it answered with
+func serve(t *testing.T, h http.Handler, requestID string) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/project/1/tasks", nil)
+	if requestID != "" {
+		r.Header.Set(middleware.RequestIDHeader, requestID)
+	}
+	w := httptest.NewRecorder()
+	CorrelationID(h).ServeHTTP(w, r)
+
+	var p Problem
+	if w.Header().Get("Content-Type") == ContentType {
+		if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w, p
+}
+
+func writing(err error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { Write(w, r, err) })
+}
+
+func TestWriteStatusOfKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{apperror.NotFound("task 3 not found"), http.StatusNotFound},
+		{apperror.Conflict("leave already decided"), http.StatusConflict},
+		{apperror.Validation("invalid body"), http.StatusBadRequest},
+		{apperror.Unauthorized("invalid token"), http.StatusUnauthorized},
+		{apperror.Forbidden("not an approver"), http.StatusForbidden},
+		{apperror.Unavailable("redis unavailable"), http.StatusServiceUnavailable},
+		{fmt.Errorf("saving: %w", apperror.NotFound("project 1 not found")), http.StatusNotFound},
+		{errors.New("pq: connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w, p := serve(t, writing(tt.err), "req-1")
+		if w.Code != tt.want || p.Status != tt.want || p.Title != http.StatusText(tt.want) || p.Type != "about:blank" {
+			t.Errorf("%v: status = %d, problem = %+v, want %d", tt.err, w.Code, p, tt.want)
+		}
+		if p.Instance != "/project/1/tasks" || p.CorrelationID != "req-1" || w.Header().Get(middleware.RequestIDHeader) != "req-1" {
+			t.Errorf("%v: problem = %+v, want the instance and correlation id of the request", tt.err, p)
+		}
+	}
+}
+
+func TestWriteHidesInternalErrors(t *testing.T) {
+	w, p := serve(t, writing(errors.New("pq: password authentication failed for user postgres")), "")
+	if w.Code != http.StatusInternalServerError || p.Detail != "an unexpected error occurred, report it with the correlation id" {
+		t.Fatalf("problem = %+v, want the details withheld", p)
+	}
+	// a correlation id is generated without X-Request-ID
+	if p.CorrelationID == "" || w.Header().Get(middleware.RequestIDHeader) != p.CorrelationID {
+		t.Fatalf("correlation id = %q, header %q", p.CorrelationID, w.Header().Get(middleware.RequestIDHeader))
+	}
+}
+
+func TestWriteFieldErrors(t *testing.T) {
+	invalid := apperror.New(apperror.KindValidation, "invalid task")
+	_, p := serve(t, writing(invalid.WithField("title", "title is required").WithField("estimateHours", "estimateHours must not be negative")), "req-2")
+
+	want := []apperror.FieldError{{Field: "title", Message: "title is required"}, {Field: "estimateHours", Message: "estimateHours must not be negative"}}
+	if p.Status != http.StatusBadRequest || !reflect.DeepEqual(p.Errors, want) || p.Detail != "invalid task: title is required; estimateHours must not be negative" {
+		t.Fatalf("problem = %+v, want the field errors", p)
+	}
+}
+
+func TestRecoverer(t *testing.T) {
+	panicking := Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tasks map[int]string
+		tasks[1] = "nil map"
+	}))
+	w, p := serve(t, panicking, "req-3")
+	if w.Code != http.StatusInternalServerError || p.CorrelationID != "req-3" || p.Instance != "/project/1/tasks" {
+		t.Fatalf("status = %d, problem = %+v, want an internal error problem", w.Code, p)
+	}
+
+	// handlers that do not panic are untouched
+	w, _ = serve(t, Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})), "")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want the status of the handler", w.Code)
+	}
+
+	// hijacked upgrades cannot be answered
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Connection", "Upgrade")
+	w = httptest.NewRecorder()
+	Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("after upgrade") })).ServeHTTP(w, r)
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Fatalf("upgraded request answered with %q", w.Body.String())
+	}
+}
+
+func TestRecovererRepanicsAbortHandler(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler for the server to abort the response", rec)
+		}
+	}()
+	Recoverer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/dashboard/stream", nil))
+	t.Fatal("abort did not propagate")
+}
+
+func TestIntParam(t *testing.T) {
+	tests := []struct {
+		path       string
+		want       int
+		wantStatus int
+	}{
+		{"/tasks/42", 42, http.StatusOK},
+		{"/tasks/-1", -1, http.StatusOK},
+		{"/tasks/abc", 0, http.StatusBadRequest},
+		{"/tasks/", 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var got int
+		router := chi.NewRouter()
+		router.Get("/tasks/{taskID}", func(w http.ResponseWriter, r *http.Request) {
+			got, _ = IntParam(w, r, "taskID")
+		})
+		router.Get("/tasks/", func(w http.ResponseWriter, r *http.Request) {
+			got, _ = IntParam(w, r, "taskID")
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.wantStatus || got != tt.want {
+			t.Errorf("%s: status = %d, param = %d, want %d, %d", tt.path, w.Code, got, tt.wantStatus, tt.want)
+		}
+		if tt.wantStatus == http.StatusBadRequest && w.Header().Get("Content-Type") != ContentType {
+			t.Errorf("%s: content type = %q, want a problem", tt.path, w.Header().Get("Content-Type"))
+		}
+	}
+}
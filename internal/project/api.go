@@ -1,51 +1,78 @@
 package project
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/cache"
-	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+	"github.com/renniemaharaj/project-list-go/internal/task"
 )
 
-var (
-	projectLogger = logger.New().Prefix("Projects Router")
-)
+// Handler serves the project routes and mounts the milestone and task routes under them
+type Handler struct {
+	service Service
+	// search serves the search listing, which tolerates replication lag
+	search     Service
+	pagination config.Pagination
+	milestones *milestone.Handler
+	tasks      *task.Handler
+	cache      *cache.Cache
+}
+
+// NewHandler returns a handler serving the project routes through service and search, listing pages
+// of the configured sizes and caching reads in c
+func NewHandler(service, search Service, pagination config.Pagination, milestones *milestone.Handler, tasks *task.Handler, c *cache.Cache) *Handler {
+	return &Handler{
+		service:    service,
+		search:     search,
+		pagination: pagination,
+		milestones: milestones,
+		tasks:      tasks,
+		cache:      c,
+	}
+}
 
-// ProjectHandler router, chi routing
-func ProjectHandler(r chi.Router) {
-	r.Get("/page/{pageNumber}", GetAllProjectIDSByPage)
-	r.Get("/one/{projectID}", GetProjectsByID)
-	r.Get("/search/{searchQuery}/page/{pageNumber}", GetProjectsBySearchQuery)
+// Routes registers the project routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/page/{pageNumber}", h.GetAllProjectIDSByPage)
+	r.Get("/one/{projectID}", h.GetProjectsByID)
+	r.Get("/search/{searchQuery}/page/{pageNumber}", h.GetProjectsBySearchQuery)
+	r.Route("/{projectID}/milestones", h.milestones.Routes)
+	r.Route("/{projectID}/tasks", h.tasks.Routes)
 }
 
 // Gets page number from request
 func getPageNumberFromRequest(w http.ResponseWriter, r *http.Request) (int, error) {
 	pageNumberStr := chi.URLParam(r, "pageNumber")
 	if pageNumberStr == "" {
-		http.Error(w, "pageNumber is required", http.StatusBadRequest)
-		projectLogger.Error("pageNumber was missing from request")
-		return 0, fmt.Errorf("")
+		err := apperror.Invalid("pageNumber", "pageNumber is required")
+		problem.Write(w, r, err)
+		return 0, err
 	}
 
 	pageNumber, err := strconv.Atoi(pageNumberStr)
 	if err != nil || pageNumber < 0 {
-		http.Error(w, "Invalid page number", http.StatusBadRequest)
-		return 0, fmt.Errorf("")
+		err = apperror.Invalid("pageNumber", "pageNumber must be a non-negative integer")
+		problem.Write(w, r, err)
+		return 0, err
 	}
 
 	return pageNumber, nil
 }
 
 // Uses a search query to get matching projects
-func GetProjectsBySearchQuery(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProjectsBySearchQuery(w http.ResponseWriter, r *http.Request) {
 	searchQuery := chi.URLParam(r, "searchQuery")
 	if searchQuery == "" {
-		http.Error(w, "search query required", http.StatusBadRequest)
+		problem.Write(w, r, apperror.Invalid("searchQuery", "searchQuery is required"))
 		return
 	}
 
@@ -54,15 +81,15 @@ func GetProjectsBySearchQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	const pageSize = 20
+	pageSize := h.pagination.SearchPageSize
 	offset := pageNumber * pageSize
-	projects, err := cache.Use(fmt.Sprintf("projects:search:%s:page:%d", searchQuery, pageNumber), func() ([]int, error) {
-		return NewService(NewRepository(database.Automatic, projectLogger), projectLogger).GetProjectIDSBySearchQuery(r.Context(), searchQuery, pageSize, offset)
+	projects, err := cache.Use(r.Context(), h.cache, fmt.Sprintf("projects:search:%s:page:%d:size:%d", searchQuery, pageNumber, pageSize), func(ctx context.Context) ([]int, error) {
+		// search tolerates replica lag
+		return h.search.GetProjectIDSBySearchQuery(ctx, searchQuery, pageSize, offset)
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
-		projectLogger.Fatal(err)
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -71,22 +98,21 @@ func GetProjectsBySearchQuery(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetAllProjectIDSByPage returns projects paginated by page number
-func GetAllProjectIDSByPage(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllProjectIDSByPage(w http.ResponseWriter, r *http.Request) {
 	pageNumber, err := getPageNumberFromRequest(w, r)
 	if err != nil {
 		return
 	}
 
-	const pageSize = 10
+	pageSize := h.pagination.ProjectPageSize
 	offset := pageNumber * pageSize
 
-	projects, err := cache.Use(fmt.Sprintf("projects:page:%d", pageNumber), func() ([]int, error) {
-		return NewService(NewRepository(database.Automatic, projectLogger), projectLogger).GetProjectIDSByPage(r.Context(), pageSize, offset)
+	projects, err := cache.Use(r.Context(), h.cache, fmt.Sprintf("projects:page:%d:size:%d", pageNumber, pageSize), func(ctx context.Context) ([]int, error) {
+		return h.service.GetProjectIDSByPage(ctx, pageSize, offset)
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to fetch projects", http.StatusInternalServerError)
-		projectLogger.Fatal(err)
+		problem.Write(w, r, err)
 		return
 	}
 
@@ -95,28 +121,25 @@ func GetAllProjectIDSByPage(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetProjectsByID returns a single project by ID
-func GetProjectsByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetProjectsByID(w http.ResponseWriter, r *http.Request) {
 	projectIDStr := chi.URLParam(r, "projectID")
 	if projectIDStr == "" {
-		http.Error(w, "projectID is required", http.StatusBadRequest)
-		projectLogger.Error("projectID was missing from request")
+		problem.Write(w, r, apperror.Invalid("projectID", "projectID is required"))
 		return
 	}
 
 	projectID, err := strconv.Atoi(projectIDStr)
 	if err != nil {
-		http.Error(w, "invalid projectID", http.StatusBadRequest)
-		projectLogger.Fatal(err)
+		problem.Write(w, r, apperror.Invalid("projectID", "projectID must be an integer"))
 		return
 	}
 
-	project, err := cache.Use("projects:one:"+projectIDStr, func() (*Project, error) {
-		return NewService(NewRepository(database.Automatic, projectLogger), projectLogger).GetProjectDataByID(r.Context(), projectID)
+	project, err := cache.Use(r.Context(), h.cache, "projects:one:"+projectIDStr, func(ctx context.Context) (*Project, error) {
+		return h.service.GetProjectDataByID(ctx, projectID)
 	})
 
 	if err != nil {
-		http.Error(w, "Failed to fetch project", http.StatusInternalServerError)
-		projectLogger.Fatal(err)
+		problem.Write(w, r, err)
 		return
 	}
 
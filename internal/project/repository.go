@@ -2,13 +2,17 @@ package project
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"strings"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
 	internalIDField "github.com/renniemaharaj/project-list-go/internal/idRow"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
 type Repository interface {
@@ -33,6 +37,9 @@ func NewRepository(dbContext *database.DBContext, logger *logger.Logger) Reposit
 
 // InsertProjectByStruct will insert a project from project struct
 func (r *repository) InsertProjectByStruct(ctx context.Context, p *entity.Project) error {
+	ctx, span := tracing.Start(ctx, "project.repository.InsertProjectByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Insert("projects", dbx.Params{
 			"projected_start_date": p.ProjectedStartDate,
@@ -50,8 +57,16 @@ func (r *repository) InsertProjectByStruct(ctx context.Context, p *entity.Projec
 
 // GetProjectDataByID will get and return a project by ID and (error or nil)
 func (r *repository) GetProjectDataByID(ctx context.Context, projectID int) (*entity.Project, error) {
+	ctx, span := tracing.Start(ctx, "project.repository.GetProjectDataByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var Project entity.Project
 	err := r.dbContext.Get().WithContext(ctx).Select().From("projects").Where(dbx.HashExp{"id": projectID}).One(&Project)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("project %d not found", projectID)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +75,11 @@ func (r *repository) GetProjectDataByID(ctx context.Context, projectID int) (*en
 
 // GetProjectsDataByIDS gets projects by a slice of int project IDs and (error or nil)
 func (r *repository) GetProjectsDataByIDS(ctx context.Context, ids []int) ([]entity.Project, error) {
+	ctx, span := tracing.Start(ctx, "project.repository.GetProjectsDataByIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	if len(ids) == 0 {
 		return []entity.Project{}, nil
 	}
@@ -81,6 +101,11 @@ func (r *repository) GetProjectsDataByIDS(ctx context.Context, ids []int) ([]ent
 
 // GetAllProjectIDS will list projects and return their IDs
 func (r *repository) GetAllProjectIDS(ctx context.Context) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "project.repository.GetAllProjectIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	idFields := []internalIDField.IDField{}
 
 	err := r.dbContext.Get().WithContext(ctx).Select("p.id").
@@ -98,6 +123,11 @@ func (r *repository) GetAllProjectIDS(ctx context.Context) ([]int, error) {
 
 // GetProjectIDSByPage will list all projects by page
 func (r *repository) GetProjectIDSByPage(ctx context.Context, limit, offset int) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "project.repository.GetProjectIDSByPage")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	idFields := []internalIDField.IDField{}
 
 	err := r.dbContext.Get().WithContext(ctx).Select("p.id").
@@ -118,6 +148,11 @@ func (r *repository) GetProjectIDSByPage(ctx context.Context, limit, offset int)
 
 // GetProjectIDSBySearchQuery will use searchQuery and return matching project IDS
 func (r *repository) GetProjectIDSBySearchQuery(ctx context.Context, searchQuery string, limit, offset int) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "project.repository.GetProjectIDSBySearchQuery")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	// Split `+` separated terms
 	terms := strings.Split(searchQuery, "+")
 
@@ -166,6 +201,9 @@ func (r *repository) GetProjectIDSBySearchQuery(ctx context.Context, searchQuery
 
 // UpdateProjectByStruct will update a project by project struct ID
 func (r *repository) UpdateProjectByStruct(ctx context.Context, p *entity.Project) error {
+	ctx, span := tracing.Start(ctx, "project.repository.UpdateProjectByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Update("projects", dbx.Params{
 			"projected_start_date": p.ProjectedStartDate,
@@ -183,6 +221,9 @@ func (r *repository) UpdateProjectByStruct(ctx context.Context, p *entity.Projec
 
 // DeleteProjectByID will delete a project by ID
 func (r *repository) DeleteProjectByID(ctx context.Context, projectID int) error {
+	ctx, span := tracing.Start(ctx, "project.repository.DeleteProjectByID")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Delete("projects", dbx.HashExp{"id": projectID}).Execute()
 		return err
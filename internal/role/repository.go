@@ -7,6 +7,7 @@ import (
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
 type Repository interface {
@@ -24,6 +25,9 @@ func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository
 
 // InsertConsultantRoleByStruct will insert a consultant role into consultant_roles table
 func (r *repository) InsertConsultantRoleByStruct(ctx context.Context, consultantRole entity.ConsultantRole) error {
+	ctx, span := tracing.Start(ctx, "role.repository.InsertConsultantRoleByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Insert("consultant_roles",
 			dbx.Params{
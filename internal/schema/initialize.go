@@ -7,10 +7,16 @@ import (
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
 )
 
+// Version is the schema version InitializeDatabaseTables brings the database to, bump it with every
+// schema change so instances can tell whether the database is ready for them
+const Version = 1
+
 type Repository interface {
 	InitializeDatabaseTables(ctx context.Context) error
+	GetVersion(ctx context.Context) (int, error)
 }
 
 type repository struct {
@@ -31,6 +37,7 @@ func NewRepository(_db *database.DBContext, _l *logger.Logger) Repository {
 //
 // 1) Domain (dimension) – relation‑independent
 //   - consultants               -> master record for a consultant (people catalog)
+//   - holidays                  -> calendar of days nobody is expected to work
 //
 // 2) Domain (dimension) – relation‑dependent
 //   - projects                  -> projects managed by/for consultants
@@ -41,10 +48,30 @@ func NewRepository(_db *database.DBContext, _l *logger.Logger) Repository {
 //   - consultant_roles          -> 1‑to‑many roles per consultant (role catalog)
 //   - project_tags              -> N‑to‑N string tags per project
 //   - project_consultants       -> N‑to‑N assignment of consultants to projects
+//   - project_milestones        -> named deliverables per project, owned by a consultant
+//   - project_tasks             -> work breakdown per project with estimates and assignees
+//   - project_task_dependencies -> finish‑to‑start links between tasks of a project
+//   - consultant_capacities     -> 1‑to‑1 weekly hours and part‑time percentage per consultant
+//   - project_allocations       -> planned percentage or weekly hours per project assignment and period
+//   - consultant_leaves         -> vacation, sick and public‑holiday absences awaiting or after approval
+//   - event_outbox              -> project events written with their mutation, awaiting webhook fan‑out
+//   - webhook_subscriptions     -> outgoing webhook targets with secret and event filter
+//   - webhook_deliveries        -> per subscription delivery attempts, retries and dead letters
+//   - notification_preferences  -> per consultant and event type delivery mode (immediate, digest, off)
+//   - notifications             -> email notifications awaiting immediate or digest delivery
+//
+// 4) Functions – depend on the tables above
+//   - consultant_availability   -> available hours per consultant and day (capacity, holidays, leave)
+//
+// 5) Triggers – change feed for live updates
+//   - notify_project_list_change -> NOTIFY project_list_changes on writes to projects, statuses, time entries, milestones
+//
+// 6) Version – recorded last, once everything above exists
+//   - schema_migrations         -> schema versions applied, the highest is the current one
 //
 // Table creation order respects foreign‑key dependencies:
 //
-//	consultants -> projects -> (project_time_entries, project_statuses, consultant_roles, project_tags, project_consultants)
+//	consultants -> projects -> (project_time_entries, project_statuses, consultant_roles, project_tags, project_consultants, project_milestones, project_tasks)
 //
 // Error handling: schema creation is wrapped in a transaction using UseTransaction API.
 // Any failure aborts and rolls back changes automatically.
@@ -65,6 +92,21 @@ func (r *repository) InitializeDatabaseTables(ctx context.Context) error {
 			return fmt.Errorf("init tables (non-domain dependent) error: %w", err)
 		}
 
+		// 4) Functions
+		if err := createFunctions(tx); err != nil {
+			return fmt.Errorf("init functions error: %w", err)
+		}
+
+		// 5) Triggers
+		if err := createTriggers(tx); err != nil {
+			return fmt.Errorf("init triggers error: %w", err)
+		}
+
+		// 6) Version
+		if err := recordVersion(tx); err != nil {
+			return fmt.Errorf("init version error: %w", err)
+		}
+
 		return nil
 	})
 }
@@ -82,6 +124,13 @@ func createDomainIndependentTables(tx *dbx.Tx) error {
 			email           VARCHAR(255) UNIQUE NOT NULL,
 			profile_picture TEXT
 		);`,
+
+		// holidays -- one row per non-working day, shared by every consultant
+		`CREATE TABLE IF NOT EXISTS holidays (
+			id           SERIAL PRIMARY KEY,
+			holiday_date DATE UNIQUE NOT NULL,
+			name         VARCHAR(200) NOT NULL
+		);`,
 	}
 	return runQueries(tx, queries)
 }
@@ -121,7 +170,7 @@ func createNonDomainDependentTables(tx *dbx.Tx) error {
 			id            SERIAL PRIMARY KEY,
 			project_id    INTEGER REFERENCES projects(id) ON DELETE CASCADE,
 			consultant_id INTEGER REFERENCES consultants(id) ON DELETE SET NULL,
-			type          VARCHAR(10) NOT NULL,
+			type          VARCHAR(10) NOT NULL, -- debit, credit or leave (non-billable)
 			hours         NUMERIC(6,2) NOT NULL,
 			title         TEXT NOT NULL,
 			description   TEXT,
@@ -162,6 +211,222 @@ func createNonDomainDependentTables(tx *dbx.Tx) error {
 			consultant_id INTEGER REFERENCES consultants(id) ON DELETE CASCADE,
 			role          VARCHAR(50) NOT NULL
 		);`,
+
+		// project_milestones -- named deliverables per project with a due date
+		//
+		// Notes:
+		//   owner_id is optional; deleting the owning consultant keeps the milestone.
+		`CREATE TABLE IF NOT EXISTS project_milestones (
+			id             SERIAL PRIMARY KEY,
+			project_id     INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			owner_id       INTEGER REFERENCES consultants(id) ON DELETE SET NULL,
+			title          VARCHAR(200) NOT NULL,
+			description    TEXT NOT NULL DEFAULT '',
+			due_date       TIMESTAMP NOT NULL,
+			completed      BOOLEAN NOT NULL DEFAULT FALSE,
+			date_completed TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_project_milestones_project_id ON project_milestones(project_id);`,
+
+		// project_time_entries.milestone_id -- optional linkage of logged hours to a milestone
+		`ALTER TABLE project_time_entries
+			ADD COLUMN IF NOT EXISTS milestone_id INTEGER REFERENCES project_milestones(id) ON DELETE SET NULL;`,
+
+		// project_tasks -- work breakdown under a project
+		`CREATE TABLE IF NOT EXISTS project_tasks (
+			id             SERIAL PRIMARY KEY,
+			project_id     INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			assignee_id    INTEGER REFERENCES consultants(id) ON DELETE SET NULL,
+			title          VARCHAR(200) NOT NULL,
+			description    TEXT NOT NULL DEFAULT '',
+			estimate_hours NUMERIC(8,2) NOT NULL DEFAULT 0,
+			completed      BOOLEAN NOT NULL DEFAULT FALSE
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_project_tasks_project_id ON project_tasks(project_id);`,
+
+		// project_task_dependencies -- finish‑to‑start: task_id starts after depends_on_id finishes
+		//
+		// Notes:
+		//   cycles across several links are rejected on insert by the task repository.
+		`CREATE TABLE IF NOT EXISTS project_task_dependencies (
+			id            SERIAL PRIMARY KEY,
+			task_id       INTEGER NOT NULL REFERENCES project_tasks(id) ON DELETE CASCADE,
+			depends_on_id INTEGER NOT NULL REFERENCES project_tasks(id) ON DELETE CASCADE,
+			CHECK (task_id <> depends_on_id),
+			UNIQUE (task_id, depends_on_id)
+		);`,
+
+		// project_time_entries.task_id -- optional linkage so task actuals roll up
+		`ALTER TABLE project_time_entries
+			ADD COLUMN IF NOT EXISTS task_id INTEGER REFERENCES project_tasks(id) ON DELETE SET NULL;`,
+		`CREATE INDEX IF NOT EXISTS ix_project_time_entries_task_id ON project_time_entries(task_id);`,
+
+		// consultant_capacities -- contracted working time, consultants without a row work 40h at 100%
+		`CREATE TABLE IF NOT EXISTS consultant_capacities (
+			consultant_id     INTEGER PRIMARY KEY REFERENCES consultants(id) ON DELETE CASCADE,
+			weekly_hours      NUMERIC(5,2) NOT NULL DEFAULT 40 CHECK (weekly_hours >= 0),
+			part_time_percent NUMERIC(5,2) NOT NULL DEFAULT 100 CHECK (part_time_percent BETWEEN 0 AND 100)
+		);`,
+
+		// utilization aggregates logged hours per consultant and period
+		`CREATE INDEX IF NOT EXISTS ix_project_time_entries_consultant_date ON project_time_entries(consultant_id, entry_date);`,
+
+		// project_allocations -- planned allocation of a consultant to a project for a date range
+		//
+		// Notes:
+		//   percent is relative to the consultant's capacity; hours_per_week is absolute.
+		`CREATE TABLE IF NOT EXISTS project_allocations (
+			id             SERIAL PRIMARY KEY,
+			project_id     INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+			consultant_id  INTEGER NOT NULL REFERENCES consultants(id) ON DELETE CASCADE,
+			percent        NUMERIC(5,2) CHECK (percent > 0),
+			hours_per_week NUMERIC(5,2) CHECK (hours_per_week > 0),
+			start_date     DATE NOT NULL,
+			end_date       DATE NOT NULL,
+			CHECK (end_date >= start_date),
+			CHECK ((percent IS NULL) <> (hours_per_week IS NULL))
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_project_allocations_consultant_range ON project_allocations(consultant_id, start_date, end_date);`,
+
+		// consultant_leaves -- absences; only approved leave reduces availability
+		`CREATE TABLE IF NOT EXISTS consultant_leaves (
+			id            SERIAL PRIMARY KEY,
+			consultant_id INTEGER NOT NULL REFERENCES consultants(id) ON DELETE CASCADE,
+			type          VARCHAR(20) NOT NULL CHECK (type IN ('vacation', 'sick', 'public-holiday')),
+			start_date    DATE NOT NULL,
+			end_date      DATE NOT NULL,
+			status        VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+			approver_id   INTEGER REFERENCES consultants(id) ON DELETE SET NULL,
+			note          TEXT NOT NULL DEFAULT '',
+			date_created  TIMESTAMP NOT NULL DEFAULT NOW(),
+			date_decided  TIMESTAMP,
+			CHECK (end_date >= start_date)
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_consultant_leaves_consultant_range ON consultant_leaves(consultant_id, start_date, end_date);`,
+
+		// dashboard aggregates resolve the latest status and sum hours per project in SQL
+		`CREATE INDEX IF NOT EXISTS ix_project_statuses_project_latest ON project_statuses(project_id, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS ix_project_time_entries_project_type ON project_time_entries(project_id, type);`,
+
+		// event_outbox -- project events written in the transaction of their mutation, fanned out to
+		// webhook_deliveries by the dispatcher
+		`CREATE TABLE IF NOT EXISTS event_outbox (
+			id              BIGSERIAL PRIMARY KEY,
+			event_type      VARCHAR(50) NOT NULL,
+			project_id      INTEGER NOT NULL,
+			payload         JSONB NOT NULL,
+			date_created    TIMESTAMP NOT NULL DEFAULT NOW(),
+			date_dispatched TIMESTAMP
+		);`,
+		// trace_context carries the W3C trace context of the mutation on to its webhook deliveries
+		`ALTER TABLE event_outbox
+			ADD COLUMN IF NOT EXISTS trace_context JSONB NOT NULL DEFAULT '{}';`,
+		`CREATE INDEX IF NOT EXISTS ix_event_outbox_pending ON event_outbox(id) WHERE date_dispatched IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS ix_event_outbox_dispatched ON event_outbox(date_dispatched) WHERE date_dispatched IS NOT NULL;`,
+
+		// webhook_subscriptions -- target URL, signing secret and event type filter (empty means all)
+		`CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id           SERIAL PRIMARY KEY,
+			url          TEXT NOT NULL,
+			event_types  TEXT[] NOT NULL DEFAULT '{}',
+			secret       TEXT NOT NULL,
+			active       BOOLEAN NOT NULL DEFAULT TRUE,
+			date_created TIMESTAMP NOT NULL DEFAULT NOW()
+		);`,
+
+		// webhook_deliveries -- one delivery per subscription and event with its retry state
+		//
+		// Notes:
+		//   dead deliveries exhausted their attempts and are only retried on request.
+		//   locked_until leases a claimed delivery to one dispatcher while it is sent.
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id               BIGSERIAL PRIMARY KEY,
+			subscription_id  INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+			outbox_id        BIGINT NOT NULL REFERENCES event_outbox(id) ON DELETE CASCADE,
+			event_type       VARCHAR(50) NOT NULL,
+			status           VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+			attempts         INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+			locked_until     TIMESTAMP,
+			last_status_code INTEGER,
+			last_error       TEXT NOT NULL DEFAULT '',
+			date_created     TIMESTAMP NOT NULL DEFAULT NOW(),
+			date_delivered   TIMESTAMP,
+			UNIQUE (subscription_id, outbox_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';`,
+		// the retention sweep looks up the deliveries left of an event
+		`CREATE INDEX IF NOT EXISTS ix_webhook_deliveries_outbox ON webhook_deliveries(outbox_id);`,
+
+		// notification_preferences -- how a consultant wants to receive an event type, no row means immediate
+		`CREATE TABLE IF NOT EXISTS notification_preferences (
+			consultant_id INTEGER NOT NULL REFERENCES consultants(id) ON DELETE CASCADE,
+			event_type    VARCHAR(50) NOT NULL,
+			mode          VARCHAR(20) NOT NULL CHECK (mode IN ('immediate', 'digest', 'off')),
+			PRIMARY KEY (consultant_id, event_type)
+		);`,
+
+		// notifications -- one notification per recipient and occurrence with its delivery state
+		//
+		// Notes:
+		//   dedupe_key identifies the occurrence (e.g. the on-hold status) so detection is idempotent.
+		//   skipped notifications were detected while the recipient had the event type turned off.
+		//   locked_until leases a claimed notification to one notifier while it is sent.
+		`CREATE TABLE IF NOT EXISTS notifications (
+			id            BIGSERIAL PRIMARY KEY,
+			consultant_id INTEGER NOT NULL REFERENCES consultants(id) ON DELETE CASCADE,
+			event_type    VARCHAR(50) NOT NULL,
+			project_id    INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+			dedupe_key    VARCHAR(200) NOT NULL,
+			data          JSONB NOT NULL,
+			mode          VARCHAR(20) NOT NULL CHECK (mode IN ('immediate', 'digest', 'off')),
+			status        VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+			attempts      INTEGER NOT NULL DEFAULT 0,
+			locked_until  TIMESTAMP,
+			last_error    TEXT NOT NULL DEFAULT '',
+			date_created  TIMESTAMP NOT NULL DEFAULT NOW(),
+			date_sent     TIMESTAMP,
+			UNIQUE (consultant_id, dedupe_key)
+		);`,
+		`CREATE INDEX IF NOT EXISTS ix_notifications_pending ON notifications(mode, date_created) WHERE status = 'pending';`,
+	}
+	return runQueries(tx, queries)
+}
+
+// createFunctions creates (or replaces) SQL functions shared by reporting queries.
+func createFunctions(tx *dbx.Tx) error {
+	queries := []string{
+		// consultant_availability -- one row per consultant and day of the range with the hours the
+		// consultant can work: the daily share of weekly capacity on weekdays, 0 on weekends,
+		// holidays and approved leave. Capacity defaults are passed in by the caller.
+		`CREATE OR REPLACE FUNCTION consultant_availability(
+			range_from DATE, range_to DATE, default_weekly_hours NUMERIC, default_part_time_percent NUMERIC
+		)
+		RETURNS TABLE (consultant_id INTEGER, day DATE, available_hours NUMERIC, reason TEXT)
+		LANGUAGE sql STABLE AS $$
+			SELECT c.id, d.day,
+				CASE WHEN d.reason = '' THEN
+					COALESCE(cc.weekly_hours, default_weekly_hours)
+						* COALESCE(cc.part_time_percent, default_part_time_percent) / 100.0 / 5
+				ELSE 0 END,
+				d.reason
+			FROM consultants c
+			LEFT JOIN consultant_capacities cc ON cc.consultant_id = c.id
+			CROSS JOIN LATERAL (
+				SELECT s::date AS day,
+					CASE
+						WHEN EXTRACT(ISODOW FROM s) >= 6 THEN 'weekend'
+						WHEN EXISTS (SELECT 1 FROM holidays h WHERE h.holiday_date = s::date) THEN 'holiday'
+						WHEN EXISTS (
+							SELECT 1 FROM consultant_leaves l
+							WHERE l.consultant_id = c.id AND l.status = 'approved'
+								AND s::date BETWEEN l.start_date AND l.end_date
+						) THEN 'leave'
+						ELSE ''
+					END AS reason
+				FROM generate_series(range_from, range_to, interval '1 day') AS s
+			) AS d
+		$$;`,
 	}
 	return runQueries(tx, queries)
 }
@@ -177,3 +442,52 @@ func runQueries(tx *dbx.Tx, queries []string) error {
 	}
 	return nil
 }
+
+// createTriggers creates the change feed triggers. Each statement writing to a table the dashboard is
+// computed from sends one NOTIFY on project_list_changes with the table and operation as JSON.
+func createTriggers(tx *dbx.Tx) error {
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION notify_project_list_change() RETURNS trigger
+		LANGUAGE plpgsql AS $$
+		BEGIN
+			PERFORM pg_notify('project_list_changes', json_build_object('table', TG_TABLE_NAME, 'op', TG_OP)::text);
+			RETURN NULL;
+		END
+		$$;`,
+	}
+	for _, table := range []string{"projects", "project_statuses", "project_time_entries", "project_milestones"} {
+		queries = append(queries,
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS %s_notify_change ON %s;`, table, table),
+			fmt.Sprintf(`CREATE TRIGGER %s_notify_change
+				AFTER INSERT OR UPDATE OR DELETE ON %s
+				FOR EACH STATEMENT EXECUTE FUNCTION notify_project_list_change();`, table, table),
+		)
+	}
+	return runQueries(tx, queries)
+}
+
+// recordVersion records Version as applied.
+func recordVersion(tx *dbx.Tx) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version      INTEGER PRIMARY KEY,
+			date_applied TIMESTAMP NOT NULL DEFAULT NOW()
+		);`,
+		fmt.Sprintf(`INSERT INTO schema_migrations (version) VALUES (%d) ON CONFLICT (version) DO NOTHING;`, Version),
+	}
+	return runQueries(tx, queries)
+}
+
+// GetVersion returns the current schema version of the database, 0 when none was recorded
+func (r *repository) GetVersion(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "schema.repository.GetVersion")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var version int
+	err := r.dbContext.Get().WithContext(ctx).
+		NewQuery(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).
+		Row(&version)
+	return version, err
+}
@@ -7,6 +7,9 @@ import (
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 )
 
 type Repository interface {
@@ -24,21 +27,36 @@ func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository
 	return &repository{dbContext, _l}
 }
 
-// InsertProjectStatusByStruct will insert a new status relating to project id into status table
+// InsertProjectStatusByStruct will insert a new status relating to project id into status table, set
+// its ID and creation date and enqueue a status.added event
 func (r *repository) InsertProjectStatusByStruct(ctx context.Context, s *entity.ProjectStatus) error {
+	ctx, span := tracing.Start(ctx, "status.repository.InsertProjectStatusByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
-		_, err := tx.Insert("project_statuses", dbx.Params{
-			"title":         s.Title,
-			"description":   s.Description,
-			"project_id":    s.ProjectID,
-			"consultant_id": s.ConsultantID,
-		}).Execute()
-		return err
+		err := tx.NewQuery(`INSERT INTO project_statuses (title, description, project_id, consultant_id)
+			VALUES ({:title}, {:description}, {:project_id}, {:consultant_id})
+			RETURNING id, date_created`).
+			Bind(dbx.Params{
+				"title":         s.Title,
+				"description":   s.Description,
+				"project_id":    s.ProjectID,
+				"consultant_id": s.ConsultantID,
+			}).Row(&s.ID, &s.DateCreated)
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeStatusAdded, s.ProjectID, s)
 	})
 }
 
 // GetStatusHistoryByProjectsIDS will return all project_statuses relating to the given projectIDs (history)
 func (r *repository) GetStatusHistoryByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.ProjectStatus, error) {
+	ctx, span := tracing.Start(ctx, "status.repository.GetStatusHistoryByProjectsIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.ProjectStatus
 
 	// Convert []int -> []interface{} for dbx.In
@@ -58,6 +76,11 @@ func (r *repository) GetStatusHistoryByProjectsIDS(ctx context.Context, projectI
 
 // GetStatusHistoryByProjectID will return all project_statuses relating to the projectID (history)
 func (r *repository) GetStatusHistoryByProjectID(ctx context.Context, projectID int) ([]entity.ProjectStatus, error) {
+	ctx, span := tracing.Start(ctx, "status.repository.GetStatusHistoryByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.ProjectStatus
 	err := r.dbContext.Get().WithContext(ctx).Select().
 		From("project_statuses").
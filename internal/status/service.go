@@ -2,9 +2,12 @@ package status
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
 )
 
 type Service interface {
@@ -15,6 +18,7 @@ type Service interface {
 // Service
 type service struct {
 	repo   Repository
+	cache  *cache.Cache
 	logger *logger.Logger
 }
 
@@ -22,8 +26,9 @@ type ProjectStatus struct {
 	entity.ProjectStatus
 }
 
-func NewService(repo Repository, logger *logger.Logger) Service {
-	return &service{repo, logger}
+// NewService returns a status service publishing project events through c
+func NewService(repo Repository, c *cache.Cache, logger *logger.Logger) Service {
+	return &service{repo, c, logger}
 }
 
 func (s *service) GetStatusHistoryByProjectID(ctx context.Context, projectID int) ([]ProjectStatus, error) {
@@ -39,5 +44,11 @@ func (s *service) GetStatusHistoryByProjectID(ctx context.Context, projectID int
 }
 
 func (s *service) InsertProjectStatusByStruct(ctx context.Context, projectStatus *ProjectStatus) error {
-	return s.repo.InsertProjectStatusByStruct(ctx, &projectStatus.ProjectStatus)
+	if err := s.repo.InsertProjectStatusByStruct(ctx, &projectStatus.ProjectStatus); err != nil {
+		return err
+	}
+	if err := events.Publish(ctx, s.cache, events.TypeStatusAdded, projectStatus.ProjectID, projectStatus.ProjectStatus); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to publish %s: %s", events.TypeStatusAdded, err.Error()))
+	}
+	return nil
 }
@@ -7,10 +7,14 @@ import (
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 )
 
 type Repository interface {
 	InsertProjectTagByStruct(ctx context.Context, tag entity.ProjectTag) error
+	RemoveProjectTagByProjectID(ctx context.Context, projectID int, tag string) error
 }
 
 type repository struct {
@@ -24,17 +28,28 @@ func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository
 
 // InsertProjectTagByStruct will insert a project tag into project_tags
 func (r *repository) InsertProjectTagByStruct(ctx context.Context, tag entity.ProjectTag) error {
+	ctx, span := tracing.Start(ctx, "tag.repository.InsertProjectTagByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Insert("project_tags", dbx.Params{
 			"project_id": tag.ProjectID,
 			"tag":        tag.Tag,
 		}).Execute()
-		return err
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeTagChanged, tag.ProjectID, events.TagChange{Tag: tag.Tag})
 	})
 }
 
 // GetProjectTagsByProjectID, from project_tags table, will return all tags with projectID
 func (r *repository) GetProjectTagsByProjectID(ctx context.Context, projectID int) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "tag.repository.GetProjectTagsByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var tags []string
 	err := r.dbContext.Get().WithContext(ctx).Select("tag").
 		From("project_tags").
@@ -45,11 +60,17 @@ func (r *repository) GetProjectTagsByProjectID(ctx context.Context, projectID in
 
 // RemoveProjectTagByProjectID, using projectID && tag, will remove tag from project_tags table
 func (r *repository) RemoveProjectTagByProjectID(ctx context.Context, projectID int, tag string) error {
+	ctx, span := tracing.Start(ctx, "tag.repository.RemoveProjectTagByProjectID")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
 		_, err := tx.Delete("project_tags", dbx.HashExp{
 			"project_id": projectID,
 			"tag":        tag,
 		}).Execute()
-		return err
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeTagChanged, projectID, events.TagChange{Tag: tag, Removed: true})
 	})
 }
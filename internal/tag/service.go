@@ -2,18 +2,23 @@ package tag
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
 )
 
 type Service interface {
 	InsertProjectTagByStruct(ctx context.Context, projectTag ProjectTag) error
+	RemoveProjectTagByProjectID(ctx context.Context, projectID int, tag string) error
 }
 
 // Service
 type service struct {
 	repo   Repository
+	cache  *cache.Cache
 	logger *logger.Logger
 }
 
@@ -21,10 +26,30 @@ type ProjectTag struct {
 	entity.ProjectTag
 }
 
-func NewService(repo Repository, logger *logger.Logger) Service {
-	return &service{repo, logger}
+// NewService returns a tag service publishing project events through c
+func NewService(repo Repository, c *cache.Cache, logger *logger.Logger) Service {
+	return &service{repo, c, logger}
 }
 
 func (s *service) InsertProjectTagByStruct(ctx context.Context, projectTag ProjectTag) error {
-	return s.repo.InsertProjectTagByStruct(ctx, projectTag.ProjectTag)
+	if err := s.repo.InsertProjectTagByStruct(ctx, projectTag.ProjectTag); err != nil {
+		return err
+	}
+	s.publish(ctx, projectTag.ProjectID, events.TagChange{Tag: projectTag.Tag})
+	return nil
+}
+
+func (s *service) RemoveProjectTagByProjectID(ctx context.Context, projectID int, tag string) error {
+	if err := s.repo.RemoveProjectTagByProjectID(ctx, projectID, tag); err != nil {
+		return err
+	}
+	s.publish(ctx, projectID, events.TagChange{Tag: tag, Removed: true})
+	return nil
+}
+
+// publish notifies the project's subscribers of a committed tag change
+func (s *service) publish(ctx context.Context, projectID int, change events.TagChange) {
+	if err := events.Publish(ctx, s.cache, events.TypeTagChanged, projectID, change); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to publish %s: %s", events.TypeTagChanged, err.Error()))
+	}
 }
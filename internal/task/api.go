@@ -0,0 +1,230 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+// Handler serves the task routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the task routes through service, caching reads in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
+
+// Routes registers the task routes, mounted under /project/{projectID}/tasks
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.GetTasksByProjectID)
+	r.Post("/", h.CreateTask)
+	r.Get("/forecast", h.GetProjectForecast)
+	r.Get("/{taskID}", h.GetTaskByID)
+	r.Put("/{taskID}", h.UpdateTask)
+	r.Delete("/{taskID}", h.DeleteTask)
+	r.Post("/{taskID}/dependencies", h.CreateTaskDependency)
+	r.Delete("/{taskID}/dependencies/{dependsOnID}", h.DeleteTaskDependency)
+}
+
+// InvalidateProjectTasks clears cached task lists and forecasts of a project, time entries linked
+// to its tasks change their actuals too
+func InvalidateProjectTasks(c *cache.Cache, projectID int) {
+	c.Invalidate(
+		fmt.Sprintf("projects:tasks:%d", projectID),
+		fmt.Sprintf("projects:forecast:%d", projectID),
+	)
+}
+
+// Loads a task and makes sure it belongs to the project in the url
+func (h *Handler) getProjectTask(w http.ResponseWriter, r *http.Request) (*Task, bool) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return nil, false
+	}
+	taskID, err := problem.IntParam(w, r, "taskID")
+	if err != nil {
+		return nil, false
+	}
+
+	t, err := h.service.GetTaskByID(r.Context(), taskID)
+	if err == nil && t.ProjectID != projectID {
+		err = apperror.NotFound("task %d not found", taskID)
+	}
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return t, true
+}
+
+// GetTasksByProjectID returns all tasks of a project with rolled up actual hours
+func (h *Handler) GetTasksByProjectID(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	tasks, err := cache.Use(r.Context(), h.cache, fmt.Sprintf("projects:tasks:%d", projectID), func(ctx context.Context) ([]TaskProgress, error) {
+		return h.service.GetTasksByProjectID(ctx, projectID)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tasks)
+}
+
+// GetProjectForecast returns the critical path forecast of a project
+func (h *Handler) GetProjectForecast(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	forecast, err := cache.Use(r.Context(), h.cache, fmt.Sprintf("projects:forecast:%d", projectID), func(ctx context.Context) (*ProjectForecast, error) {
+		return h.service.GetProjectForecastByProjectID(ctx, projectID)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(forecast)
+}
+
+// GetTaskByID returns a single task of a project
+func (h *Handler) GetTaskByID(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getProjectTask(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// CreateTask inserts a task for the project from the request body
+func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	var t Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid task body"))
+		return
+	}
+	t.ID = 0
+	t.ProjectID = projectID
+
+	if err := h.service.InsertTaskByStruct(r.Context(), &t); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	InvalidateProjectTasks(h.cache, projectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// UpdateTask replaces a task of the project with the request body
+func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getProjectTask(w, r)
+	if !ok {
+		return
+	}
+
+	var t Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid task body"))
+		return
+	}
+	t.ID = existing.ID
+	t.ProjectID = existing.ProjectID
+
+	if err := h.service.UpdateTaskByStruct(r.Context(), &t); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	InvalidateProjectTasks(h.cache, t.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(t)
+}
+
+// DeleteTask removes a task of the project
+func (h *Handler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getProjectTask(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTaskByID(r.Context(), t.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	InvalidateProjectTasks(h.cache, t.ProjectID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateTaskDependency makes the task depend on the task in the request body (finish-to-start)
+func (h *Handler) CreateTaskDependency(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getProjectTask(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		DependsOnID int `json:"dependsOnID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.DependsOnID == 0 {
+		problem.Write(w, r, apperror.Invalid("dependsOnID", "dependsOnID is required"))
+		return
+	}
+
+	d, err := h.service.InsertTaskDependency(r.Context(), t.ID, body.DependsOnID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	InvalidateProjectTasks(h.cache, t.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(d)
+}
+
+// DeleteTaskDependency removes the dependency of the task on dependsOnID
+func (h *Handler) DeleteTaskDependency(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.getProjectTask(w, r)
+	if !ok {
+		return
+	}
+	dependsOnID, err := problem.IntParam(w, r, "dependsOnID")
+	if err != nil {
+		return
+	}
+
+	if err := h.service.DeleteTaskDependency(r.Context(), t.ID, dependsOnID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	InvalidateProjectTasks(h.cache, t.ProjectID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
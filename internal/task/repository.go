@@ -0,0 +1,240 @@
+package task
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+var (
+	// ErrDependencyCycle is returned when a dependency would make a task (indirectly) depend on itself
+	ErrDependencyCycle = apperror.New(apperror.KindConflict, "task dependency would create a cycle")
+	// ErrCrossProjectDependency is returned when linking tasks of different projects
+	ErrCrossProjectDependency = apperror.New(apperror.KindValidation, "task dependencies must stay within one project")
+)
+
+// dependencyLockClass namespaces the advisory locks taken while inserting task dependencies
+const dependencyLockClass = 27001
+
+type Repository interface {
+	InsertTaskByStruct(ctx context.Context, t *entity.Task) error
+	GetTaskByID(ctx context.Context, taskID int) (*entity.Task, error)
+	GetTasksProgressByProjectID(ctx context.Context, projectID int) ([]entity.TaskProgress, error)
+	GetTaskDependenciesByProjectID(ctx context.Context, projectID int) ([]entity.TaskDependency, error)
+	GetProjectScheduleByID(ctx context.Context, projectID int) (*entity.Project, error)
+	UpdateTaskByStruct(ctx context.Context, t *entity.Task) error
+	DeleteTaskByID(ctx context.Context, taskID int) error
+	InsertTaskDependencyByStruct(ctx context.Context, d *entity.TaskDependency) error
+	DeleteTaskDependency(ctx context.Context, taskID, dependsOnID int) error
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	types     config.TimeEntryTypes
+	logger    *logger.Logger
+}
+
+// NewRepository returns a task repository rolling up hours of the credit type of types as actuals
+func NewRepository(dbContext *database.DBContext, types config.TimeEntryTypes, _l *logger.Logger) Repository {
+	return &repository{dbContext, types, _l}
+}
+
+// InsertTaskByStruct will insert a task into project_tasks and set its ID
+func (r *repository) InsertTaskByStruct(ctx context.Context, t *entity.Task) error {
+	ctx, span := tracing.Start(ctx, "task.repository.InsertTaskByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`INSERT INTO project_tasks
+			(project_id, assignee_id, title, description, estimate_hours, completed)
+			VALUES ({:project_id}, {:assignee_id}, {:title}, {:description}, {:estimate_hours}, {:completed})
+			RETURNING id`).
+			Bind(dbx.Params{
+				"project_id":     t.ProjectID,
+				"assignee_id":    t.AssigneeID,
+				"title":          t.Title,
+				"description":    t.Description,
+				"estimate_hours": t.EstimateHours,
+				"completed":      t.Completed,
+			}).Row(&t.ID)
+	})
+}
+
+// GetTaskByID will get and return a task by ID
+func (r *repository) GetTaskByID(ctx context.Context, taskID int) (*entity.Task, error) {
+	ctx, span := tracing.Start(ctx, "task.repository.GetTaskByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var t entity.Task
+	err := r.dbContext.Get().WithContext(ctx).Select().From("project_tasks").Where(dbx.HashExp{"id": taskID}).One(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("task %d not found", taskID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetTasksProgressByProjectID will return the tasks of a project with credit hours of linked
+// time entries rolled up as actual hours
+func (r *repository) GetTasksProgressByProjectID(ctx context.Context, projectID int) ([]entity.TaskProgress, error) {
+	ctx, span := tracing.Start(ctx, "task.repository.GetTasksProgressByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.TaskProgress
+	err := r.dbContext.Get().WithContext(ctx).
+		Select("t.*", "COALESCE(SUM(te.hours) FILTER (WHERE te.type = {:credit_type}), 0) AS actual_hours").
+		From("project_tasks t").
+		LeftJoin("project_time_entries te", dbx.NewExp("te.task_id = t.id")).
+		Where(dbx.HashExp{"t.project_id": projectID}).
+		Bind(dbx.Params{"credit_type": r.types.Credit}).
+		GroupBy("t.id").
+		OrderBy("t.id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetTaskDependenciesByProjectID will return every dependency between tasks of a project
+func (r *repository) GetTaskDependenciesByProjectID(ctx context.Context, projectID int) ([]entity.TaskDependency, error) {
+	ctx, span := tracing.Start(ctx, "task.repository.GetTaskDependenciesByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.TaskDependency
+	err := r.dbContext.Get().WithContext(ctx).Select("d.*").
+		From("project_task_dependencies d").
+		InnerJoin("project_tasks t", dbx.NewExp("t.id = d.task_id")).
+		Where(dbx.HashExp{"t.project_id": projectID}).
+		OrderBy("d.id ASC").
+		All(&list)
+	return list, err
+}
+
+// GetProjectScheduleByID will return the project row used as the forecast baseline
+func (r *repository) GetProjectScheduleByID(ctx context.Context, projectID int) (*entity.Project, error) {
+	ctx, span := tracing.Start(ctx, "task.repository.GetProjectScheduleByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var p entity.Project
+	err := r.dbContext.Get().WithContext(ctx).Select().From("projects").Where(dbx.HashExp{"id": projectID}).One(&p)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("project %d not found", projectID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdateTaskByStruct will update a task by ID
+func (r *repository) UpdateTaskByStruct(ctx context.Context, t *entity.Task) error {
+	ctx, span := tracing.Start(ctx, "task.repository.UpdateTaskByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Update("project_tasks", dbx.Params{
+			"assignee_id":    t.AssigneeID,
+			"title":          t.Title,
+			"description":    t.Description,
+			"estimate_hours": t.EstimateHours,
+			"completed":      t.Completed,
+		}, dbx.HashExp{"id": t.ID}).Execute()
+		return err
+	})
+}
+
+// DeleteTaskByID will delete a task by ID, its dependencies cascade
+func (r *repository) DeleteTaskByID(ctx context.Context, taskID int) error {
+	ctx, span := tracing.Start(ctx, "task.repository.DeleteTaskByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("project_tasks", dbx.HashExp{"id": taskID}).Execute()
+		return err
+	})
+}
+
+// InsertTaskDependencyByStruct will insert a finish-to-start dependency after checking that both
+// tasks belong to the same project and that the new link does not close a cycle
+func (r *repository) InsertTaskDependencyByStruct(ctx context.Context, d *entity.TaskDependency) error {
+	ctx, span := tracing.Start(ctx, "task.repository.InsertTaskDependencyByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		// 1. both tasks must belong to the same project
+		var projectIDs []int
+		err := tx.NewQuery("SELECT project_id FROM project_tasks WHERE id IN ({:task_id}, {:depends_on_id})").
+			Bind(dbx.Params{"task_id": d.TaskID, "depends_on_id": d.DependsOnID}).
+			Column(&projectIDs)
+		if err != nil {
+			return err
+		}
+		if len(projectIDs) != 2 || projectIDs[0] != projectIDs[1] {
+			return ErrCrossProjectDependency
+		}
+
+		// serialize dependency inserts per project so concurrent links cannot close a cycle together
+		_, err = tx.NewQuery("SELECT pg_advisory_xact_lock({:lock_class}, {:project_id})").
+			Bind(dbx.Params{"lock_class": dependencyLockClass, "project_id": projectIDs[0]}).
+			Execute()
+		if err != nil {
+			return err
+		}
+
+		// 2. a cycle exists when depends_on_id already (transitively) depends on task_id
+		var cycle bool
+		err = tx.NewQuery(`WITH RECURSIVE upstream(id) AS (
+				SELECT depends_on_id FROM project_task_dependencies WHERE task_id = {:depends_on_id}
+				UNION
+				SELECT d.depends_on_id FROM project_task_dependencies d
+				INNER JOIN upstream u ON d.task_id = u.id
+			)
+			SELECT EXISTS (SELECT 1 FROM upstream WHERE id = {:task_id})`).
+			Bind(dbx.Params{"task_id": d.TaskID, "depends_on_id": d.DependsOnID}).
+			Row(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrDependencyCycle
+		}
+
+		// 3. insert, ignoring an already existing identical link
+		return tx.NewQuery(`INSERT INTO project_task_dependencies (task_id, depends_on_id)
+			VALUES ({:task_id}, {:depends_on_id})
+			ON CONFLICT (task_id, depends_on_id) DO UPDATE SET task_id = EXCLUDED.task_id
+			RETURNING id`).
+			Bind(dbx.Params{"task_id": d.TaskID, "depends_on_id": d.DependsOnID}).
+			Row(&d.ID)
+	})
+}
+
+// DeleteTaskDependency will remove the dependency of taskID on dependsOnID
+func (r *repository) DeleteTaskDependency(ctx context.Context, taskID, dependsOnID int) error {
+	ctx, span := tracing.Start(ctx, "task.repository.DeleteTaskDependency")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("project_task_dependencies", dbx.HashExp{
+			"task_id":       taskID,
+			"depends_on_id": dependsOnID,
+		}).Execute()
+		return err
+	})
+}
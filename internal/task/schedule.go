@@ -0,0 +1,153 @@
+package task
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+const (
+	// hoursPerWorkingDay is the pace of unassigned tasks and of assignees without capacity
+	hoursPerWorkingDay = 8
+	// workingDaysPerWeek spreads the weekly hours of a consultant capacity over the weekdays
+	workingDaysPerWeek = 5
+)
+
+// criticalPath runs a forward and backward pass over the task graph in topological order.
+// Completed tasks take no time; open tasks take their estimate less the hours already logged.
+// It returns the schedule per task, the ordered critical path and the total remaining hours.
+func criticalPath(tasks []entity.TaskProgress, deps []entity.TaskDependency) ([]entity.TaskSchedule, []int, float32, error) {
+	index := make(map[int]int, len(tasks))
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+
+	// successors and in-degrees of the finish-to-start graph
+	successors := make([][]int, len(tasks))
+	predecessors := make([][]int, len(tasks))
+	inDegree := make([]int, len(tasks))
+	for _, d := range deps {
+		from, okFrom := index[d.DependsOnID]
+		to, okTo := index[d.TaskID]
+		if !okFrom || !okTo {
+			continue
+		}
+		successors[from] = append(successors[from], to)
+		predecessors[to] = append(predecessors[to], from)
+		inDegree[to]++
+	}
+
+	// Kahn topological order
+	order := make([]int, 0, len(tasks))
+	queue := []int{}
+	for i := range tasks {
+		if inDegree[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		order = append(order, n)
+		for _, s := range successors[n] {
+			inDegree[s]--
+			if inDegree[s] == 0 {
+				queue = append(queue, s)
+			}
+		}
+	}
+	if len(order) != len(tasks) {
+		return nil, nil, 0, ErrDependencyCycle
+	}
+
+	schedule := make([]entity.TaskSchedule, len(tasks))
+	for i, t := range tasks {
+		remaining := float32(0)
+		if !t.Completed {
+			remaining = float32(math.Max(float64(t.EstimateHours-t.ActualHours), 0))
+		}
+		schedule[i] = entity.TaskSchedule{TaskID: t.ID, RemainingHours: remaining}
+	}
+
+	// forward pass: earliest start is the latest earliest finish of all predecessors
+	var total float32
+	for _, n := range order {
+		for _, p := range predecessors[n] {
+			if schedule[p].EarliestFinish > schedule[n].EarliestStart {
+				schedule[n].EarliestStart = schedule[p].EarliestFinish
+			}
+		}
+		schedule[n].EarliestFinish = schedule[n].EarliestStart + schedule[n].RemainingHours
+		if schedule[n].EarliestFinish > total {
+			total = schedule[n].EarliestFinish
+		}
+	}
+
+	// backward pass: latest finish is the earliest latest start of all successors
+	for i := len(order) - 1; i >= 0; i-- {
+		n := order[i]
+		schedule[n].LatestFinish = total
+		for _, s := range successors[n] {
+			if schedule[s].LatestStart < schedule[n].LatestFinish {
+				schedule[n].LatestFinish = schedule[s].LatestStart
+			}
+		}
+		schedule[n].LatestStart = schedule[n].LatestFinish - schedule[n].RemainingHours
+		schedule[n].Slack = schedule[n].LatestStart - schedule[n].EarliestStart
+		schedule[n].Critical = schedule[n].Slack < 0.01 && schedule[n].RemainingHours > 0
+	}
+
+	path := []int{}
+	for _, n := range order {
+		if schedule[n].Critical {
+			path = append(path, n)
+		}
+	}
+	sort.SliceStable(path, func(i, j int) bool {
+		return schedule[path[i]].EarliestStart < schedule[path[j]].EarliestStart
+	})
+	for i, n := range path {
+		path[i] = schedule[n].TaskID
+	}
+
+	return schedule, path, total, nil
+}
+
+// forecastHoursPerDay is the pace of the critical path, every critical task is worked at the daily
+// hours of its assignee so a chain takes the sum of the working days of its tasks. dailyHours maps
+// assignees to their daily hours, unassigned tasks and assignees without capacity take
+// hoursPerWorkingDay.
+func forecastHoursPerDay(tasks []entity.TaskProgress, schedule []entity.TaskSchedule, dailyHours map[int]float32) float32 {
+	var hours, days float32
+	for i, t := range tasks {
+		if !schedule[i].Critical {
+			continue
+		}
+		pace := float32(hoursPerWorkingDay)
+		if t.AssigneeID != nil && dailyHours[*t.AssigneeID] > 0 {
+			pace = dailyHours[*t.AssigneeID]
+		}
+		hours += schedule[i].RemainingHours
+		days += schedule[i].RemainingHours / pace
+	}
+	if days == 0 {
+		return hoursPerWorkingDay
+	}
+	return hours / days
+}
+
+// addWorkingHours moves start forward by the given hours worked at hoursPerDay, counting only
+// weekdays that are not in holidays, a set of time.DateOnly dates
+func addWorkingHours(start time.Time, hours, hoursPerDay float32, holidays map[string]bool) time.Time {
+	days := int(math.Ceil(float64(hours / hoursPerDay)))
+	end := start
+	for days > 0 {
+		end = end.AddDate(0, 0, 1)
+		if end.Weekday() != time.Saturday && end.Weekday() != time.Sunday && !holidays[end.Format(time.DateOnly)] {
+			days--
+		}
+	}
+	return end
+}
@@ -0,0 +1,180 @@
+package task
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// open task of the given estimate
+func task(id int, estimate float32) entity.TaskProgress {
+	return entity.TaskProgress{Task: entity.Task{ID: id, EstimateHours: estimate}}
+}
+
+// dependency of task on dependsOn
+func dep(task, dependsOn int) entity.TaskDependency {
+	return entity.TaskDependency{TaskID: task, DependsOnID: dependsOn}
+}
+
+func TestCriticalPath(t *testing.T) {
+	logged := task(2, 10)
+	logged.ActualHours = 4
+	done := task(3, 10)
+	done.Completed = true
+
+	tests := []struct {
+		name      string
+		tasks     []entity.TaskProgress
+		deps      []entity.TaskDependency
+		wantPath  []int
+		wantTotal float32
+		wantSlack map[int]float32
+	}{
+		{
+			// 1 fans out to 2 and 3 which join in 4, the longer branch through 3 is critical
+			name:      "diamond",
+			tasks:     []entity.TaskProgress{task(1, 4), task(2, 2), task(3, 6), task(4, 3)},
+			deps:      []entity.TaskDependency{dep(2, 1), dep(3, 1), dep(4, 2), dep(4, 3)},
+			wantPath:  []int{1, 3, 4},
+			wantTotal: 13,
+			wantSlack: map[int]float32{1: 0, 2: 4, 3: 0, 4: 0},
+		},
+		{
+			// independent chains 1 → 2 and 3 → 4, the shorter one floats by the difference
+			name:      "parallel chains",
+			tasks:     []entity.TaskProgress{task(1, 2), task(2, 2), task(3, 5), task(4, 5)},
+			deps:      []entity.TaskDependency{dep(2, 1), dep(4, 3)},
+			wantPath:  []int{3, 4},
+			wantTotal: 10,
+			wantSlack: map[int]float32{1: 6, 2: 6, 3: 0, 4: 0},
+		},
+		{
+			// a zero duration task on the chain takes no time and is never critical
+			name:      "zero duration",
+			tasks:     []entity.TaskProgress{task(1, 3), task(2, 0), task(3, 3)},
+			deps:      []entity.TaskDependency{dep(2, 1), dep(3, 2)},
+			wantPath:  []int{1, 3},
+			wantTotal: 6,
+			wantSlack: map[int]float32{1: 0, 2: 0, 3: 0},
+		},
+		{
+			// logged hours reduce the remaining work and completed tasks take none
+			name:      "progress",
+			tasks:     []entity.TaskProgress{task(1, 2), logged, done},
+			deps:      []entity.TaskDependency{dep(2, 1), dep(3, 2)},
+			wantPath:  []int{1, 2},
+			wantTotal: 8,
+			wantSlack: map[int]float32{1: 0, 2: 0, 3: 0},
+		},
+		{
+			// dependencies on tasks of other projects are ignored
+			name:      "unknown dependency",
+			tasks:     []entity.TaskProgress{task(1, 2)},
+			deps:      []entity.TaskDependency{dep(1, 99)},
+			wantPath:  []int{1},
+			wantTotal: 2,
+			wantSlack: map[int]float32{1: 0},
+		},
+		{
+			name:      "no tasks",
+			wantPath:  []int{},
+			wantTotal: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, path, total, err := criticalPath(tt.tasks, tt.deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(path, tt.wantPath) || total != tt.wantTotal {
+				t.Fatalf("path = %v, total = %v, want %v, %v", path, total, tt.wantPath, tt.wantTotal)
+			}
+			for _, s := range schedule {
+				if want := tt.wantSlack[s.TaskID]; s.Slack != want {
+					t.Errorf("task %d: slack = %v, want %v", s.TaskID, s.Slack, want)
+				}
+				if s.EarliestFinish-s.EarliestStart != s.RemainingHours || s.LatestFinish-s.LatestStart != s.RemainingHours {
+					t.Errorf("task %d: schedule %+v does not span its remaining hours", s.TaskID, s)
+				}
+			}
+		})
+	}
+}
+
+func TestCriticalPathCycle(t *testing.T) {
+	tasks := []entity.TaskProgress{task(1, 1), task(2, 1), task(3, 1)}
+	deps := []entity.TaskDependency{dep(2, 1), dep(3, 2), dep(1, 3)}
+
+	if _, _, _, err := criticalPath(tasks, deps); !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("err = %v, want ErrDependencyCycle", err)
+	}
+}
+
+func TestAddWorkingHours(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	monday := map[string]bool{"2026-10-19": true}
+	saturday := map[string]bool{"2026-10-17": true}
+	tests := []struct {
+		hours       float32
+		hoursPerDay float32
+		holidays    map[string]bool
+		want        time.Time
+	}{
+		{0, 8, nil, friday},
+		{1, 8, nil, friday.AddDate(0, 0, 3)},  // a part day ends on monday
+		{8, 8, nil, friday.AddDate(0, 0, 3)},  // over the weekend
+		{9, 8, nil, friday.AddDate(0, 0, 4)},  // into tuesday
+		{40, 8, nil, friday.AddDate(0, 0, 7)}, // a working week
+		{8, 4, nil, friday.AddDate(0, 0, 4)},  // part time takes two days
+		{16, 6.4, nil, friday.AddDate(0, 0, 5)},
+		{8, 8, monday, friday.AddDate(0, 0, 4)},   // the holiday is skipped
+		{40, 8, monday, friday.AddDate(0, 0, 10)}, // a working week with a holiday ends the monday after
+		{8, 8, saturday, friday.AddDate(0, 0, 3)}, // a holiday on a weekend takes nothing
+	}
+	for _, tt := range tests {
+		if got := addWorkingHours(friday, tt.hours, tt.hoursPerDay, tt.holidays); !got.Equal(tt.want) {
+			t.Errorf("addWorkingHours(friday, %v, %v, %v) = %s, want %s", tt.hours, tt.hoursPerDay, tt.holidays, got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestForecastHoursPerDay(t *testing.T) {
+	// open task of the given estimate assigned to a consultant
+	assigned := func(id int, estimate float32, assigneeID int) entity.TaskProgress {
+		a := task(id, estimate)
+		a.AssigneeID = &assigneeID
+		return a
+	}
+	dailyHours := map[int]float32{7: 4, 8: 8, 9: 0}
+
+	tests := []struct {
+		name  string
+		tasks []entity.TaskProgress
+		deps  []entity.TaskDependency
+		want  float32
+	}{
+		{"no tasks", nil, nil, hoursPerWorkingDay},
+		{"unassigned", []entity.TaskProgress{task(1, 16)}, nil, hoursPerWorkingDay},
+		{"part time", []entity.TaskProgress{assigned(1, 16, 7)}, nil, 4},
+		{"no capacity", []entity.TaskProgress{assigned(1, 16, 9)}, nil, hoursPerWorkingDay},
+		// 8h at 4h a day then 8h at 8h a day: 16 hours in 3 days
+		{"chain", []entity.TaskProgress{assigned(1, 8, 7), assigned(2, 8, 8)}, []entity.TaskDependency{dep(2, 1)}, 16.0 / 3},
+		// the parallel task has slack, only the critical one sets the pace
+		{"slack", []entity.TaskProgress{assigned(1, 16, 8), assigned(2, 4, 7)}, nil, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, _, _, err := criticalPath(tt.tasks, tt.deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := forecastHoursPerDay(tt.tasks, schedule, dailyHours); got != tt.want {
+				t.Fatalf("hours per day = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
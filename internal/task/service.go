@@ -0,0 +1,202 @@
+package task
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/capacity"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// ErrInvalidTask is returned when a task fails validation
+var ErrInvalidTask = apperror.New(apperror.KindValidation, "invalid task")
+
+type Service interface {
+	InsertTaskByStruct(ctx context.Context, t *Task) error
+	GetTaskByID(ctx context.Context, taskID int) (*Task, error)
+	GetTasksByProjectID(ctx context.Context, projectID int) ([]TaskProgress, error)
+	UpdateTaskByStruct(ctx context.Context, t *Task) error
+	DeleteTaskByID(ctx context.Context, taskID int) error
+	InsertTaskDependency(ctx context.Context, taskID, dependsOnID int) (*entity.TaskDependency, error)
+	DeleteTaskDependency(ctx context.Context, taskID, dependsOnID int) error
+	GetProjectForecastByProjectID(ctx context.Context, projectID int) (*ProjectForecast, error)
+}
+
+// Service
+type service struct {
+	repo       Repository
+	capacities capacity.Repository
+	logger     *logger.Logger
+}
+
+type Task struct {
+	entity.Task
+}
+
+type TaskProgress struct {
+	entity.TaskProgress
+}
+
+type ProjectForecast struct {
+	entity.ProjectForecast
+}
+
+// NewService returns a task service forecasting with the capacities and holidays of capacities
+func NewService(repo Repository, capacities capacity.Repository, logger *logger.Logger) Service {
+	return &service{repo, capacities, logger}
+}
+
+// validate checks required fields of a task
+func (t *Task) validate() error {
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" {
+		return ErrInvalidTask.WithField("title", "title is required")
+	}
+	if t.EstimateHours < 0 {
+		return ErrInvalidTask.WithField("estimateHours", "estimateHours must not be negative")
+	}
+	return nil
+}
+
+func (s *service) InsertTaskByStruct(ctx context.Context, t *Task) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	return s.repo.InsertTaskByStruct(ctx, &t.Task)
+}
+
+func (s *service) GetTaskByID(ctx context.Context, taskID int) (*Task, error) {
+	t, err := s.repo.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return &Task{}, err
+	}
+	return &Task{*t}, nil
+}
+
+// GetTasksByProjectID returns the tasks of a project with actual hours and the IDs they depend on
+func (s *service) GetTasksByProjectID(ctx context.Context, projectID int) ([]TaskProgress, error) {
+	tasks, err := s.repo.GetTasksProgressByProjectID(ctx, projectID)
+	if err != nil {
+		return []TaskProgress{}, err
+	}
+	deps, err := s.repo.GetTaskDependenciesByProjectID(ctx, projectID)
+	if err != nil {
+		return []TaskProgress{}, err
+	}
+
+	dependsOn := make(map[int][]int)
+	for _, d := range deps {
+		dependsOn[d.TaskID] = append(dependsOn[d.TaskID], d.DependsOnID)
+	}
+
+	results := []TaskProgress{}
+	for _, t := range tasks {
+		t.DependsOn = dependsOn[t.ID]
+		results = append(results, TaskProgress{t})
+	}
+	return results, nil
+}
+
+func (s *service) UpdateTaskByStruct(ctx context.Context, t *Task) error {
+	if err := t.validate(); err != nil {
+		return err
+	}
+	return s.repo.UpdateTaskByStruct(ctx, &t.Task)
+}
+
+func (s *service) DeleteTaskByID(ctx context.Context, taskID int) error {
+	return s.repo.DeleteTaskByID(ctx, taskID)
+}
+
+func (s *service) InsertTaskDependency(ctx context.Context, taskID, dependsOnID int) (*entity.TaskDependency, error) {
+	if taskID == dependsOnID {
+		return nil, ErrDependencyCycle
+	}
+	d := &entity.TaskDependency{TaskID: taskID, DependsOnID: dependsOnID}
+	if err := s.repo.InsertTaskDependencyByStruct(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *service) DeleteTaskDependency(ctx context.Context, taskID, dependsOnID int) error {
+	return s.repo.DeleteTaskDependency(ctx, taskID, dependsOnID)
+}
+
+// GetProjectForecastByProjectID computes the earliest finish of the remaining work along the
+// critical path and compares the resulting end date to the projected end date of the project.
+// Remaining work starts at the project start (actual, else projected), or now if that has passed,
+// critical tasks are worked at the capacity of their assignees on weekdays that are not holidays.
+func (s *service) GetProjectForecastByProjectID(ctx context.Context, projectID int) (*ProjectForecast, error) {
+	project, err := s.repo.GetProjectScheduleByID(ctx, projectID)
+	if err != nil {
+		return &ProjectForecast{}, err
+	}
+	tasks, err := s.repo.GetTasksProgressByProjectID(ctx, projectID)
+	if err != nil {
+		return &ProjectForecast{}, err
+	}
+	deps, err := s.repo.GetTaskDependenciesByProjectID(ctx, projectID)
+	if err != nil {
+		return &ProjectForecast{}, err
+	}
+
+	schedule, path, remaining, err := criticalPath(tasks, deps)
+	if err != nil {
+		return &ProjectForecast{}, err
+	}
+
+	start := project.StartDate
+	if start.IsZero() {
+		start = project.ProjectedStartDate
+	}
+	if now := time.Now(); start.Before(now) {
+		start = now
+	}
+
+	dailyHours := map[int]float32{}
+	for i, t := range tasks {
+		if !schedule[i].Critical || t.AssigneeID == nil {
+			continue
+		}
+		if _, ok := dailyHours[*t.AssigneeID]; ok {
+			continue
+		}
+		c, err := s.capacities.GetCapacityByConsultantID(ctx, *t.AssigneeID)
+		if err != nil {
+			return &ProjectForecast{}, err
+		}
+		dailyHours[*t.AssigneeID] = c.WeeklyHours * c.PartTimePercent / 100 / workingDaysPerWeek
+	}
+	hoursPerDay := forecastHoursPerDay(tasks, schedule, dailyHours)
+
+	// weekends and holidays at most double the working days, a month more covers short forecasts
+	days := int(math.Ceil(float64(remaining / hoursPerDay)))
+	list, err := s.capacities.GetHolidaysInRange(ctx, start, start.AddDate(0, 0, 2*days+31))
+	if err != nil {
+		return &ProjectForecast{}, err
+	}
+	holidays := make(map[string]bool, len(list))
+	for _, h := range list {
+		holidays[h.HolidayDate.Format(time.DateOnly)] = true
+	}
+	end := addWorkingHours(start, remaining, hoursPerDay, holidays)
+
+	forecast := entity.ProjectForecast{
+		ProjectID:        projectID,
+		ForecastStart:    start,
+		ForecastEndDate:  end,
+		ProjectedEndDate: project.ProjectedEndDate,
+		RemainingHours:   remaining,
+		CriticalPath:     path,
+		Schedule:         schedule,
+	}
+	if !project.ProjectedEndDate.IsZero() {
+		forecast.VarianceDays = int(math.Ceil(end.Sub(project.ProjectedEndDate).Hours() / 24))
+	}
+	return &ProjectForecast{forecast}, nil
+}
@@ -0,0 +1,92 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/capacity"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// monday is the start of the forecasts, far enough ahead not to be replaced by now
+var monday = time.Date(2099, 1, 5, 0, 0, 0, 0, time.UTC)
+
+// fakeRepository has one project starting on monday with the given tasks and dependencies
+type fakeRepository struct {
+	Repository
+	tasks []entity.TaskProgress
+	deps  []entity.TaskDependency
+}
+
+func (f *fakeRepository) GetProjectScheduleByID(ctx context.Context, projectID int) (*entity.Project, error) {
+	return &entity.Project{ID: projectID, StartDate: monday}, nil
+}
+
+func (f *fakeRepository) GetTasksProgressByProjectID(ctx context.Context, projectID int) ([]entity.TaskProgress, error) {
+	return f.tasks, nil
+}
+
+func (f *fakeRepository) GetTaskDependenciesByProjectID(ctx context.Context, projectID int) ([]entity.TaskDependency, error) {
+	return f.deps, nil
+}
+
+// fakeCapacities has consultant 7 working half time and a holiday on the wednesday after monday
+type fakeCapacities struct {
+	capacity.Repository
+}
+
+func (f *fakeCapacities) GetCapacityByConsultantID(ctx context.Context, consultantID int) (*entity.ConsultantCapacity, error) {
+	c := entity.ConsultantCapacity{ConsultantID: consultantID, WeeklyHours: capacity.DefaultWeeklyHours, PartTimePercent: capacity.DefaultPartTimePercent}
+	if consultantID == 7 {
+		c.PartTimePercent = 50
+	}
+	return &c, nil
+}
+
+func (f *fakeCapacities) GetHolidaysInRange(ctx context.Context, from, to time.Time) ([]entity.Holiday, error) {
+	wednesday := monday.AddDate(0, 0, 2)
+	if wednesday.Before(from) || wednesday.After(to) {
+		return nil, nil
+	}
+	return []entity.Holiday{{HolidayDate: wednesday, Name: "Holiday"}}, nil
+}
+
+func TestGetProjectForecastByProjectID(t *testing.T) {
+	halfTime, fullTime := 7, 8
+	assigned := func(id int, estimate float32, assigneeID *int) entity.TaskProgress {
+		a := task(id, estimate)
+		a.AssigneeID = assigneeID
+		return a
+	}
+
+	tests := []struct {
+		name  string
+		tasks []entity.TaskProgress
+		deps  []entity.TaskDependency
+		want  time.Time
+	}{
+		// two days tuesday and thursday around the holiday
+		{"unassigned", []entity.TaskProgress{task(1, 16)}, nil, monday.AddDate(0, 0, 3)},
+		{"full time", []entity.TaskProgress{assigned(1, 16, &fullTime)}, nil, monday.AddDate(0, 0, 3)},
+		// four days at 4 hours a day, tuesday to friday and the next monday
+		{"half time", []entity.TaskProgress{assigned(1, 16, &halfTime)}, nil, monday.AddDate(0, 0, 7)},
+		// one day then two days, tuesday to friday
+		{"chain", []entity.TaskProgress{assigned(1, 8, &fullTime), assigned(2, 8, &halfTime)}, []entity.TaskDependency{dep(2, 1)}, monday.AddDate(0, 0, 4)},
+		{"no work", nil, nil, monday},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(&fakeRepository{tasks: tt.tasks, deps: tt.deps}, &fakeCapacities{}, logger.New().Prefix("Task Test"))
+			f, err := s.GetProjectForecastByProjectID(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !f.ForecastStart.Equal(monday) || !f.ForecastEndDate.Equal(tt.want) {
+				t.Fatalf("forecast %s to %s, want %s to %s", f.ForecastStart.Format(time.DateOnly), f.ForecastEndDate.Format(time.DateOnly),
+					monday.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+}
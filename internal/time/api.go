@@ -0,0 +1,164 @@
+package time
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+// Handler serves the time entry routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the time entry routes through service, invalidating the cached
+// values embedding time entries in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
+
+// Routes registers the time entry routes, mounted under /time
+func (h *Handler) Routes(r chi.Router) {
+	r.Post("/", h.CreateTimeEntry)
+	r.Get("/project/{projectID}", h.GetTimeEntryHistoryByProjectID)
+	r.Get("/consultant/{consultantID}", h.GetTimeEntryHistoryByConsultantID)
+	r.Get("/{timeEntryID}", h.GetTimeEntryByID)
+	r.Put("/{timeEntryID}", h.UpdateTimeEntry)
+	r.Delete("/{timeEntryID}", h.DeleteTimeEntry)
+}
+
+// Clears cached values which embed the time entries of a project, task actuals are cleared by the service
+func (h *Handler) invalidateProjectTime(projectID int) {
+	h.cache.Invalidate(
+		fmt.Sprintf("projects:meta:%d", projectID),
+		"metrics_dashboard",
+	)
+}
+
+// Loads the time entry in the url
+func (h *Handler) getTimeEntry(w http.ResponseWriter, r *http.Request) (*TimeEntry, bool) {
+	timeEntryID, err := problem.IntParam(w, r, "timeEntryID")
+	if err != nil {
+		return nil, false
+	}
+
+	e, err := h.service.GetTimeEntryByTimeEntryID(r.Context(), timeEntryID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return e, true
+}
+
+// GetTimeEntryHistoryByProjectID returns all time entries of a project
+func (h *Handler) GetTimeEntryHistoryByProjectID(w http.ResponseWriter, r *http.Request) {
+	projectID, err := problem.IntParam(w, r, "projectID")
+	if err != nil {
+		return
+	}
+
+	timeEntries, err := h.service.GetTimeEntryHistoryByProjectID(r.Context(), projectID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(timeEntries)
+}
+
+// GetTimeEntryHistoryByConsultantID returns all time entries logged by a consultant
+func (h *Handler) GetTimeEntryHistoryByConsultantID(w http.ResponseWriter, r *http.Request) {
+	consultantID, err := problem.IntParam(w, r, "consultantID")
+	if err != nil {
+		return
+	}
+
+	timeEntries, err := h.service.GetTimeEntryHistoryByConsultantID(r.Context(), consultantID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(timeEntries)
+}
+
+// GetTimeEntryByID returns a single time entry
+func (h *Handler) GetTimeEntryByID(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.getTimeEntry(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// CreateTimeEntry logs a time entry from the request body, optionally linked to a milestone or task
+// of its project
+func (h *Handler) CreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	var e TimeEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid time entry body"))
+		return
+	}
+	e.ID = 0
+
+	if err := h.service.InsertTimeEntryByStruct(r.Context(), &e); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectTime(e.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// UpdateTimeEntry replaces a time entry with the request body, the entry stays on its project
+func (h *Handler) UpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getTimeEntry(w, r)
+	if !ok {
+		return
+	}
+
+	var e TimeEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid time entry body"))
+		return
+	}
+	e.ID = existing.ID
+	e.ProjectID = existing.ProjectID
+
+	if err := h.service.UpdateTimeEntryByStruct(r.Context(), &e); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectTime(e.ProjectID)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// DeleteTimeEntry removes a time entry
+func (h *Handler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	e, ok := h.getTimeEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTimeEntryByTimeEntryID(r.Context(), e.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+	h.invalidateProjectTime(e.ProjectID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package time
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// stubService serves time entry 1 of project 10 and records deletions
+type stubService struct {
+	Service
+	deleted []int
+}
+
+func (s *stubService) GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*TimeEntry, error) {
+	if id != 1 {
+		return nil, apperror.NotFound("time entry %d not found", id)
+	}
+	return &TimeEntry{entity.TimeEntry{ID: 1, ProjectID: 10, Type: types.Debit, Hours: 2}}, nil
+}
+
+func (s *stubService) GetTimeEntryHistoryByProjectID(ctx context.Context, projectID int) ([]TimeEntry, error) {
+	return []TimeEntry{}, nil
+}
+
+func (s *stubService) InsertTimeEntryByStruct(ctx context.Context, e *TimeEntry) error {
+	if err := e.validate(types); err != nil {
+		return err
+	}
+	e.ID = 2
+	return nil
+}
+
+func (s *stubService) UpdateTimeEntryByStruct(ctx context.Context, e *TimeEntry) error {
+	return e.validate(types)
+}
+
+func (s *stubService) DeleteTimeEntryByTimeEntryID(ctx context.Context, id int) error {
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func newTestRouter(s Service) http.Handler {
+	r := chi.NewRouter()
+	r.Route("/time", NewHandler(s, nil).Routes)
+	return r
+}
+
+func TestTimeEntryRoutes(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodGet, "/time/1", "", http.StatusOK},
+		{http.MethodGet, "/time/3", "", http.StatusNotFound},
+		{http.MethodGet, "/time/first", "", http.StatusBadRequest},
+		{http.MethodGet, "/time/project/10", "", http.StatusOK},
+		{http.MethodPost, "/time", `{"projectID":10,"type":"billable","hours":1.5,"taskID":4}`, http.StatusCreated},
+		{http.MethodPost, "/time", `{"projectID":10,"type":"overtime","hours":1}`, http.StatusBadRequest},
+		{http.MethodPost, "/time", `{`, http.StatusBadRequest},
+		{http.MethodPut, "/time/3", `{"type":"billable","hours":1}`, http.StatusNotFound},
+		{http.MethodDelete, "/time/3", "", http.StatusNotFound},
+		{http.MethodDelete, "/time/1", "", http.StatusNoContent},
+	}
+	s := &stubService{}
+	router := newTestRouter(s)
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body.String())
+		}
+	}
+	if len(s.deleted) != 1 || s.deleted[0] != 1 {
+		t.Fatalf("deleted = %v, want time entry 1 once", s.deleted)
+	}
+}
+
+func TestUpdateTimeEntryKeepsProject(t *testing.T) {
+	w := httptest.NewRecorder()
+	body := `{"id":7,"projectID":99,"type":"absence","hours":8}`
+	newTestRouter(&stubService{}).ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/time/1", strings.NewReader(body)))
+
+	var e entity.TimeEntry
+	if err := json.NewDecoder(w.Body).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || e.ID != 1 || e.ProjectID != 10 {
+		t.Fatalf("status = %d, entry = %+v, want time entry 1 of project 10", w.Code, e)
+	}
+}
@@ -2,11 +2,18 @@ package time
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
 	"github.com/renniemaharaj/project-list-go/internal/database"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"github.com/renniemaharaj/project-list-go/internal/webhook"
 )
 
 type Repository interface {
@@ -28,26 +35,80 @@ func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository
 	return &repository{dbContext, _l}
 }
 
-// InsertTimeEntryByStruct will insert a time entry to project_time_entries table
+// InsertTimeEntryByStruct will insert a time entry to project_time_entries table, set its ID and
+// enqueue a time_entry.created event
 func (r *repository) InsertTimeEntryByStruct(ctx context.Context, e *entity.TimeEntry) error {
+	ctx, span := tracing.Start(ctx, "time.repository.InsertTimeEntryByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
-		_, err := tx.Insert("project_time_entries", dbx.Params{
-			"hours":         e.Hours,
-			"title":         e.Title,
-			"description":   e.Description,
-			"consultant_id": e.ConsultantID,
-			"project_id":    e.ProjectID,
-			"type":          e.Type,
-			"entry_date":    e.EntryDate,
-		}).Execute()
-		return err
+		if err := checkLinkedProject(tx, e); err != nil {
+			return err
+		}
+		err := tx.NewQuery(`INSERT INTO project_time_entries
+			(hours, title, description, consultant_id, project_id, type, entry_date, milestone_id, task_id)
+			VALUES ({:hours}, {:title}, {:description}, {:consultant_id}, {:project_id}, {:type}, {:entry_date}, {:milestone_id}, {:task_id})
+			RETURNING id`).
+			Bind(dbx.Params{
+				"hours":         e.Hours,
+				"title":         e.Title,
+				"description":   e.Description,
+				"consultant_id": e.ConsultantID,
+				"project_id":    e.ProjectID,
+				"type":          e.Type,
+				"entry_date":    e.EntryDate,
+				"milestone_id":  e.MilestoneID,
+				"task_id":       e.TaskID,
+			}).Row(&e.ID)
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeTimeEntryCreated, e.ProjectID, e)
 	})
 }
 
+// checkLinkedProject makes sure the milestone and task an entry is linked to belong to its project,
+// otherwise task actuals and milestone rollups would count hours of other projects
+func checkLinkedProject(tx *dbx.Tx, e *entity.TimeEntry) error {
+	links := []struct {
+		id    *int
+		table string
+		field string
+		name  string
+	}{
+		{e.MilestoneID, "project_milestones", "milestoneID", "milestone"},
+		{e.TaskID, "project_tasks", "taskID", "task"},
+	}
+	for _, link := range links {
+		if link.id == nil {
+			continue
+		}
+		var ok bool
+		err := tx.NewQuery("SELECT EXISTS (SELECT 1 FROM " + link.table + " WHERE id = {:id} AND project_id = {:project_id})").
+			Bind(dbx.Params{"id": *link.id, "project_id": e.ProjectID}).
+			Row(&ok)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTimeEntry.WithField(link.field, fmt.Sprintf("%s %d does not belong to project %d", link.name, *link.id, e.ProjectID))
+		}
+	}
+	return nil
+}
+
 // GetTimeEntryByTimeEntryID will return a specific time entry by ID
 func (r *repository) GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*entity.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "time.repository.GetTimeEntryByTimeEntryID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var e entity.TimeEntry
 	err := r.dbContext.Get().WithContext(ctx).Select().From("project_time_entries").Where(dbx.HashExp{"id": id}).One(&e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("time entry %d not found", id)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +117,11 @@ func (r *repository) GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*en
 
 // GetTimeEntryHistoryByProjectsIDS will return all time entries for multiple projects
 func (r *repository) GetTimeEntryHistoryByProjectsIDS(ctx context.Context, projectIDS []int) ([]entity.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "time.repository.GetTimeEntryHistoryByProjectsIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.TimeEntry
 
 	args := make([]interface{}, len(projectIDS))
@@ -73,6 +139,11 @@ func (r *repository) GetTimeEntryHistoryByProjectsIDS(ctx context.Context, proje
 
 // GetTimeEntryHistoryByProjectID will return all time entries for project
 func (r *repository) GetTimeEntryHistoryByProjectID(ctx context.Context, projectID int) ([]entity.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "time.repository.GetTimeEntryHistoryByProjectID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.TimeEntry
 	err := r.dbContext.Get().WithContext(ctx).Select().
 		From("project_time_entries").
@@ -84,6 +155,11 @@ func (r *repository) GetTimeEntryHistoryByProjectID(ctx context.Context, project
 
 // GetTimeEntryHistoryByConsultantID will return all time entries by consultant
 func (r *repository) GetTimeEntryHistoryByConsultantID(ctx context.Context, consultantID int) ([]entity.TimeEntry, error) {
+	ctx, span := tracing.Start(ctx, "time.repository.GetTimeEntryHistoryByConsultantID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
 	var list []entity.TimeEntry
 	err := r.dbContext.Get().WithContext(ctx).Select().
 		From("project_time_entries").
@@ -95,7 +171,13 @@ func (r *repository) GetTimeEntryHistoryByConsultantID(ctx context.Context, cons
 
 // UpdateTimeEntryByStruct will update a time entry by ID
 func (r *repository) UpdateTimeEntryByStruct(ctx context.Context, e *entity.TimeEntry) error {
+	ctx, span := tracing.Start(ctx, "time.repository.UpdateTimeEntryByStruct")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		if err := checkLinkedProject(tx, e); err != nil {
+			return err
+		}
 		_, err := tx.Update("project_time_entries", dbx.Params{
 			"hours":         e.Hours,
 			"title":         e.Title,
@@ -104,15 +186,29 @@ func (r *repository) UpdateTimeEntryByStruct(ctx context.Context, e *entity.Time
 			"project_id":    e.ProjectID,
 			"type":          e.Type,
 			"entry_date":    e.EntryDate,
+			"milestone_id":  e.MilestoneID,
+			"task_id":       e.TaskID,
 		}, dbx.HashExp{"id": e.ID}).Execute()
-		return err
+		if err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeTimeEntryUpdated, e.ProjectID, e)
 	})
 }
 
-// DeleteTimeEntryByTimeEntryID will delete a time entry by ID
+// DeleteTimeEntryByTimeEntryID will delete a time entry by ID and enqueue a time_entry.deleted event
 func (r *repository) DeleteTimeEntryByTimeEntryID(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "time.repository.DeleteTimeEntryByTimeEntryID")
+	defer span.End()
+
 	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
-		_, err := tx.Delete("project_time_entries", dbx.HashExp{"id": id}).Execute()
-		return err
+		var e entity.TimeEntry
+		if err := tx.Select().From("project_time_entries").Where(dbx.HashExp{"id": id}).One(&e); err != nil {
+			return err
+		}
+		if _, err := tx.Delete("project_time_entries", dbx.HashExp{"id": id}).Execute(); err != nil {
+			return err
+		}
+		return webhook.Enqueue(ctx, tx, events.TypeTimeEntryDeleted, e.ProjectID, e)
 	})
 }
@@ -0,0 +1,79 @@
+package time
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	stdTime "time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/schema"
+)
+
+// testDatabase returns the migrated testing database, skipping without TEST_POSTGRES_DSN
+func testDatabase(t *testing.T) *database.DBContext {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	db := database.New(config.Database{TestingDSN: dsn}).Testing
+	if _, err := db.GetManual(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := schema.NewRepository(db, logger.New().Prefix("Time Test")).InitializeDatabaseTables(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// insertID runs an INSERT ... RETURNING id
+func insertID(t *testing.T, db *database.DBContext, query string, args ...any) int {
+	var id int
+	if err := db.Get().DB().QueryRow(query, args...).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestInsertTimeEntryRejectsLinksOfOtherProjects(t *testing.T) {
+	db := testDatabase(t)
+	suffix := stdTime.Now().UnixNano()
+
+	consultantID := insertID(t, db, `INSERT INTO consultants (first_name, last_name, email) VALUES ('Ada', 'Lovelace', $1) RETURNING id`, fmt.Sprintf("ada-%d@example.com", suffix))
+	own := insertID(t, db, `INSERT INTO projects (number, name) VALUES ($1, 'Own') RETURNING id`, fmt.Sprintf("OWN-%d", suffix))
+	other := insertID(t, db, `INSERT INTO projects (number, name) VALUES ($1, 'Other') RETURNING id`, fmt.Sprintf("OTHER-%d", suffix))
+	t.Cleanup(func() {
+		db.Get().DB().Exec(`DELETE FROM projects WHERE id IN ($1, $2)`, own, other)
+		db.Get().DB().Exec(`DELETE FROM consultants WHERE id = $1`, consultantID)
+	})
+	milestoneID := insertID(t, db, `INSERT INTO project_milestones (project_id, title, due_date) VALUES ($1, 'Go live', NOW()) RETURNING id`, other)
+	taskID := insertID(t, db, `INSERT INTO project_tasks (project_id, title) VALUES ($1, 'Build') RETURNING id`, other)
+
+	r := NewRepository(db, logger.New().Prefix("Time Test"))
+	entry := func(projectID int, milestoneID, taskID *int) *entity.TimeEntry {
+		return &entity.TimeEntry{ProjectID: projectID, ConsultantID: consultantID, Type: "debit", Hours: 2, Title: "Work",
+			EntryDate: stdTime.Now(), MilestoneID: milestoneID, TaskID: taskID}
+	}
+
+	for _, e := range []*entity.TimeEntry{entry(own, &milestoneID, nil), entry(own, nil, &taskID)} {
+		if err := r.InsertTimeEntryByStruct(context.Background(), e); !errors.Is(err, ErrInvalidTimeEntry) {
+			t.Fatalf("err = %v, want ErrInvalidTimeEntry for a link to another project", err)
+		}
+	}
+
+	e := entry(other, &milestoneID, &taskID)
+	if err := r.InsertTimeEntryByStruct(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	// moving the entry to another project keeps its links checked
+	e.ProjectID = own
+	if err := r.UpdateTimeEntryByStruct(context.Background(), e); !errors.Is(err, ErrInvalidTimeEntry) {
+		t.Fatalf("err = %v, want ErrInvalidTimeEntry when moving linked hours", err)
+	}
+}
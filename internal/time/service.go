@@ -2,11 +2,20 @@ package time
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/config"
 	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/task"
 )
 
+// ErrInvalidTimeEntry is returned when a time entry fails validation
+var ErrInvalidTimeEntry = apperror.New(apperror.KindValidation, "invalid time entry")
+
 type Service interface {
 	InsertTimeEntryByStruct(ctx context.Context, e *TimeEntry) error
 	GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*TimeEntry, error)
@@ -19,6 +28,8 @@ type Service interface {
 // Service
 type service struct {
 	repo   Repository
+	types  config.TimeEntryTypes
+	cache  *cache.Cache
 	logger *logger.Logger
 }
 
@@ -26,18 +37,57 @@ type TimeEntry struct {
 	entity.TimeEntry
 }
 
-func NewService(repo Repository, logger *logger.Logger) Service {
-	return &service{repo, logger}
+// NewService returns a time entry service accepting entries of types, cached task lists are
+// invalidated and project events published through c
+func NewService(repo Repository, types config.TimeEntryTypes, c *cache.Cache, logger *logger.Logger) Service {
+	return &service{repo, types, c, logger}
+}
+
+// validate checks the type and hours of a time entry, the type is the debit, credit or leave type of
+// types
+func (e *TimeEntry) validate(types config.TimeEntryTypes) error {
+	switch e.Type {
+	case types.Debit, types.Credit, types.Leave:
+	default:
+		return ErrInvalidTimeEntry.WithField("type", fmt.Sprintf("type must be %s, %s or %s", types.Debit, types.Credit, types.Leave))
+	}
+	if e.Hours <= 0 {
+		return ErrInvalidTimeEntry.WithField("hours", "hours must be positive")
+	}
+	return nil
 }
 
 func (s *service) InsertTimeEntryByStruct(ctx context.Context, timeEntry *TimeEntry) error {
-	return s.repo.InsertTimeEntryByStruct(ctx, &timeEntry.TimeEntry)
+	if err := timeEntry.validate(s.types); err != nil {
+		return err
+	}
+	if err := s.repo.InsertTimeEntryByStruct(ctx, &timeEntry.TimeEntry); err != nil {
+		return err
+	}
+	s.invalidateTaskActuals(timeEntry.TimeEntry)
+	s.publish(ctx, events.TypeTimeEntryCreated, timeEntry.TimeEntry)
+	return nil
+}
+
+// invalidateTaskActuals clears the cached tasks and forecast of the project of an entry linked to a
+// task, its hours are part of the task actuals
+func (s *service) invalidateTaskActuals(timeEntry entity.TimeEntry) {
+	if timeEntry.TaskID != nil {
+		task.InvalidateProjectTasks(s.cache, timeEntry.ProjectID)
+	}
+}
+
+// publish notifies the project's subscribers of a committed change
+func (s *service) publish(ctx context.Context, eventType string, timeEntry entity.TimeEntry) {
+	if err := events.Publish(ctx, s.cache, eventType, timeEntry.ProjectID, timeEntry); err != nil {
+		s.logger.Error(fmt.Sprintf("Failed to publish %s: %s", eventType, err.Error()))
+	}
 }
 
 func (s *service) GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*TimeEntry, error) {
 	timeEntry, err := s.repo.GetTimeEntryByTimeEntryID(ctx, id)
 	if err != nil {
-		return &TimeEntry{}, nil
+		return nil, err
 	}
 	return &TimeEntry{*timeEntry}, nil
 }
@@ -67,9 +117,33 @@ func (s *service) GetTimeEntryHistoryByConsultantID(ctx context.Context, consult
 }
 
 func (s *service) UpdateTimeEntryByStruct(ctx context.Context, timeEntry *TimeEntry) error {
-	return s.repo.UpdateTimeEntryByStruct(ctx, &timeEntry.TimeEntry)
+	if err := timeEntry.validate(s.types); err != nil {
+		return err
+	}
+	// load the entry first, the task it was linked to loses the hours
+	previous, err := s.repo.GetTimeEntryByTimeEntryID(ctx, timeEntry.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateTimeEntryByStruct(ctx, &timeEntry.TimeEntry); err != nil {
+		return err
+	}
+	s.invalidateTaskActuals(*previous)
+	s.invalidateTaskActuals(timeEntry.TimeEntry)
+	s.publish(ctx, events.TypeTimeEntryUpdated, timeEntry.TimeEntry)
+	return nil
 }
 
 func (s *service) DeleteTimeEntryByTimeEntryID(ctx context.Context, id int) error {
-	return s.repo.DeleteTimeEntryByTimeEntryID(ctx, id)
+	// load the entry first, the event needs its project
+	timeEntry, err := s.repo.GetTimeEntryByTimeEntryID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTimeEntryByTimeEntryID(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateTaskActuals(*timeEntry)
+	s.publish(ctx, events.TypeTimeEntryDeleted, *timeEntry)
+	return nil
 }
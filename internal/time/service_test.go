@@ -0,0 +1,102 @@
+package time
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+// types are configured, not the defaults
+var types = config.TimeEntryTypes{Debit: "billable", Credit: "non-billable", Leave: "absence"}
+
+// fakeRepository knows time entry 1, linked to task 4, and records updates and deletes
+type fakeRepository struct {
+	Repository
+	updated []entity.TimeEntry
+	deleted bool
+}
+
+func (f *fakeRepository) GetTimeEntryByTimeEntryID(ctx context.Context, id int) (*entity.TimeEntry, error) {
+	if id != 1 {
+		return nil, apperror.NotFound("time entry %d not found", id)
+	}
+	taskID := 4
+	return &entity.TimeEntry{ID: 1, ProjectID: 10, TaskID: &taskID}, nil
+}
+
+func (f *fakeRepository) UpdateTimeEntryByStruct(ctx context.Context, e *entity.TimeEntry) error {
+	f.updated = append(f.updated, *e)
+	return nil
+}
+
+func (f *fakeRepository) DeleteTimeEntryByTimeEntryID(ctx context.Context, id int) error {
+	f.deleted = true
+	return nil
+}
+
+func TestUpdateTimeEntryLoadsPreviousEntry(t *testing.T) {
+	repo := &fakeRepository{}
+	s := NewService(repo, types, nil, logger.New().Prefix("Time Test"))
+
+	// the previous entry is loaded first, the task it was linked to loses the hours
+	e := &TimeEntry{entity.TimeEntry{ID: 9, ProjectID: 10, Type: types.Debit, Hours: 2}}
+	if err := s.UpdateTimeEntryByStruct(context.Background(), e); apperror.KindOf(err) != apperror.KindNotFound || len(repo.updated) != 0 {
+		t.Fatalf("err = %v, updated %d, want the lookup error before updating", err, len(repo.updated))
+	}
+
+	e.ID = 1
+	if err := s.UpdateTimeEntryByStruct(context.Background(), e); err != nil || len(repo.updated) != 1 {
+		t.Fatalf("err = %v, updated %d", err, len(repo.updated))
+	}
+
+	// invalid entries are rejected before anything is loaded
+	e.Hours = 0
+	if err := s.UpdateTimeEntryByStruct(context.Background(), e); !errors.Is(err, ErrInvalidTimeEntry) || len(repo.updated) != 1 {
+		t.Fatalf("err = %v, want ErrInvalidTimeEntry", err)
+	}
+}
+
+func TestValidateAcceptsConfiguredTypes(t *testing.T) {
+	for _, tt := range []struct {
+		entry entity.TimeEntry
+		valid bool
+	}{
+		{entity.TimeEntry{Type: "billable", Hours: 1}, true},
+		{entity.TimeEntry{Type: "non-billable", Hours: 0.5}, true},
+		{entity.TimeEntry{Type: "absence", Hours: 8}, true},
+		{entity.TimeEntry{Type: "debit", Hours: 1}, false}, // the default types are not configured
+		{entity.TimeEntry{Type: "leave", Hours: 8}, false},
+		{entity.TimeEntry{Type: "", Hours: 1}, false},
+		{entity.TimeEntry{Type: "billable", Hours: 0}, false},
+		{entity.TimeEntry{Type: "billable", Hours: -2}, false},
+	} {
+		e := TimeEntry{tt.entry}
+		if err := e.validate(types); tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrInvalidTimeEntry)) {
+			t.Errorf("%+v: err = %v", tt.entry, err)
+		}
+	}
+}
+
+func TestGetTimeEntryByTimeEntryIDNotFound(t *testing.T) {
+	repo := &fakeRepository{}
+	s := NewService(repo, types, nil, logger.New().Prefix("Time Test"))
+
+	if e, err := s.GetTimeEntryByTimeEntryID(context.Background(), 1); err != nil || e.ID != 1 {
+		t.Fatalf("entry = %+v, err = %v, want entry 1", e, err)
+	}
+
+	e, err := s.GetTimeEntryByTimeEntryID(context.Background(), 9)
+	if apperror.KindOf(err) != apperror.KindNotFound || e != nil {
+		t.Fatalf("entry = %+v, err = %v, want not found", e, err)
+	}
+
+	// deleting an unknown entry is not found either, and deletes nothing
+	if err := s.DeleteTimeEntryByTimeEntryID(context.Background(), 9); apperror.KindOf(err) != apperror.KindNotFound || repo.deleted {
+		t.Fatalf("delete err = %v, deleted = %v, want not found", err, repo.deleted)
+	}
+}
@@ -0,0 +1,105 @@
+package timeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/cache"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+const (
+	// dateLayout is the layout of the from and to query parameters
+	dateLayout = "2006-01-02"
+	// maxWindow caps the timeline window to keep responses bounded
+	maxWindow = 3 * 366 * 24 * time.Hour
+)
+
+// Handler serves the timeline routes through its service
+type Handler struct {
+	service Service
+	cache   *cache.Cache
+}
+
+// NewHandler returns a handler serving the timeline routes through service, caching reads in c
+func NewHandler(service Service, c *cache.Cache) *Handler {
+	return &Handler{service, c}
+}
+
+// Routes registers the timeline routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.GetTimeline)
+}
+
+// Gets the timeline filter from query parameters, the window defaults to 3 months back and 6 months ahead
+func getFilterFromRequest(r *http.Request) (entity.TimelineFilter, error) {
+	query := r.URL.Query()
+	today := time.Now().Truncate(24 * time.Hour)
+	filter := entity.TimelineFilter{
+		From: today.AddDate(0, -3, 0),
+		To:   today.AddDate(0, 6, 0),
+		Tag:  query.Get("tag"),
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return filter, apperror.Invalid("from", fmt.Sprintf("invalid from, expected %s", dateLayout))
+		}
+		filter.From = from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return filter, apperror.Invalid("to", fmt.Sprintf("invalid to, expected %s", dateLayout))
+		}
+		// include the whole last day
+		filter.To = to.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !filter.To.After(filter.From) {
+		return filter, apperror.Invalid("to", "to must be after from")
+	}
+	if filter.To.Sub(filter.From) > maxWindow {
+		return filter, apperror.Invalid("to", "window must not exceed 3 years")
+	}
+
+	if v := query.Get("managerID"); v != "" {
+		managerID, err := strconv.Atoi(v)
+		if err != nil {
+			return filter, apperror.Invalid("managerID", "invalid managerID")
+		}
+		filter.ManagerID = managerID
+	}
+
+	return filter, nil
+}
+
+// GetTimeline returns projected vs actual dates, status change points and milestones of every
+// project in the window, optionally filtered by manager and tag
+func (h *Handler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	filter, err := getFilterFromRequest(r)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	key := fmt.Sprintf("timeline:%s:%s:manager:%d:tag:%s",
+		filter.From.Format(dateLayout), filter.To.Format(dateLayout), filter.ManagerID, filter.Tag)
+	timeline, err := cache.Use(r.Context(), h.cache, key, func(ctx context.Context) (*Timeline, error) {
+		return h.service.GetTimelineByFilter(ctx, filter)
+	})
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(timeline)
+}
@@ -0,0 +1,59 @@
+package timeline
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetFilterFromRequest(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantErr  bool
+		wantFrom string
+		wantTo   string
+		wantMgr  int
+		wantTag  string
+	}{
+		{query: "from=2026-01-01&to=2026-03-31&managerID=4&tag=cloud", wantFrom: "2026-01-01", wantTo: "2026-03-31", wantMgr: 4, wantTag: "cloud"},
+		{query: "from=2026-01-01&to=2026-01-01", wantFrom: "2026-01-01", wantTo: "2026-01-01"}, // a single whole day
+		{query: "from=01/01/2026", wantErr: true},
+		{query: "from=2026-01-01&to=2026-13-01", wantErr: true},
+		{query: "from=2026-03-01&to=2026-01-01", wantErr: true},
+		{query: "from=2020-01-01&to=2026-01-01", wantErr: true}, // wider than 3 years
+		{query: "from=2026-01-01&to=2026-03-31&managerID=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			filter, err := getFilterFromRequest(httptest.NewRequest("GET", "/timeline?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("filter = %+v, want an error", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filter.From.Format(dateLayout) != tt.wantFrom || filter.To.Format(dateLayout) != tt.wantTo ||
+				filter.ManagerID != tt.wantMgr || filter.Tag != tt.wantTag {
+				t.Fatalf("filter = %+v", filter)
+			}
+			// to covers the whole of its day
+			if filter.To.Hour() != 23 {
+				t.Fatalf("to = %s, want the end of the day", filter.To)
+			}
+		})
+	}
+}
+
+func TestGetFilterFromRequestDefaultWindow(t *testing.T) {
+	filter, err := getFilterFromRequest(httptest.NewRequest("GET", "/timeline", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := time.Now().Truncate(24 * time.Hour)
+	if !filter.From.Equal(today.AddDate(0, -3, 0)) || !filter.To.Equal(today.AddDate(0, 6, 0)) {
+		t.Fatalf("window = %s - %s, want 3 months back and 6 ahead", filter.From, filter.To)
+	}
+}
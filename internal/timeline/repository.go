@@ -0,0 +1,174 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/lib/pq"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	internalIDField "github.com/renniemaharaj/project-list-go/internal/idRow"
+	"github.com/renniemaharaj/project-list-go/internal/milestone"
+	"github.com/renniemaharaj/project-list-go/internal/project"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+type Repository interface {
+	GetTimelineProjectIDS(ctx context.Context, filter entity.TimelineFilter) ([]int, error)
+	GetStatusChangesByProjectsIDS(ctx context.Context, projectIDS []int, from, to time.Time) ([]entity.StatusChange, error)
+	GetTimelineByFilter(ctx context.Context, filter entity.TimelineFilter) (*entity.Timeline, error)
+}
+
+type repository struct {
+	dbContext  *database.DBContext
+	projects   project.Repository
+	milestones milestone.Repository
+	l          *logger.Logger
+}
+
+func NewRepository(_db *database.DBContext, projects project.Repository, milestones milestone.Repository, _l *logger.Logger) Repository {
+	return &repository{_db, projects, milestones, _l}
+}
+
+// GetTimelineProjectIDS will return the IDs of projects matching the filter whose projected or
+// actual span overlaps the window, or which changed status or have a milestone due inside it
+func (r *repository) GetTimelineProjectIDS(ctx context.Context, filter entity.TimelineFilter) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "timeline.repository.GetTimelineProjectIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	window := dbx.Params{"from": filter.From, "to": filter.To}
+
+	q := r.dbContext.Get().WithContext(ctx).Select("p.id").
+		From("projects p").
+		Where(dbx.Or(
+			dbx.NewExp("p.projected_start_date <= {:to} AND p.projected_end_date >= {:from}", window),
+			dbx.NewExp("p.start_date <= {:to} AND (p.end_date IS NULL OR p.end_date >= {:from})", window),
+			dbx.NewExp("EXISTS (SELECT 1 FROM project_statuses s WHERE s.project_id = p.id AND s.date_created BETWEEN {:from} AND {:to})", window),
+			dbx.NewExp("EXISTS (SELECT 1 FROM project_milestones m WHERE m.project_id = p.id AND m.due_date BETWEEN {:from} AND {:to})", window),
+		))
+
+	if filter.ManagerID != 0 {
+		q.AndWhere(dbx.HashExp{"p.manager_id": filter.ManagerID})
+	}
+	if filter.Tag != "" {
+		q.AndWhere(dbx.NewExp("EXISTS (SELECT 1 FROM project_tags tg WHERE tg.project_id = p.id AND tg.tag = {:tag})", dbx.Params{"tag": filter.Tag}))
+	}
+
+	idFields := []internalIDField.IDField{}
+	if err := q.OrderBy("p.id ASC").All(&idFields); err != nil {
+		return nil, err
+	}
+	return internalIDField.ToIntSlice(idFields), nil
+}
+
+// GetStatusChangesByProjectsIDS will return, for multiple projects, the statuses inside [from, to]
+// whose title differs from the status before it. The previous title is resolved over the full
+// history so the first change inside the window is reported correctly.
+func (r *repository) GetStatusChangesByProjectsIDS(ctx context.Context, projectIDS []int, from, to time.Time) ([]entity.StatusChange, error) {
+	ctx, span := tracing.Start(ctx, "timeline.repository.GetStatusChangesByProjectsIDS")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.StatusChange
+
+	// Convert []int -> pq.Int64Array for = ANY
+	ids := make(pq.Int64Array, len(projectIDS))
+	for i, id := range projectIDS {
+		ids[i] = int64(id)
+	}
+
+	err := r.dbContext.Get().WithContext(ctx).NewQuery(`SELECT h.* FROM (
+			SELECT s.id, s.project_id, s.title, s.date_created,
+				LAG(s.title) OVER (PARTITION BY s.project_id ORDER BY s.date_created, s.id) AS previous_title
+			FROM project_statuses s
+			WHERE s.project_id = ANY({:ids})
+		) h
+		WHERE h.date_created BETWEEN {:from} AND {:to}
+			AND (h.previous_title IS NULL OR h.previous_title <> h.title)
+		ORDER BY h.project_id ASC, h.date_created ASC, h.id ASC`).
+		Bind(dbx.Params{"ids": ids, "from": from, "to": to}).
+		All(&list)
+	return list, err
+}
+
+// GetTimelineByFilter will return the portfolio timeline in 4 batched queries: matching project IDs,
+// project rows, status change points and milestones inside the window
+func (r *repository) GetTimelineByFilter(ctx context.Context, filter entity.TimelineFilter) (*entity.Timeline, error) {
+	ctx, span := tracing.Start(ctx, "timeline.repository.GetTimelineByFilter")
+	defer span.End()
+
+	start := time.Now()
+	timeline := &entity.Timeline{From: filter.From, To: filter.To, Projects: []entity.TimelineProject{}}
+
+	// --- 1. Matching project IDs ---
+	projectIDs, err := r.GetTimelineProjectIDS(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectIDs) == 0 {
+		return timeline, nil
+	}
+
+	// --- 2. Batch fetch projects ---
+	projects, err := r.projects.GetProjectsDataByIDS(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- 3. Batch fetch status change points ---
+	changes, err := r.GetStatusChangesByProjectsIDS(ctx, projectIDs, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- 4. Batch fetch milestones ---
+	milestones, err := r.milestones.GetMilestonesByProjectsIDSInRange(ctx, projectIDs, filter.From, filter.To)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- 5. Group results into maps for quick lookup ---
+	projectMap := make(map[int]entity.Project, len(projects))
+	for _, p := range projects {
+		projectMap[p.ID] = p
+	}
+
+	changeMap := make(map[int][]entity.StatusChange)
+	for _, c := range changes {
+		changeMap[c.ProjectID] = append(changeMap[c.ProjectID], c)
+	}
+
+	milestoneMap := make(map[int][]entity.Milestone)
+	for _, m := range milestones {
+		milestoneMap[m.ProjectID] = append(milestoneMap[m.ProjectID], m)
+	}
+
+	// --- 6. Construct timeline rows in project ID order ---
+	for _, pid := range projectIDs {
+		p, ok := projectMap[pid]
+		if !ok {
+			continue
+		}
+		timeline.Projects = append(timeline.Projects, entity.TimelineProject{
+			ProjectID:          p.ID,
+			Number:             p.Number,
+			Name:               p.Name,
+			ManagerID:          p.ManagerID,
+			ProjectedStartDate: p.ProjectedStartDate,
+			ProjectedEndDate:   p.ProjectedEndDate,
+			StartDate:          p.StartDate,
+			EndDate:            p.EndDate,
+			StatusChanges:      changeMap[pid],
+			Milestones:         milestoneMap[pid],
+		})
+	}
+
+	r.l.Info(fmt.Sprintf("Completed GetTimelineByFilter for %d projects in total %v", len(timeline.Projects), time.Since(start)))
+	return timeline, nil
+}
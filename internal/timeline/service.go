@@ -0,0 +1,34 @@
+package timeline
+
+import (
+	"context"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+)
+
+type Service interface {
+	GetTimelineByFilter(ctx context.Context, filter entity.TimelineFilter) (*Timeline, error)
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Timeline struct {
+	entity.Timeline
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+func (s *service) GetTimelineByFilter(ctx context.Context, filter entity.TimelineFilter) (*Timeline, error) {
+	timeline, err := s.repo.GetTimelineByFilter(ctx, filter)
+	if err != nil {
+		return &Timeline{}, err
+	}
+	return &Timeline{*timeline}, nil
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatementLength caps the statement text kept on a span
+const maxStatementLength = 4096
+
+// stringLiteral matches quoted SQL string literals, which carry the bound values
+var stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// RecordSQL records a finished statement as a child span of the span in ctx, from the duration reported
+// by the dbx log hooks. operation is query or exec. String literals are masked so bound values such as
+// emails and secrets never leave the process.
+func RecordSQL(ctx context.Context, operation string, d time.Duration, statement string, err error) {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return
+	}
+
+	end := time.Now()
+	name := statementOperation(statement)
+	if name == "" {
+		name = "db." + operation
+	}
+	text := stringLiteral.ReplaceAllString(statement, "?")
+	if len(text) > maxStatementLength {
+		text = text[:maxStatementLength]
+	}
+
+	_, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(end.Add(-d)),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", name),
+			attribute.String("db.query.text", text),
+		),
+	)
+	if err != nil {
+		RecordError(span, err)
+	}
+	span.End(trace.WithTimestamp(end))
+}
+
+// statementOperation returns the leading keyword of a statement, e.g. SELECT or WITH
+func statementOperation(statement string) string {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
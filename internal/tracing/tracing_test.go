@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recorder records every span of the tests. The package tracer binds to the first provider installed
+// globally, so the provider is installed once for all tests.
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+// record returns a function listing the spans ended since record was called
+func record(t *testing.T) func() []sdktrace.ReadOnlySpan {
+	t.Helper()
+	offset := len(recorder.Ended())
+	return func() []sdktrace.ReadOnlySpan {
+		return recorder.Ended()[offset:]
+	}
+}
+
+// attr returns the value of an attribute of a span
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	ended := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/project/{projectID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// an incoming traceparent is continued
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/project/7", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /project/{projectID}" || span.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("span = %s (%s), want the route pattern as name", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != traceID || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("trace = %s, parent = %s, want the incoming trace continued", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	if attr(span, "http.route").AsString() != "/project/{projectID}" || attr(span, "http.response.status_code").AsInt64() != 500 {
+		t.Fatalf("attributes = %v", span.Attributes())
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("status = %v, want an error for a 500", span.Status())
+	}
+}
+
+func TestRecordSQL(t *testing.T) {
+	ended := record(t)
+
+	// outside a trace statements are not recorded
+	RecordSQL(context.Background(), "query", time.Millisecond, "SELECT 1", nil)
+	if len(ended()) != 0 {
+		t.Fatal("statement recorded outside a trace")
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "request")
+	RecordSQL(ctx, "exec", 5*time.Millisecond, "  update consultants SET email='ada@example.com', note='it''s' WHERE id=1", errors.New("deadlock"))
+	parent.End()
+
+	span := ended()[0]
+	if span.Name() != "UPDATE" || span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("span = %s, want an UPDATE child of the request", span.Name())
+	}
+	if text := attr(span, "db.query.text").AsString(); text != "  update consultants SET email=?, note=? WHERE id=1" {
+		t.Fatalf("statement = %q, want string literals masked", text)
+	}
+	if d := span.EndTime().Sub(span.StartTime()); d != 5*time.Millisecond {
+		t.Fatalf("span lasted %s, want the statement duration", d)
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("status = %v, want the statement error", span.Status())
+	}
+}
+
+func TestStartOutsideTrace(t *testing.T) {
+	ended := record(t)
+
+	ctx, span := Start(context.Background(), "webhook.dispatch")
+	span.End()
+	if len(ended()) != 0 || trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("background work started a root trace")
+	}
+}
+
+func TestCarrierRoundTrip(t *testing.T) {
+	record(t)
+
+	ctx, span := tracer.Start(context.Background(), "mutation")
+	defer span.End()
+
+	// the trace of a mutation is stored with its outbox event and continued by the dispatcher
+	carrier := Carrier(ctx)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("carrier = %v, want a traceparent", carrier)
+	}
+	continued := trace.SpanContextFromContext(FromCarrier(context.Background(), carrier))
+	if continued.TraceID() != span.SpanContext().TraceID() {
+		t.Fatalf("trace = %s, want %s", continued.TraceID(), span.SpanContext().TraceID())
+	}
+}
+
+func TestInitRejectsUnknownExporter(t *testing.T) {
+	if _, err := Init(context.Background(), config.Tracing{Exporter: "zipkin"}); err == nil {
+		t.Fatal("unknown exporter accepted")
+	}
+}
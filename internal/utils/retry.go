@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+)
+
+const (
+	// minRetryDelay doubles per failed attempt up to maxRetryDelay
+	minRetryDelay = 500 * time.Millisecond
+	maxRetryDelay = 10 * time.Second
+)
+
+// Retry calls fn until it succeeds, waiting with exponential backoff between attempts. It gives up
+// with the last error once timeout elapsed or ctx is done.
+func Retry(ctx context.Context, timeout time.Duration, l *logger.Logger, name string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		l.WarningF("%s attempt %d failed, retrying in %s: %s", name, attempt, delay, err.Error())
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s gave up after %d attempts: %w", name, attempt, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+)
+
+var errDown = errors.New("connection refused")
+
+func TestRetrySucceedsAfterFailures(t *testing.T) {
+	attempts := 0
+	err := Retry(context.Background(), 5*time.Second, logger.New().Prefix("Retry Test"), "postgres", func(ctx context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errDown
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("err = %v after %d attempts, want success on the second", err, attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	start := time.Now()
+	attempts := 0
+	err := Retry(context.Background(), 100*time.Millisecond, logger.New().Prefix("Retry Test"), "redis", func(ctx context.Context) error {
+		attempts++
+		return errDown
+	})
+	// the timeout cuts the first backoff short
+	if !errors.Is(err, errDown) || attempts != 1 || time.Since(start) >= minRetryDelay {
+		t.Fatalf("err = %v after %d attempts in %s, want the last error once the timeout elapsed", err, attempts, time.Since(start))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Retry(ctx, time.Minute, logger.New().Prefix("Retry Test"), "redis", func(ctx context.Context) error { return errDown }); !errors.Is(err, errDown) {
+		t.Fatalf("err = %v, want the last error once ctx is done", err)
+	}
+}
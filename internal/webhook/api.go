@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/problem"
+)
+
+// Handler serves the webhook subscription routes through its service
+type Handler struct {
+	service Service
+}
+
+// NewHandler returns a handler serving the webhook subscription routes through service
+func NewHandler(service Service) *Handler {
+	return &Handler{service}
+}
+
+// Routes registers the webhook subscription routes, chi routing
+func (h *Handler) Routes(r chi.Router) {
+	r.Get("/", h.GetAllSubscriptions)
+	r.Post("/", h.CreateSubscription)
+	r.Post("/deliveries/{deliveryID}/retry", h.RetryDelivery)
+	r.Get("/{subscriptionID}", h.GetSubscriptionByID)
+	r.Put("/{subscriptionID}", h.UpdateSubscription)
+	r.Delete("/{subscriptionID}", h.DeleteSubscription)
+	r.Get("/{subscriptionID}/deliveries", h.GetDeliveriesBySubscriptionID)
+}
+
+// Loads the subscription in the url
+func (h *Handler) getSubscription(w http.ResponseWriter, r *http.Request) (*Subscription, bool) {
+	subscriptionID, err := problem.IntParam(w, r, "subscriptionID")
+	if err != nil {
+		return nil, false
+	}
+
+	s, err := h.service.GetSubscriptionByID(r.Context(), subscriptionID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return nil, false
+	}
+	return s, true
+}
+
+// CreateSubscription inserts a subscription from the request body, the response carries the secret
+// deliveries are signed with, it is not returned again
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var s Subscription
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid webhook subscription body"))
+		return
+	}
+	s.ID = 0
+
+	if err := h.service.InsertSubscriptionByStruct(r.Context(), &s); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// GetAllSubscriptions returns every subscription without secrets
+func (h *Handler) GetAllSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.GetAllSubscriptions(r.Context())
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(subs)
+}
+
+// GetSubscriptionByID returns a single subscription without its secret
+func (h *Handler) GetSubscriptionByID(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// UpdateSubscription changes the url, event filter or active flag of a subscription, the secret is kept
+func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var s Subscription
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		problem.Write(w, r, apperror.Validation("invalid webhook subscription body"))
+		return
+	}
+	s.ID = existing.ID
+	s.DateCreated = existing.DateCreated
+
+	if err := h.service.UpdateSubscriptionByStruct(r.Context(), &s); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// DeleteSubscription removes a subscription and its delivery log
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscriptionByID(r.Context(), s.ID); err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveriesBySubscriptionID returns the delivery log of a subscription, newest first, optionally
+// filtered by the status query param (pending, delivered or dead) and capped by limit
+func (h *Handler) GetDeliveriesBySubscriptionID(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	limit := DefaultDeliveryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			problem.Write(w, r, apperror.Invalid("limit", "invalid limit"))
+			return
+		}
+	}
+
+	deliveries, err := h.service.GetDeliveriesBySubscriptionID(r.Context(), s.ID, r.URL.Query().Get("status"), limit)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(deliveries)
+}
+
+// RetryDelivery moves a dead-lettered delivery back to pending with a fresh attempt budget
+func (h *Handler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := problem.IntParam(w, r, "deliveryID")
+	if err != nil {
+		return
+	}
+
+	err = h.service.RetryDeliveryByID(r.Context(), deliveryID)
+	if err != nil {
+		problem.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	// MaxAttempts before a delivery is dead-lettered
+	MaxAttempts = 8
+	// baseBackoff doubles per failed attempt up to maxBackoff
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+
+	// pollInterval between outbox and delivery passes
+	pollInterval = 2 * time.Second
+	// batchSize of outbox events and deliveries per pass
+	batchSize = 50
+	// concurrency of deliveries in flight per dispatcher
+	concurrency = 8
+	// lease of a claimed delivery, longer than the client timeout
+	lease = time.Minute
+	// maxErrorBody is the part of a failed response body kept in the delivery log
+	maxErrorBody = 512
+
+	// retention of delivered and dead deliveries and of dispatched outbox events
+	retention = 30 * 24 * time.Hour
+	// sweepInterval between deletions of what outlived retention
+	sweepInterval = time.Hour
+)
+
+// Signature headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the signature of a delivery: sha256= followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by the subscription secret. Receivers recompute it to verify the sender
+// and reject stale timestamps to prevent replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt after attempts failures, with up to 10% jitter
+func backoff(attempts int) time.Duration {
+	d := maxBackoff
+	if attempts < 20 {
+		d = min(baseBackoff<<(attempts-1), maxBackoff)
+	}
+	return d + time.Duration(rand.Int64N(int64(d/10)+1))
+}
+
+// Dispatcher moves outbox events into deliveries and sends the due deliveries
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+	logger *logger.Logger
+}
+
+// NewDispatcher returns a dispatcher sending with client, whose timeout bounds every attempt
+func NewDispatcher(repo Repository, client *http.Client, logger *logger.Logger) *Dispatcher {
+	return &Dispatcher{repo, client, logger}
+}
+
+// Run dispatches until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var swept time.Time
+	for {
+		if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("Webhook dispatch failed: " + err.Error())
+		}
+		if time.Since(swept) >= sweepInterval {
+			if err := d.Sweep(ctx); err != nil && ctx.Err() == nil {
+				d.logger.Error("Webhook retention sweep failed: " + err.Error())
+			}
+			swept = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce fans the pending outbox out and sends one batch of due deliveries
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	for {
+		n, err := d.repo.DispatchOutbox(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		if n < batchSize {
+			break
+		}
+	}
+
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, batchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for _, delivery := range deliveries {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result := d.send(ctx, delivery)
+			if err := d.repo.RecordDeliveryResult(ctx, delivery.ID, result); err != nil {
+				d.logger.Error(fmt.Sprintf("Failed to record webhook delivery %d: %s", delivery.ID, err.Error()))
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Sweep deletes the deliveries settled and the outbox events dispatched longer than retention ago
+func (d *Dispatcher) Sweep(ctx context.Context) error {
+	n, err := d.repo.DeleteSettledBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		d.logger.InfoF("Deleted %d webhook events past retention", n)
+	}
+	return nil
+}
+
+// send attempts one delivery, any 2xx response counts as delivered. The attempt continues the trace of
+// the mutation that raised the event and passes it on in the traceparent header.
+func (d *Dispatcher) send(ctx context.Context, delivery ClaimedDelivery) DeliveryResult {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	var carrier map[string]string
+	_ = json.Unmarshal([]byte(delivery.TraceContext), &carrier)
+	ctx, span := tracing.Start(tracing.FromCarrier(ctx, carrier), "webhook.deliver",
+		attribute.Int("webhook.delivery_id", delivery.ID),
+		attribute.String("webhook.event_type", delivery.EventType),
+		attribute.Int("webhook.attempt", delivery.Attempts+1),
+	)
+	defer span.End()
+
+	fail := func(statusCode *int, reason string) DeliveryResult {
+		span.SetStatus(codes.Error, reason)
+		attempts := delivery.Attempts + 1
+		if attempts >= MaxAttempts {
+			return DeliveryResult{StatusCode: statusCode, Error: reason, Dead: true}
+		}
+		return DeliveryResult{StatusCode: statusCode, Error: reason, NextAt: time.Now().Add(backoff(attempts))}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fail(nil, err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+	tracing.Inject(req)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fail(nil, err.Error())
+	}
+	defer resp.Body.Close()
+
+	statusCode := resp.StatusCode
+	span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	if statusCode >= 200 && statusCode < 300 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+		return DeliveryResult{StatusCode: &statusCode, Delivered: true}
+	}
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return fail(&statusCode, fmt.Sprintf("%s: %s", resp.Status, excerpt))
+}
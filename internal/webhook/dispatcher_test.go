@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+)
+
+// dispatchRepository hands out its deliveries once and records the result of every attempt
+type dispatchRepository struct {
+	Repository
+	mu         sync.Mutex
+	deliveries []ClaimedDelivery
+	results    map[int]DeliveryResult
+	sweptTo    time.Time
+}
+
+func (f *dispatchRepository) DispatchOutbox(ctx context.Context, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (f *dispatchRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deliveries := f.deliveries
+	f.deliveries = nil
+	return deliveries, nil
+}
+
+func (f *dispatchRepository) RecordDeliveryResult(ctx context.Context, deliveryID int, result DeliveryResult) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results[deliveryID] = result
+	return nil
+}
+
+func (f *dispatchRepository) DeleteSettledBefore(ctx context.Context, before time.Time) (int64, error) {
+	f.sweptTo = before
+	return 3, nil
+}
+
+// Dispatches deliveries to a receiver answering status, returning the recorded results
+func dispatch(t *testing.T, status int, deliveries ...ClaimedDelivery) map[int]DeliveryResult {
+	t.Helper()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	for i := range deliveries {
+		deliveries[i].URL = receiver.URL
+	}
+	repo := &dispatchRepository{deliveries: deliveries, results: map[int]DeliveryResult{}}
+	if err := NewDispatcher(repo, receiver.Client(), logger.New().Prefix("Webhook Test")).RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return repo.results
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	var (
+		header http.Header
+		body   []byte
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+
+	repo := &dispatchRepository{
+		deliveries: []ClaimedDelivery{{ID: 7, EventType: "project.over_budget", URL: receiver.URL, Secret: "whsec", Payload: `{"projectID":1}`}},
+		results:    map[int]DeliveryResult{},
+	}
+	if err := NewDispatcher(repo, receiver.Client(), logger.New().Prefix("Webhook Test")).RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !repo.results[7].Delivered {
+		t.Fatalf("result = %+v, want delivered", repo.results[7])
+	}
+	if string(body) != `{"projectID":1}` || header.Get(HeaderEvent) != "project.over_budget" || header.Get(HeaderDelivery) != "7" {
+		t.Fatalf("request = %v %s", header, body)
+	}
+
+	// receivers verify the HMAC-SHA256 of "<timestamp>.<body>"
+	mac := hmac.New(sha256.New, []byte("whsec"))
+	mac.Write([]byte(header.Get(HeaderTimestamp) + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); header.Get(HeaderSignature) != want {
+		t.Fatalf("signature = %q, want %q", header.Get(HeaderSignature), want)
+	}
+}
+
+func TestDispatcherBacksOffOnServerErrors(t *testing.T) {
+	start := time.Now()
+	results := dispatch(t, http.StatusServiceUnavailable,
+		ClaimedDelivery{ID: 1, Attempts: 0},
+		ClaimedDelivery{ID: 2, Attempts: 1},
+		ClaimedDelivery{ID: 3, Attempts: 4},
+		ClaimedDelivery{ID: 4, Attempts: 6},
+	)
+
+	// the delay doubles per failed attempt with up to 10% jitter
+	for id, want := range map[int]time.Duration{1: baseBackoff, 2: 2 * baseBackoff, 3: 16 * baseBackoff, 4: 64 * baseBackoff} {
+		result := results[id]
+		if result.Delivered || result.Dead || result.StatusCode == nil || *result.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("delivery %d: result = %+v, want a retried 503", id, result)
+		}
+		delay := result.NextAt.Sub(start)
+		if delay < want || delay > want+want/10+time.Second {
+			t.Fatalf("delivery %d: retried after %s, want %s", id, delay, want)
+		}
+	}
+
+	// and is capped at maxBackoff
+	if d := backoff(20); d < maxBackoff || d > maxBackoff+maxBackoff/10 {
+		t.Fatalf("backoff after 20 attempts = %s, want %s", d, maxBackoff)
+	}
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	results := dispatch(t, http.StatusInternalServerError,
+		ClaimedDelivery{ID: 1, Attempts: MaxAttempts - 2},
+		ClaimedDelivery{ID: 2, Attempts: MaxAttempts - 1},
+	)
+
+	if results[1].Dead || results[1].NextAt.IsZero() {
+		t.Fatalf("attempt %d: result = %+v, want a retry", MaxAttempts-1, results[1])
+	}
+	if !results[2].Dead || !results[2].NextAt.IsZero() {
+		t.Fatalf("attempt %d: result = %+v, want dead-lettered", MaxAttempts, results[2])
+	}
+}
+
+func TestDispatcherSweepsPastRetention(t *testing.T) {
+	repo := &dispatchRepository{}
+	start := time.Now()
+	if err := NewDispatcher(repo, http.DefaultClient, logger.New().Prefix("Webhook Test")).Sweep(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(-retention); repo.sweptTo.Before(want) || repo.sweptTo.After(want.Add(time.Second)) {
+		t.Fatalf("swept to %s, want %s", repo.sweptTo, want)
+	}
+}
+
+func TestEnqueueWithoutEvents(t *testing.T) {
+	// no transaction is touched when events are suppressed
+	if err := Enqueue(WithoutEvents(context.Background()), nil, events.TypeStatusAdded, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+}
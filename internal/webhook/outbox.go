@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+// withoutEventsKey marks the contexts of mutations that enqueue no events
+type withoutEventsKey struct{}
+
+// WithoutEvents returns a copy of ctx whose mutations enqueue no events, for bulk writes such as demo
+// seeding that subscribers must not be flooded with
+func WithoutEvents(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutEventsKey{}, true)
+}
+
+// Enqueue writes a project event to the outbox inside the transaction of its mutation, so the event
+// is delivered if and only if the mutation commits. The trace context of ctx is kept for the deliveries.
+func Enqueue(ctx context.Context, tx *dbx.Tx, eventType string, projectID int, data any) error {
+	if ctx.Value(withoutEventsKey{}) != nil {
+		return nil
+	}
+	event, err := events.New(eventType, projectID, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	traceContext, err := json.Marshal(tracing.Carrier(ctx))
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewQuery(`INSERT INTO event_outbox (event_type, project_id, payload, trace_context)
+		VALUES ({:event_type}, {:project_id}, {:payload}::jsonb, {:trace_context}::jsonb)`).
+		Bind(dbx.Params{
+			"event_type":    eventType,
+			"project_id":    projectID,
+			"payload":       string(payload),
+			"trace_context": string(traceContext),
+		}).
+		Execute()
+	return err
+}
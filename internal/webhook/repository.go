@@ -0,0 +1,308 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/database"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/tracing"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// ErrDeliveryNotDead is returned when retrying a delivery that did not exhaust its attempts
+var ErrDeliveryNotDead = apperror.New(apperror.KindConflict, "only dead deliveries can be retried")
+
+// ClaimedDelivery is a due delivery leased to the dispatcher with what it needs to send it
+type ClaimedDelivery struct {
+	ID           int
+	OutboxID     int
+	EventType    string
+	Attempts     int
+	URL          string
+	Secret       string
+	Payload      string
+	TraceContext string
+}
+
+// DeliveryResult is the outcome of one attempt
+type DeliveryResult struct {
+	StatusCode *int
+	Error      string
+	Delivered  bool
+	Dead       bool
+	NextAt     time.Time
+}
+
+type Repository interface {
+	InsertSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error
+	GetSubscriptionByID(ctx context.Context, subscriptionID int) (*entity.WebhookSubscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error)
+	UpdateSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error
+	DeleteSubscriptionByID(ctx context.Context, subscriptionID int) error
+	GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID int, status string, limit int) ([]entity.WebhookDelivery, error)
+	RetryDeliveryByID(ctx context.Context, deliveryID int) error
+	DispatchOutbox(ctx context.Context, limit int) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error)
+	RecordDeliveryResult(ctx context.Context, deliveryID int, result DeliveryResult) error
+	DeleteSettledBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type repository struct {
+	dbContext *database.DBContext
+	logger    *logger.Logger
+}
+
+func NewRepository(dbContext *database.DBContext, _l *logger.Logger) Repository {
+	return &repository{dbContext, _l}
+}
+
+// InsertSubscriptionByStruct will insert a subscription and set its ID and creation date
+func (r *repository) InsertSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error {
+	ctx, span := tracing.Start(ctx, "webhook.repository.InsertSubscriptionByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`INSERT INTO webhook_subscriptions (url, event_types, secret, active)
+			VALUES ({:url}, {:event_types}, {:secret}, {:active})
+			RETURNING id, date_created`).
+			Bind(dbx.Params{
+				"url":         s.URL,
+				"event_types": s.EventTypes,
+				"secret":      s.Secret,
+				"active":      s.Active,
+			}).Row(&s.ID, &s.DateCreated)
+	})
+}
+
+// GetSubscriptionByID will get and return a subscription by ID, secret included
+func (r *repository) GetSubscriptionByID(ctx context.Context, subscriptionID int) (*entity.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.GetSubscriptionByID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var s entity.WebhookSubscription
+	err := r.dbContext.Get().WithContext(ctx).Select().From("webhook_subscriptions").Where(dbx.HashExp{"id": subscriptionID}).One(&s)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("webhook subscription %d not found", subscriptionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAllSubscriptions will return every subscription, secrets included
+func (r *repository) GetAllSubscriptions(ctx context.Context) ([]entity.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.GetAllSubscriptions")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.WebhookSubscription
+	err := r.dbContext.Get().WithContext(ctx).Select().From("webhook_subscriptions").OrderBy("id ASC").All(&list)
+	return list, err
+}
+
+// UpdateSubscriptionByStruct will update the url, event filter and active flag of a subscription
+func (r *repository) UpdateSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error {
+	ctx, span := tracing.Start(ctx, "webhook.repository.UpdateSubscriptionByStruct")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Update("webhook_subscriptions", dbx.Params{
+			"url":         s.URL,
+			"event_types": s.EventTypes,
+			"active":      s.Active,
+		}, dbx.HashExp{"id": s.ID}).Execute()
+		return err
+	})
+}
+
+// DeleteSubscriptionByID will delete a subscription and its deliveries
+func (r *repository) DeleteSubscriptionByID(ctx context.Context, subscriptionID int) error {
+	ctx, span := tracing.Start(ctx, "webhook.repository.DeleteSubscriptionByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Delete("webhook_subscriptions", dbx.HashExp{"id": subscriptionID}).Execute()
+		return err
+	})
+}
+
+// GetDeliveriesBySubscriptionID will return the latest deliveries of a subscription, optionally by status
+func (r *repository) GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID int, status string, limit int) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.GetDeliveriesBySubscriptionID")
+	defer span.End()
+	ctx, cancel := r.dbContext.WithTimeout(ctx)
+	defer cancel()
+
+	var list []entity.WebhookDelivery
+	q := r.dbContext.Get().WithContext(ctx).
+		Select("id", "subscription_id", "outbox_id", "event_type", "status", "attempts", "next_attempt_at",
+			"last_status_code", "last_error", "date_created", "date_delivered").
+		From("webhook_deliveries").
+		Where(dbx.HashExp{"subscription_id": subscriptionID})
+	if status != "" {
+		q.AndWhere(dbx.HashExp{"status": status})
+	}
+	err := q.OrderBy("id DESC").Limit(int64(limit)).All(&list)
+	return list, err
+}
+
+// RetryDeliveryByID will reschedule a dead delivery with a fresh attempt budget
+func (r *repository) RetryDeliveryByID(ctx context.Context, deliveryID int) error {
+	ctx, span := tracing.Start(ctx, "webhook.repository.RetryDeliveryByID")
+	defer span.End()
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		result, err := tx.Update("webhook_deliveries", dbx.Params{
+			"status":          StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_until":    nil,
+		}, dbx.HashExp{"id": deliveryID, "status": StatusDead}).Execute()
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return ErrDeliveryNotDead
+		}
+		return nil
+	})
+}
+
+// DispatchOutbox will fan a batch of undispatched outbox events out to one delivery per matching
+// active subscription and mark them dispatched, returning the number of events dispatched. Concurrent
+// dispatchers skip each other's batches.
+func (r *repository) DispatchOutbox(ctx context.Context, limit int) (int64, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.DispatchOutbox")
+	defer span.End()
+
+	var dispatched int64
+	err := r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		result, err := tx.NewQuery(`WITH batch AS (
+				SELECT id, event_type FROM event_outbox
+				WHERE date_dispatched IS NULL
+				ORDER BY id
+				LIMIT {:limit}
+				FOR UPDATE SKIP LOCKED
+			),
+			created AS (
+				INSERT INTO webhook_deliveries (subscription_id, outbox_id, event_type)
+				SELECT s.id, b.id, b.event_type
+				FROM batch b
+				JOIN webhook_subscriptions s
+					ON s.active AND (cardinality(s.event_types) = 0 OR b.event_type = ANY(s.event_types))
+				ON CONFLICT (subscription_id, outbox_id) DO NOTHING
+			)
+			UPDATE event_outbox SET date_dispatched = NOW()
+			WHERE id IN (SELECT id FROM batch)`).
+			Bind(dbx.Params{"limit": limit}).
+			Execute()
+		if err != nil {
+			return err
+		}
+		dispatched, err = result.RowsAffected()
+		return err
+	})
+	return dispatched, err
+}
+
+// ClaimDueDeliveries will lease a batch of due pending deliveries for lease and return them with their
+// target and payload. A delivery whose lease expires, e.g. after a crash, is claimed again.
+func (r *repository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ClaimedDelivery, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.ClaimDueDeliveries")
+	defer span.End()
+
+	var list []ClaimedDelivery
+	err := r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		return tx.NewQuery(`UPDATE webhook_deliveries d
+			SET locked_until = NOW() + make_interval(secs => {:lease_seconds})
+			FROM webhook_subscriptions s, event_outbox o
+			WHERE s.id = d.subscription_id AND o.id = d.outbox_id
+				AND d.id IN (
+					SELECT id FROM webhook_deliveries
+					WHERE status = 'pending' AND next_attempt_at <= NOW()
+						AND (locked_until IS NULL OR locked_until < NOW())
+					ORDER BY next_attempt_at, id
+					LIMIT {:limit}
+					FOR UPDATE SKIP LOCKED
+				)
+			RETURNING d.id, d.outbox_id, d.event_type, d.attempts, s.url, s.secret, o.payload::text AS payload,
+				o.trace_context::text AS trace_context`).
+			Bind(dbx.Params{"limit": limit, "lease_seconds": lease.Seconds()}).
+			All(&list)
+	})
+	return list, err
+}
+
+// RecordDeliveryResult will store the outcome of an attempt and release the lease
+func (r *repository) RecordDeliveryResult(ctx context.Context, deliveryID int, result DeliveryResult) error {
+	ctx, span := tracing.Start(ctx, "webhook.repository.RecordDeliveryResult")
+	defer span.End()
+
+	params := dbx.Params{
+		"attempts":         dbx.NewExp("attempts + 1"),
+		"last_status_code": result.StatusCode,
+		"last_error":       result.Error,
+		"locked_until":     nil,
+	}
+	switch {
+	case result.Delivered:
+		params["status"] = StatusDelivered
+		params["date_delivered"] = time.Now()
+	case result.Dead:
+		params["status"] = StatusDead
+	default:
+		params["next_attempt_at"] = result.NextAt
+	}
+
+	return r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		_, err := tx.Update("webhook_deliveries", params, dbx.HashExp{"id": deliveryID}).Execute()
+		return err
+	})
+}
+
+// DeleteSettledBefore will delete the delivered and dead deliveries last attempted before before, then
+// the outbox events dispatched before before that no delivery refers to anymore, returning the number
+// of events deleted. Events with pending deliveries are kept as deleting them cascades to the deliveries.
+func (r *repository) DeleteSettledBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "webhook.repository.DeleteSettledBefore")
+	defer span.End()
+
+	var deleted int64
+	err := r.dbContext.UseTransaction(ctx, func(tx *dbx.Tx) error {
+		// a dead delivery was last attempted at its next_attempt_at
+		_, err := tx.NewQuery(`DELETE FROM webhook_deliveries
+			WHERE status IN ('delivered', 'dead') AND COALESCE(date_delivered, next_attempt_at) < {:before}`).
+			Bind(dbx.Params{"before": before}).
+			Execute()
+		if err != nil {
+			return err
+		}
+		result, err := tx.NewQuery(`DELETE FROM event_outbox o
+			WHERE o.date_dispatched < {:before}
+				AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.outbox_id = o.id)`).
+			Bind(dbx.Params{"before": before}).
+			Execute()
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	return deleted, err
+}
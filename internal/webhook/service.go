@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/apperror"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+)
+
+const (
+	// DefaultDeliveryLimit and MaxDeliveryLimit bound the delivery log
+	DefaultDeliveryLimit = 50
+	MaxDeliveryLimit     = 500
+)
+
+// ErrInvalidSubscription is returned when a subscription fails validation
+var ErrInvalidSubscription = apperror.New(apperror.KindValidation, "invalid webhook subscription")
+
+type Service interface {
+	InsertSubscriptionByStruct(ctx context.Context, s *Subscription) error
+	GetSubscriptionByID(ctx context.Context, subscriptionID int) (*Subscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]Subscription, error)
+	UpdateSubscriptionByStruct(ctx context.Context, s *Subscription) error
+	DeleteSubscriptionByID(ctx context.Context, subscriptionID int) error
+	GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID int, status string, limit int) ([]entity.WebhookDelivery, error)
+	RetryDeliveryByID(ctx context.Context, deliveryID int) error
+}
+
+// Service
+type service struct {
+	repo   Repository
+	logger *logger.Logger
+}
+
+type Subscription struct {
+	entity.WebhookSubscription
+}
+
+func NewService(repo Repository, logger *logger.Logger) Service {
+	return &service{repo, logger}
+}
+
+// validate checks the target URL and the event filter of a subscription
+func (s *Subscription) validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidSubscription.WithField("url", "url must be an absolute http or https URL")
+	}
+	for _, t := range s.EventTypes {
+		if !slices.Contains(events.Types, t) {
+			return ErrInvalidSubscription.WithField("eventTypes", fmt.Sprintf("unknown event type %q", t))
+		}
+	}
+	if s.EventTypes == nil {
+		s.EventTypes = []string{}
+	}
+	return nil
+}
+
+// InsertSubscriptionByStruct creates an active subscription, generating a secret when none is given.
+// The secret is returned once and never read back.
+func (s *service) InsertSubscriptionByStruct(ctx context.Context, sub *Subscription) error {
+	if err := sub.validate(); err != nil {
+		return err
+	}
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return err
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	sub.Active = true
+	return s.repo.InsertSubscriptionByStruct(ctx, &sub.WebhookSubscription)
+}
+
+func (s *service) GetSubscriptionByID(ctx context.Context, subscriptionID int) (*Subscription, error) {
+	sub, err := s.repo.GetSubscriptionByID(ctx, subscriptionID)
+	if err != nil {
+		return &Subscription{}, err
+	}
+	sub.Secret = ""
+	return &Subscription{*sub}, nil
+}
+
+func (s *service) GetAllSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subs, err := s.repo.GetAllSubscriptions(ctx)
+	if err != nil {
+		return []Subscription{}, err
+	}
+	results := []Subscription{}
+	for _, sub := range subs {
+		sub.Secret = ""
+		results = append(results, Subscription{sub})
+	}
+	return results, nil
+}
+
+func (s *service) UpdateSubscriptionByStruct(ctx context.Context, sub *Subscription) error {
+	if err := sub.validate(); err != nil {
+		return err
+	}
+	sub.Secret = ""
+	return s.repo.UpdateSubscriptionByStruct(ctx, &sub.WebhookSubscription)
+}
+
+func (s *service) DeleteSubscriptionByID(ctx context.Context, subscriptionID int) error {
+	return s.repo.DeleteSubscriptionByID(ctx, subscriptionID)
+}
+
+// GetDeliveriesBySubscriptionID returns the delivery log of a subscription, newest first
+func (s *service) GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID int, status string, limit int) ([]entity.WebhookDelivery, error) {
+	switch status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		return []entity.WebhookDelivery{}, ErrInvalidSubscription.WithField("status", fmt.Sprintf("status must be %s, %s or %s", StatusPending, StatusDelivered, StatusDead))
+	}
+	if limit <= 0 || limit > MaxDeliveryLimit {
+		limit = DefaultDeliveryLimit
+	}
+
+	deliveries, err := s.repo.GetDeliveriesBySubscriptionID(ctx, subscriptionID, status, limit)
+	if err != nil || deliveries == nil {
+		return []entity.WebhookDelivery{}, err
+	}
+	return deliveries, nil
+}
+
+// RetryDeliveryByID moves a dead-lettered delivery back to pending
+func (s *service) RetryDeliveryByID(ctx context.Context, deliveryID int) error {
+	return s.repo.RetryDeliveryByID(ctx, deliveryID)
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renniemaharaj/grouplogs/pkg/logger"
+	"github.com/renniemaharaj/project-list-go/internal/entity"
+	"github.com/renniemaharaj/project-list-go/internal/events"
+)
+
+// fakeRepository stores one subscription and records the delivery log queries
+type fakeRepository struct {
+	Repository
+	subscription *entity.WebhookSubscription
+	limit        int
+}
+
+func (f *fakeRepository) InsertSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error {
+	stored := *s
+	f.subscription = &stored
+	return nil
+}
+
+func (f *fakeRepository) UpdateSubscriptionByStruct(ctx context.Context, s *entity.WebhookSubscription) error {
+	stored := *s
+	f.subscription = &stored
+	return nil
+}
+
+func (f *fakeRepository) GetSubscriptionByID(ctx context.Context, subscriptionID int) (*entity.WebhookSubscription, error) {
+	stored := *f.subscription
+	return &stored, nil
+}
+
+func (f *fakeRepository) GetDeliveriesBySubscriptionID(ctx context.Context, subscriptionID int, status string, limit int) ([]entity.WebhookDelivery, error) {
+	f.limit = limit
+	return nil, nil
+}
+
+func newTestService() (Service, *fakeRepository) {
+	repo := &fakeRepository{}
+	return NewService(repo, logger.New().Prefix("Webhook Test")), repo
+}
+
+func TestInsertSubscriptionValidates(t *testing.T) {
+	tests := []struct {
+		url        string
+		eventTypes []string
+		valid      bool
+	}{
+		{"https://example.com/hook", nil, true},
+		{"http://localhost:8080/hook", []string{events.TypeStatusAdded, events.TypeTagChanged}, true},
+		{"ftp://example.com/hook", nil, false},
+		{"/hook", nil, false},
+		{"https://", nil, false},
+		{"https://example.com/hook", []string{"project.deleted"}, false},
+	}
+	for _, tt := range tests {
+		s, repo := newTestService()
+		err := s.InsertSubscriptionByStruct(context.Background(), &Subscription{entity.WebhookSubscription{URL: tt.url, EventTypes: tt.eventTypes}})
+		if tt.valid != (err == nil) || (!tt.valid && (!errors.Is(err, ErrInvalidSubscription) || repo.subscription != nil)) {
+			t.Errorf("%s %v: err = %v, stored %v", tt.url, tt.eventTypes, err, repo.subscription != nil)
+		}
+		// a missing filter is stored as every event type rather than NULL
+		if tt.valid && (repo.subscription.EventTypes == nil || !repo.subscription.Active) {
+			t.Errorf("%s: stored %+v, want an active subscription with a filter", tt.url, repo.subscription)
+		}
+	}
+}
+
+func TestSubscriptionSecret(t *testing.T) {
+	s, repo := newTestService()
+
+	// a secret is generated when none is given and returned once
+	sub := &Subscription{entity.WebhookSubscription{URL: "https://example.com/hook"}}
+	if err := s.InsertSubscriptionByStruct(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	if len(sub.Secret) != 64 || repo.subscription.Secret != sub.Secret {
+		t.Fatalf("secret = %q, stored %q, want a 32 byte hex secret", sub.Secret, repo.subscription.Secret)
+	}
+
+	// but never read back
+	got, err := s.GetSubscriptionByID(context.Background(), 1)
+	if err != nil || got.Secret != "" {
+		t.Fatalf("subscription = %+v, err = %v, want the secret hidden", got, err)
+	}
+
+	// and updates cannot change it
+	sub.Secret = "chosen"
+	if err := s.UpdateSubscriptionByStruct(context.Background(), sub); err != nil || repo.subscription.Secret != "" {
+		t.Fatalf("err = %v, updated secret %q", err, repo.subscription.Secret)
+	}
+}
+
+func TestGetDeliveriesBySubscriptionID(t *testing.T) {
+	for limit, want := range map[int]int{0: DefaultDeliveryLimit, 10: 10, MaxDeliveryLimit: MaxDeliveryLimit, MaxDeliveryLimit + 1: DefaultDeliveryLimit, -1: DefaultDeliveryLimit} {
+		s, repo := newTestService()
+		deliveries, err := s.GetDeliveriesBySubscriptionID(context.Background(), 1, StatusDead, limit)
+		if err != nil || deliveries == nil || repo.limit != want {
+			t.Errorf("limit %d: deliveries = %v, err = %v, queried %d, want %d", limit, deliveries, err, repo.limit, want)
+		}
+	}
+
+	s, _ := newTestService()
+	if _, err := s.GetDeliveriesBySubscriptionID(context.Background(), 1, "failed", 0); !errors.Is(err, ErrInvalidSubscription) {
+		t.Fatalf("err = %v, want ErrInvalidSubscription for status failed", err)
+	}
+}